@@ -0,0 +1,349 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/veraison/corim/encoding"
+)
+
+// CMType identifies the type of conceptual message a claim in the Accepted
+// Claims Set has been derived from.
+type CMType uint64
+
+const (
+	CMTypeReferenceValues CMType = iota
+	CMTypeEndorsements
+	CMTypeEvidence
+)
+
+var (
+	cmTypeToString = map[CMType]string{
+		CMTypeReferenceValues: "reference-values",
+		CMTypeEndorsements:    "endorsements",
+		CMTypeEvidence:        "evidence",
+	}
+
+	stringToCMType = map[string]CMType{
+		"reference-values": CMTypeReferenceValues,
+		"endorsements":     CMTypeEndorsements,
+		"evidence":         CMTypeEvidence,
+	}
+)
+
+// String returns the string representation of the CMType
+func (o CMType) String() string {
+	text, ok := cmTypeToString[o]
+	if ok {
+		return text
+	}
+
+	return fmt.Sprintf("CMType(%d)", o)
+}
+
+// Valid returns an error if the CMType is not one of the known values
+func (o CMType) Valid() error {
+	if _, ok := cmTypeToString[o]; !ok {
+		return fmt.Errorf("unknown cmtype %d", o)
+	}
+	return nil
+}
+
+// MarshalJSON serializes the CMType to its JSON string representation
+func (o CMType) MarshalJSON() ([]byte, error) {
+	s, ok := cmTypeToString[o]
+	if !ok {
+		return nil, fmt.Errorf("unknown cmtype %d", o)
+	}
+
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON deserializes the supplied JSON string into the target CMType
+func (o *CMType) UnmarshalJSON(data []byte) error {
+	var s string
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	v, ok := stringToCMType[s]
+	if !ok {
+		return fmt.Errorf("unknown cmtype %q", s)
+	}
+
+	*o = v
+
+	return nil
+}
+
+// ECT is an Environment-Claims Tuple, i.e., an entry of the Accepted Claims
+// Set.  It records the measurements that have been accepted for an
+// environment, together with the authority that asserted them and the type of
+// conceptual message they originate from.  If the claims originate from a
+// CoMID, Source records its tag identity.
+type ECT struct {
+	Environment  Environment  `cbor:"0,keyasint" json:"environment"`
+	Measurements Measurements `cbor:"1,keyasint" json:"measurements"`
+	Authority    *CryptoKeys  `cbor:"2,keyasint,omitempty" json:"authority,omitempty"`
+	CMType       CMType       `cbor:"3,keyasint" json:"cmtype"`
+	Source       *TagIdentity `cbor:"4,keyasint,omitempty" json:"source,omitempty"`
+}
+
+// NewECT instantiates a new ECT of the specified type for the supplied
+// environment and measurements
+func NewECT(env Environment, ms Measurements, cmtype CMType) *ECT {
+	return &ECT{
+		Environment:  env,
+		Measurements: ms,
+		CMType:       cmtype,
+	}
+}
+
+// SetAuthority sets the authority associated with the claims in the target ECT
+func (o *ECT) SetAuthority(authority *CryptoKeys) *ECT {
+	if o != nil {
+		o.Authority = authority
+	}
+	return o
+}
+
+// Valid checks the validity of the target ECT
+// nolint:gocritic
+func (o ECT) Valid() error {
+	if err := o.Environment.Valid(); err != nil {
+		return fmt.Errorf("environment validation failed: %w", err)
+	}
+
+	if o.Measurements.IsEmpty() {
+		return errors.New("measurements validation failed: no measurement entries")
+	}
+
+	if err := o.Measurements.Valid(); err != nil {
+		return fmt.Errorf("measurements validation failed: %w", err)
+	}
+
+	if o.Authority != nil {
+		if err := o.Authority.Valid(); err != nil {
+			return fmt.Errorf("authority validation failed: %w", err)
+		}
+	}
+
+	if err := o.CMType.Valid(); err != nil {
+		return err
+	}
+
+	if o.Source != nil {
+		if err := o.Source.Valid(); err != nil {
+			return fmt.Errorf("source validation failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UnmarshalCBOR deserializes from CBOR
+func (o *ECT) UnmarshalCBOR(data []byte) error {
+	return encoding.PopulateStructFromCBOR(dm, data, o)
+}
+
+// MarshalCBOR serializes to CBOR
+// nolint:gocritic
+func (o ECT) MarshalCBOR() ([]byte, error) {
+	return encoding.SerializeStructToCBOR(em, o)
+}
+
+// UnmarshalJSON deserializes from JSON
+func (o *ECT) UnmarshalJSON(data []byte) error {
+	return encoding.PopulateStructFromJSON(data, o)
+}
+
+// MarshalJSON serializes to JSON
+// nolint:gocritic
+func (o ECT) MarshalJSON() ([]byte, error) {
+	return encoding.SerializeStructToJSON(o)
+}
+
+// matchMeasurements returns true if each of the reference measurements is
+// matched by at least one of the claimed measurements
+func matchMeasurements(ref, claimed Measurements) bool {
+	for _, rm := range ref.Values {
+		found := false
+
+		for _, cm := range claimed.Values {
+			if rm.Match(cm).Matched() {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ACS is the Accepted Claims Set that is built during appraisal.  It is
+// initially populated with the evidence claims, and is then augmented with
+// the reference values that have been corroborated and the endorsed values
+// that have been applied as a consequence.
+type ACS struct {
+	Entries []ECT `cbor:"0,keyasint,omitempty" json:"entries,omitempty"`
+}
+
+// NewACS instantiates an empty ACS
+func NewACS() *ACS {
+	return &ACS{}
+}
+
+// AddECT appends the supplied ECT to the target ACS
+func (o *ACS) AddECT(ect ECT) *ACS {
+	if o != nil {
+		o.Entries = append(o.Entries, ect)
+	}
+	return o
+}
+
+// AddEvidence adds the supplied evidence claims, asserted by the supplied
+// authority (e.g., the attestation key), to the target ACS
+func (o *ACS) AddEvidence(env Environment, ms Measurements, authority *CryptoKeys) *ACS {
+	if o != nil {
+		o.AddECT(*NewECT(env, ms, CMTypeEvidence).SetAuthority(authority))
+	}
+	return o
+}
+
+// GetECTs returns the entries of the specified type whose environment is
+// covered by the supplied environment (see Environment.Match)
+func (o ACS) GetECTs(env Environment, cmtype CMType) []ECT {
+	var ret []ECT
+
+	for _, e := range o.Entries {
+		if e.CMType == cmtype && env.Match(e.Environment) {
+			ret = append(ret, e)
+		}
+	}
+
+	return ret
+}
+
+// ApplyComid matches the reference values of the supplied CoMID against the
+// evidence claims in the target ACS and applies the CoMID's endorsed values.
+// authority is the key that vouches for the CoMID (typically the CoRIM
+// signing key) and is recorded against each of the claims that are added.
+//
+// Each reference-triple is corroborated if its environment covers the
+// environment of an evidence entry, and each of its measurements is matched
+// by one of the evidence measurements (see Measurement.Match).  Corroborated
+// reference values are added to the ACS against the evidence environment.
+//
+// Each endorsed-triple is then applied to the corroborated environments it
+// covers.  If the CoMID carries no reference values, its endorsed values are
+// applied unconditionally to any evidence environment they cover.
+func (o *ACS) ApplyComid(c *Comid, authority *CryptoKeys) error {
+	if o == nil {
+		return errors.New("nil ACS")
+	}
+
+	if c == nil {
+		return errors.New("nil CoMID")
+	}
+
+	if err := c.Valid(); err != nil {
+		return fmt.Errorf("invalid CoMID: %w", err)
+	}
+
+	source := c.TagIdentity
+	evidence := o.GetECTs(Environment{}, CMTypeEvidence)
+
+	// indexes (into evidence) of the environments that endorsed values
+	// may be applied to
+	var targets []int
+
+	if c.Triples.ReferenceValues == nil || c.Triples.ReferenceValues.IsEmpty() {
+		for i := range evidence {
+			targets = append(targets, i)
+		}
+	} else {
+		matched := make(map[int]bool)
+
+		for _, rv := range c.Triples.ReferenceValues.Values {
+			for i, e := range evidence {
+				if !rv.Environment.Match(e.Environment) ||
+					!matchMeasurements(rv.Measurements, e.Measurements) {
+					continue
+				}
+
+				ect := NewECT(e.Environment, rv.Measurements, CMTypeReferenceValues).
+					SetAuthority(authority)
+				ect.Source = &source
+
+				o.AddECT(*ect)
+
+				if !matched[i] {
+					matched[i] = true
+					targets = append(targets, i)
+				}
+			}
+		}
+	}
+
+	if c.Triples.EndorsedValues == nil {
+		return nil
+	}
+
+	for _, ev := range c.Triples.EndorsedValues.Values {
+		for _, i := range targets {
+			env := evidence[i].Environment
+
+			if !ev.Environment.Match(env) {
+				continue
+			}
+
+			ect := NewECT(env, ev.Measurements, CMTypeEndorsements).
+				SetAuthority(authority)
+			ect.Source = &source
+
+			o.AddECT(*ect)
+		}
+	}
+
+	return nil
+}
+
+// Valid checks the validity of all the entries in the target ACS
+func (o ACS) Valid() error {
+	for i, e := range o.Entries {
+		if err := e.Valid(); err != nil {
+			return fmt.Errorf("entry at index %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// ToCBOR serializes the target ACS to CBOR
+func (o ACS) ToCBOR() ([]byte, error) {
+	return encoding.SerializeStructToCBOR(em, o)
+}
+
+// FromCBOR deserializes the supplied CBOR data into the target ACS
+func (o *ACS) FromCBOR(data []byte) error {
+	return encoding.PopulateStructFromCBOR(dm, data, o)
+}
+
+// ToJSON serializes the target ACS to JSON
+func (o ACS) ToJSON() ([]byte, error) {
+	return encoding.SerializeStructToJSON(o)
+}
+
+// FromJSON deserializes the supplied JSON data into the target ACS
+func (o *ACS) FromJSON(data []byte) error {
+	return encoding.PopulateStructFromJSON(data, o)
+}
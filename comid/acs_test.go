@@ -0,0 +1,159 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/swid"
+)
+
+func testACSComid(t *testing.T, refSVN uint64) *Comid {
+	refEnv := Environment{Class: NewClassUUID(TestUUID)}
+
+	refMeasurements := NewMeasurements().
+		Add(MustNewUintMeasurement(uint64(1)).
+			SetMinSVN(refSVN).
+			AddDigest(swid.Sha256_32, []byte{0xde, 0xad, 0xbe, 0xef}))
+
+	endMeasurements := NewMeasurements().
+		Add(MustNewUintMeasurement(uint64(1)).SetFlagsTrue(FlagIsSecure))
+
+	c := NewComid().
+		SetTagIdentity(TestTagID, 0).
+		AddReferenceValue(ValueTriple{Environment: refEnv, Measurements: *refMeasurements}).
+		AddEndorsedValue(ValueTriple{Environment: refEnv, Measurements: *endMeasurements})
+	require.NotNil(t, c)
+
+	return c
+}
+
+func testACSEvidence(t *testing.T) *ACS {
+	evEnv := Environment{
+		Class:    NewClassUUID(TestUUID).SetVendor("ACME Ltd."),
+		Instance: MustNewUEIDInstance(TestUEID),
+	}
+
+	evMeasurements := NewMeasurements().
+		Add(MustNewUintMeasurement(uint64(1)).
+			SetSVN(3).
+			AddDigest(swid.Sha256_32, []byte{0xde, 0xad, 0xbe, 0xef})).
+		Add(MustNewUintMeasurement(uint64(2)).SetSVN(1))
+
+	authority := NewCryptoKeys().Add(MustNewPKIXBase64Key(TestECPubKey))
+
+	acs := NewACS().AddEvidence(evEnv, *evMeasurements, authority)
+	require.NoError(t, acs.Valid())
+
+	return acs
+}
+
+func TestACS_ApplyComid_match(t *testing.T) {
+	acs := testACSEvidence(t)
+	authority := NewCryptoKeys().Add(MustNewPKIXBase64Cert(TestCert))
+
+	err := acs.ApplyComid(testACSComid(t, 2), authority)
+	require.NoError(t, err)
+	require.Len(t, acs.Entries, 3)
+
+	evidence := acs.Entries[0].Environment
+
+	rv := acs.Entries[1]
+	assert.Equal(t, CMTypeReferenceValues, rv.CMType)
+	assert.Equal(t, evidence, rv.Environment)
+	assert.Equal(t, authority, rv.Authority)
+	require.NotNil(t, rv.Source)
+	assert.Equal(t, TestTagID, rv.Source.TagID.String())
+
+	ev := acs.Entries[2]
+	assert.Equal(t, CMTypeEndorsements, ev.CMType)
+	assert.Equal(t, evidence, ev.Environment)
+	assert.Equal(t, authority, ev.Authority)
+	assert.Equal(t, &True, ev.Measurements.Values[0].Val.Flags.IsSecure)
+
+	assert.Len(t, acs.GetECTs(Environment{Instance: MustNewUEIDInstance(TestUEID)}, CMTypeEndorsements), 1)
+	assert.Len(t, acs.GetECTs(Environment{Group: MustNewUUIDGroup(TestUUID)}, CMTypeEndorsements), 0)
+
+	require.NoError(t, acs.Valid())
+}
+
+func TestACS_ApplyComid_no_match(t *testing.T) {
+	acs := testACSEvidence(t)
+
+	// evidence SVN (3) is below the reference min-svn (4)
+	err := acs.ApplyComid(testACSComid(t, 4), nil)
+	require.NoError(t, err)
+	assert.Len(t, acs.Entries, 1)
+}
+
+func TestACS_ApplyComid_unconditional_endorsement(t *testing.T) {
+	acs := testACSEvidence(t)
+
+	c := testACSComid(t, 2)
+	c.Triples.ReferenceValues = nil
+
+	err := acs.ApplyComid(c, nil)
+	require.NoError(t, err)
+	require.Len(t, acs.Entries, 2)
+	assert.Equal(t, CMTypeEndorsements, acs.Entries[1].CMType)
+	assert.Nil(t, acs.Entries[1].Authority)
+}
+
+func TestACS_ApplyComid_invalid(t *testing.T) {
+	acs := NewACS()
+
+	err := acs.ApplyComid(nil, nil)
+	assert.EqualError(t, err, "nil CoMID")
+
+	err = acs.ApplyComid(NewComid(), nil)
+	assert.EqualError(t, err, "invalid CoMID: tag-identity validation failed: empty tag-id")
+
+	var nilACS *ACS
+	err = nilACS.ApplyComid(testACSComid(t, 1), nil)
+	assert.EqualError(t, err, "nil ACS")
+}
+
+func TestACS_serialization(t *testing.T) {
+	acs := testACSEvidence(t)
+	require.NoError(t, acs.ApplyComid(testACSComid(t, 2), nil))
+
+	data, err := acs.ToCBOR()
+	require.NoError(t, err)
+
+	var fromCBOR ACS
+	require.NoError(t, fromCBOR.FromCBOR(data))
+	require.Len(t, fromCBOR.Entries, 3)
+	assert.NoError(t, fromCBOR.Valid())
+	assert.Equal(t, CMTypeEndorsements, fromCBOR.Entries[2].CMType)
+	assert.Equal(t, TestTagID, fromCBOR.Entries[2].Source.TagID.String())
+	assert.True(t, acs.Entries[0].Environment.Match(fromCBOR.Entries[0].Environment))
+
+	data, err = acs.ToJSON()
+	require.NoError(t, err)
+
+	var fromJSON ACS
+	require.NoError(t, fromJSON.FromJSON(data))
+	require.Len(t, fromJSON.Entries, 3)
+	assert.NoError(t, fromJSON.Valid())
+	assert.Equal(t, CMTypeReferenceValues, fromJSON.Entries[1].CMType)
+	assert.Equal(t, acs.Entries[0].Authority, fromJSON.Entries[0].Authority)
+}
+
+func TestCMType_String(t *testing.T) {
+	assert.Equal(t, "evidence", CMTypeEvidence.String())
+	assert.Equal(t, "CMType(7)", CMType(7).String())
+	assert.EqualError(t, CMType(7).Valid(), "unknown cmtype 7")
+
+	var cmtype CMType
+	require.NoError(t, cmtype.UnmarshalJSON([]byte(`"endorsements"`)))
+	assert.Equal(t, CMTypeEndorsements, cmtype)
+
+	err := cmtype.UnmarshalJSON([]byte(`"bogus"`))
+	assert.EqualError(t, err, `unknown cmtype "bogus"`)
+
+	_, err = CMType(7).MarshalJSON()
+	assert.EqualError(t, err, "unknown cmtype 7")
+}
@@ -132,6 +132,36 @@ func (o Class) Valid() error {
 	return nil
 }
 
+// Match returns true if the supplied (claimed) Class is covered by the target,
+// which is treated as the reference.  Fields that are not set in the
+// reference act as wildcards, while those that are set must be present in the
+// claimed Class with the same value.
+func (o Class) Match(claimed Class) bool {
+	if o.ClassID != nil && o.ClassID.IsSet() {
+		if claimed.ClassID == nil || !o.ClassID.Equal(*claimed.ClassID) {
+			return false
+		}
+	}
+
+	if o.Vendor != nil && (claimed.Vendor == nil || *o.Vendor != *claimed.Vendor) {
+		return false
+	}
+
+	if o.Model != nil && (claimed.Model == nil || *o.Model != *claimed.Model) {
+		return false
+	}
+
+	if o.Layer != nil && (claimed.Layer == nil || *o.Layer != *claimed.Layer) {
+		return false
+	}
+
+	if o.Index != nil && (claimed.Index == nil || *o.Index != *claimed.Index) {
+		return false
+	}
+
+	return true
+}
+
 // ToCBOR serializes the target Class to CBOR (if the Class is "valid")
 func (o Class) ToCBOR() ([]byte, error) {
 	if err := o.Valid(); err != nil {
@@ -233,3 +233,16 @@ func TestClass_UnmarshalJSON_full(t *testing.T) {
 	assert.NotNil(t, actual.Index)
 	assert.Equal(t, uint64(2), actual.GetIndex())
 }
+
+func TestClass_Match(t *testing.T) {
+	claimed := NewClassOID(TestOID).SetVendor("ACME Ltd.").SetIndex(2)
+
+	assert.True(t, Class{}.Match(*claimed))
+	assert.True(t, NewClassOID(TestOID).Match(*claimed))
+	assert.True(t, (&Class{}).SetVendor("ACME Ltd.").SetIndex(2).Match(*claimed))
+	assert.False(t, NewClassOID("2.5.2.8193").Match(*claimed))
+	assert.False(t, NewClassOID(TestOID).SetIndex(3).Match(*claimed))
+	assert.False(t, NewClassOID(TestOID).SetModel("RoadRunner").Match(*claimed))
+	assert.False(t, NewClassOID(TestOID).SetLayer(0).Match(*claimed))
+	assert.False(t, NewClassUUID(TestUUID).Match(Class{Vendor: claimed.Vendor}))
+}
@@ -63,6 +63,12 @@ func (o ClassID) IsSet() bool {
 	return o.Value != nil
 }
 
+// Equal returns true if the target ClassID and the supplied one are of the same
+// type and have the same value bytes.
+func (o ClassID) Equal(other ClassID) bool {
+	return typeChoiceEqual(o.Value, other.Value)
+}
+
 // MarshalCBOR serializes the target ClassID to CBOR
 func (o ClassID) MarshalCBOR() ([]byte, error) {
 	return em.Marshal(o.Value)
@@ -671,3 +671,19 @@ func TestClassID_ImplID(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, implID, other)
 }
+
+func TestClassID_Equal(t *testing.T) {
+	a, err := NewUUIDClassID(TestUUID)
+	require.NoError(t, err)
+
+	b, err := NewUUIDClassID(TestUUIDString)
+	require.NoError(t, err)
+
+	c, err := NewBytesClassID(TestUUID[:])
+	require.NoError(t, err)
+
+	assert.True(t, a.Equal(*b))
+	assert.False(t, a.Equal(*c))
+	assert.False(t, a.Equal(ClassID{}))
+	assert.True(t, ClassID{}.Equal(ClassID{}))
+}
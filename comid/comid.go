@@ -242,6 +242,61 @@ func (o *Comid) AddDevIdentityKey(val KeyTriple) *Comid {
 	return o
 }
 
+// AddDomainDependency adds the supplied domain dependency to the
+// domain-dependency-triples list of the target Comid.
+func (o *Comid) AddDomainDependency(val DomainDependencyTriple) *Comid {
+	if o != nil {
+		if o.Triples.AddDomainDependency(val) == nil {
+			return nil
+		}
+	}
+	return o
+}
+
+// AddDomainMembership adds the supplied domain membership to the
+// domain-membership-triples list of the target Comid.
+func (o *Comid) AddDomainMembership(val DomainMembershipTriple) *Comid {
+	if o != nil {
+		if o.Triples.AddDomainMembership(val) == nil {
+			return nil
+		}
+	}
+	return o
+}
+
+// AddCoswidTriple adds the supplied environment to CoSWID tags association to
+// the coswid-triples list of the target Comid.
+func (o *Comid) AddCoswidTriple(val CoswidTriple) *Comid {
+	if o != nil {
+		if o.Triples.AddCoswidTriple(val) == nil {
+			return nil
+		}
+	}
+	return o
+}
+
+// AddCondEndorsement adds the supplied conditional endorsement to the
+// conditional-endorsement-triples list of the target Comid.
+func (o *Comid) AddCondEndorsement(val CondEndorseTriple) *Comid {
+	if o != nil {
+		if o.Triples.AddCondEndorsement(val) == nil {
+			return nil
+		}
+	}
+	return o
+}
+
+// AddCondEndorsementSeries adds the supplied conditional endorsement series to
+// the conditional-endorsement-series-triples list of the target Comid.
+func (o *Comid) AddCondEndorsementSeries(val CondEndorseSeriesTriple) *Comid {
+	if o != nil {
+		if o.Triples.AddCondEndorsementSeries(val) == nil {
+			return nil
+		}
+	}
+	return o
+}
+
 // nolint:gocritic
 func (o Comid) Valid() error {
 	if err := o.TagIdentity.Valid(); err != nil {
@@ -0,0 +1,362 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/veraison/corim/extensions"
+)
+
+// CondEndorseTriple stores a conditional-endorsement-triple-record.  The
+// endorsements apply if each of the conditions is matched, i.e., if the state
+// of each of the condition environments corresponds to the associated
+// measurements. Each condition is a stateful-environment-record, which has the
+// same shape as a ValueTriple.
+//
+//	conditional-endorsement-triple-record = [
+//	  conditions: [ + stateful-environment-record ]
+//	  endorsements: [ + endorsed-triple-record ]
+//	]
+type CondEndorseTriple struct {
+	_            struct{}     `cbor:",toarray"`
+	Conditions   ValueTriples `json:"conditions"`
+	Endorsements ValueTriples `json:"endorsements"`
+}
+
+// NewCondEndorseTriple instantiates an empty CondEndorseTriple
+func NewCondEndorseTriple() *CondEndorseTriple {
+	return &CondEndorseTriple{
+		Conditions:   *NewValueTriples(),
+		Endorsements: *NewValueTriples(),
+	}
+}
+
+// AddCondition adds the supplied stateful environment to the conditions of
+// the target CondEndorseTriple
+func (o *CondEndorseTriple) AddCondition(val ValueTriple) *CondEndorseTriple {
+	if o != nil {
+		o.Conditions.Add(&val)
+	}
+	return o
+}
+
+// AddEndorsement adds the supplied endorsed value to the endorsements of the
+// target CondEndorseTriple
+func (o *CondEndorseTriple) AddEndorsement(val ValueTriple) *CondEndorseTriple {
+	if o != nil {
+		o.Endorsements.Add(&val)
+	}
+	return o
+}
+
+// RegisterExtensions registers the supplied Mval and Flags extensions with both
+// the conditions and the endorsements of the target CondEndorseTriple
+func (o *CondEndorseTriple) RegisterExtensions(exts extensions.Map) error {
+	if err := o.Conditions.RegisterExtensions(exts); err != nil {
+		return fmt.Errorf("conditions: %w", err)
+	}
+
+	if err := o.Endorsements.RegisterExtensions(exts); err != nil {
+		return fmt.Errorf("endorsements: %w", err)
+	}
+
+	return nil
+}
+
+// GetExtensions returns previously registered extensions
+func (o *CondEndorseTriple) GetExtensions() extensions.IMapValue {
+	return o.Endorsements.GetExtensions()
+}
+
+// Valid checks the validity of the target CondEndorseTriple
+// nolint:gocritic
+func (o CondEndorseTriple) Valid() error {
+	if o.Conditions.IsEmpty() {
+		return errors.New("conditions validation failed: no condition entries")
+	}
+
+	if err := o.Conditions.Valid(); err != nil {
+		return fmt.Errorf("conditions validation failed: %w", err)
+	}
+
+	if o.Endorsements.IsEmpty() {
+		return errors.New("endorsements validation failed: no endorsement entries")
+	}
+
+	if err := o.Endorsements.Valid(); err != nil {
+		return fmt.Errorf("endorsements validation failed: %w", err)
+	}
+
+	return nil
+}
+
+// CondEndorseTriples is a container for CondEndorseTriple instances and their
+// extensions.  It is a thin wrapper around extensions.Collection.
+type CondEndorseTriples extensions.Collection[CondEndorseTriple, *CondEndorseTriple]
+
+func NewCondEndorseTriples() *CondEndorseTriples {
+	return (*CondEndorseTriples)(extensions.NewCollection[CondEndorseTriple]())
+}
+
+func (o *CondEndorseTriples) RegisterExtensions(exts extensions.Map) error {
+	return (*extensions.Collection[CondEndorseTriple, *CondEndorseTriple])(o).RegisterExtensions(exts)
+}
+
+func (o *CondEndorseTriples) GetExtensions() extensions.IMapValue {
+	return (*extensions.Collection[CondEndorseTriple, *CondEndorseTriple])(o).GetExtensions()
+}
+
+func (o CondEndorseTriples) Valid() error {
+	return (extensions.Collection[CondEndorseTriple, *CondEndorseTriple])(o).Valid()
+}
+
+func (o *CondEndorseTriples) IsEmpty() bool {
+	return (*extensions.Collection[CondEndorseTriple, *CondEndorseTriple])(o).IsEmpty()
+}
+
+func (o *CondEndorseTriples) Add(val *CondEndorseTriple) *CondEndorseTriples {
+	ret := (*extensions.Collection[CondEndorseTriple, *CondEndorseTriple])(o).Add(val)
+	return (*CondEndorseTriples)(ret)
+}
+
+func (o CondEndorseTriples) MarshalCBOR() ([]byte, error) {
+	return (extensions.Collection[CondEndorseTriple, *CondEndorseTriple])(o).MarshalCBOR()
+}
+
+func (o *CondEndorseTriples) UnmarshalCBOR(data []byte) error {
+	return (*extensions.Collection[CondEndorseTriple, *CondEndorseTriple])(o).UnmarshalCBOR(data)
+}
+
+func (o CondEndorseTriples) MarshalJSON() ([]byte, error) {
+	return (extensions.Collection[CondEndorseTriple, *CondEndorseTriple])(o).MarshalJSON()
+}
+
+func (o *CondEndorseTriples) UnmarshalJSON(data []byte) error {
+	return (*extensions.Collection[CondEndorseTriple, *CondEndorseTriple])(o).UnmarshalJSON(data)
+}
+
+// CondSeriesRecord stores a conditional-series-record.  If the selection
+// measurements are matched, the addition measurements are endorsed.
+//
+//	conditional-series-record = [
+//	  selection: [ + measurement-map ]
+//	  addition: [ + measurement-map ]
+//	]
+type CondSeriesRecord struct {
+	_         struct{}     `cbor:",toarray"`
+	Selection Measurements `json:"selection"`
+	Addition  Measurements `json:"addition"`
+}
+
+// NewCondSeriesRecord instantiates a new CondSeriesRecord with the supplied
+// selection and addition measurements
+func NewCondSeriesRecord(selection, addition Measurements) *CondSeriesRecord {
+	return &CondSeriesRecord{
+		Selection: selection,
+		Addition:  addition,
+	}
+}
+
+// RegisterExtensions registers the supplied Mval and Flags extensions with both
+// the selection and the addition measurements of the target CondSeriesRecord
+func (o *CondSeriesRecord) RegisterExtensions(exts extensions.Map) error {
+	if err := o.Selection.RegisterExtensions(exts); err != nil {
+		return fmt.Errorf("selection: %w", err)
+	}
+
+	if err := o.Addition.RegisterExtensions(exts); err != nil {
+		return fmt.Errorf("addition: %w", err)
+	}
+
+	return nil
+}
+
+// GetExtensions returns previously registered extensions
+func (o *CondSeriesRecord) GetExtensions() extensions.IMapValue {
+	return o.Addition.GetExtensions()
+}
+
+// Valid checks the validity of the target CondSeriesRecord
+// nolint:gocritic
+func (o CondSeriesRecord) Valid() error {
+	if o.Selection.IsEmpty() {
+		return errors.New("selection validation failed: no measurement entries")
+	}
+
+	if err := o.Selection.Valid(); err != nil {
+		return fmt.Errorf("selection validation failed: %w", err)
+	}
+
+	if o.Addition.IsEmpty() {
+		return errors.New("addition validation failed: no measurement entries")
+	}
+
+	if err := o.Addition.Valid(); err != nil {
+		return fmt.Errorf("addition validation failed: %w", err)
+	}
+
+	return nil
+}
+
+// CondSeriesRecords is a container for CondSeriesRecord instances and their
+// extensions.  It is a thin wrapper around extensions.Collection.
+type CondSeriesRecords extensions.Collection[CondSeriesRecord, *CondSeriesRecord]
+
+func NewCondSeriesRecords() *CondSeriesRecords {
+	return (*CondSeriesRecords)(extensions.NewCollection[CondSeriesRecord]())
+}
+
+func (o *CondSeriesRecords) RegisterExtensions(exts extensions.Map) error {
+	return (*extensions.Collection[CondSeriesRecord, *CondSeriesRecord])(o).RegisterExtensions(exts)
+}
+
+func (o *CondSeriesRecords) GetExtensions() extensions.IMapValue {
+	return (*extensions.Collection[CondSeriesRecord, *CondSeriesRecord])(o).GetExtensions()
+}
+
+func (o CondSeriesRecords) Valid() error {
+	return (extensions.Collection[CondSeriesRecord, *CondSeriesRecord])(o).Valid()
+}
+
+func (o *CondSeriesRecords) IsEmpty() bool {
+	return (*extensions.Collection[CondSeriesRecord, *CondSeriesRecord])(o).IsEmpty()
+}
+
+func (o *CondSeriesRecords) Add(val *CondSeriesRecord) *CondSeriesRecords {
+	ret := (*extensions.Collection[CondSeriesRecord, *CondSeriesRecord])(o).Add(val)
+	return (*CondSeriesRecords)(ret)
+}
+
+func (o CondSeriesRecords) MarshalCBOR() ([]byte, error) {
+	return (extensions.Collection[CondSeriesRecord, *CondSeriesRecord])(o).MarshalCBOR()
+}
+
+func (o *CondSeriesRecords) UnmarshalCBOR(data []byte) error {
+	return (*extensions.Collection[CondSeriesRecord, *CondSeriesRecord])(o).UnmarshalCBOR(data)
+}
+
+func (o CondSeriesRecords) MarshalJSON() ([]byte, error) {
+	return (extensions.Collection[CondSeriesRecord, *CondSeriesRecord])(o).MarshalJSON()
+}
+
+func (o *CondSeriesRecords) UnmarshalJSON(data []byte) error {
+	return (*extensions.Collection[CondSeriesRecord, *CondSeriesRecord])(o).UnmarshalJSON(data)
+}
+
+// CondEndorseSeriesTriple stores a conditional-endorsement-series-triple-record.
+// If the condition is matched, the series records are evaluated in order, and
+// the additions of the first record whose selection is matched are endorsed.
+//
+//	conditional-endorsement-series-triple-record = [
+//	  condition: stateful-environment-record
+//	  series: [ + conditional-series-record ]
+//	]
+type CondEndorseSeriesTriple struct {
+	_         struct{}          `cbor:",toarray"`
+	Condition ValueTriple       `json:"condition"`
+	Series    CondSeriesRecords `json:"series"`
+}
+
+// NewCondEndorseSeriesTriple instantiates a new CondEndorseSeriesTriple with
+// the supplied condition and an empty series
+func NewCondEndorseSeriesTriple(condition ValueTriple) *CondEndorseSeriesTriple {
+	return &CondEndorseSeriesTriple{
+		Condition: condition,
+		Series:    *NewCondSeriesRecords(),
+	}
+}
+
+// AddSeries adds a series record with the supplied selection and addition
+// measurements to the target CondEndorseSeriesTriple
+func (o *CondEndorseSeriesTriple) AddSeries(selection, addition Measurements) *CondEndorseSeriesTriple {
+	if o != nil {
+		o.Series.Add(NewCondSeriesRecord(selection, addition))
+	}
+	return o
+}
+
+// RegisterExtensions registers the supplied Mval and Flags extensions with both
+// the condition and the series of the target CondEndorseSeriesTriple
+func (o *CondEndorseSeriesTriple) RegisterExtensions(exts extensions.Map) error {
+	if err := o.Condition.RegisterExtensions(exts); err != nil {
+		return fmt.Errorf("condition: %w", err)
+	}
+
+	if err := o.Series.RegisterExtensions(exts); err != nil {
+		return fmt.Errorf("series: %w", err)
+	}
+
+	return nil
+}
+
+// GetExtensions returns previously registered extensions
+func (o *CondEndorseSeriesTriple) GetExtensions() extensions.IMapValue {
+	return o.Condition.GetExtensions()
+}
+
+// Valid checks the validity of the target CondEndorseSeriesTriple
+// nolint:gocritic
+func (o CondEndorseSeriesTriple) Valid() error {
+	if err := o.Condition.Valid(); err != nil {
+		return fmt.Errorf("condition validation failed: %w", err)
+	}
+
+	if o.Series.IsEmpty() {
+		return errors.New("series validation failed: no series entries")
+	}
+
+	if err := o.Series.Valid(); err != nil {
+		return fmt.Errorf("series validation failed: %w", err)
+	}
+
+	return nil
+}
+
+// CondEndorseSeriesTriples is a container for CondEndorseSeriesTriple
+// instances and their extensions.  It is a thin wrapper around
+// extensions.Collection.
+type CondEndorseSeriesTriples extensions.Collection[CondEndorseSeriesTriple, *CondEndorseSeriesTriple]
+
+func NewCondEndorseSeriesTriples() *CondEndorseSeriesTriples {
+	return (*CondEndorseSeriesTriples)(extensions.NewCollection[CondEndorseSeriesTriple]())
+}
+
+func (o *CondEndorseSeriesTriples) RegisterExtensions(exts extensions.Map) error {
+	return (*extensions.Collection[CondEndorseSeriesTriple, *CondEndorseSeriesTriple])(o).RegisterExtensions(exts)
+}
+
+func (o *CondEndorseSeriesTriples) GetExtensions() extensions.IMapValue {
+	return (*extensions.Collection[CondEndorseSeriesTriple, *CondEndorseSeriesTriple])(o).GetExtensions()
+}
+
+func (o CondEndorseSeriesTriples) Valid() error {
+	return (extensions.Collection[CondEndorseSeriesTriple, *CondEndorseSeriesTriple])(o).Valid()
+}
+
+func (o *CondEndorseSeriesTriples) IsEmpty() bool {
+	return (*extensions.Collection[CondEndorseSeriesTriple, *CondEndorseSeriesTriple])(o).IsEmpty()
+}
+
+func (o *CondEndorseSeriesTriples) Add(val *CondEndorseSeriesTriple) *CondEndorseSeriesTriples {
+	ret := (*extensions.Collection[CondEndorseSeriesTriple, *CondEndorseSeriesTriple])(o).Add(val)
+	return (*CondEndorseSeriesTriples)(ret)
+}
+
+func (o CondEndorseSeriesTriples) MarshalCBOR() ([]byte, error) {
+	return (extensions.Collection[CondEndorseSeriesTriple, *CondEndorseSeriesTriple])(o).MarshalCBOR()
+}
+
+func (o *CondEndorseSeriesTriples) UnmarshalCBOR(data []byte) error {
+	return (*extensions.Collection[CondEndorseSeriesTriple, *CondEndorseSeriesTriple])(o).UnmarshalCBOR(data)
+}
+
+func (o CondEndorseSeriesTriples) MarshalJSON() ([]byte, error) {
+	return (extensions.Collection[CondEndorseSeriesTriple, *CondEndorseSeriesTriple])(o).MarshalJSON()
+}
+
+func (o *CondEndorseSeriesTriples) UnmarshalJSON(data []byte) error {
+	return (*extensions.Collection[CondEndorseSeriesTriple, *CondEndorseSeriesTriple])(o).UnmarshalJSON(data)
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/extensions"
+)
+
+func testCondEnv() Environment {
+	return Environment{Class: NewClassUUID(TestUUID)}
+}
+
+func testCondEndorseTriple() *CondEndorseTriple {
+	return NewCondEndorseTriple().
+		AddCondition(ValueTriple{
+			Environment:  testCondEnv(),
+			Measurements: *NewMeasurements().Add(MustNewUintMeasurement(uint64(1)).SetMinSVN(5)),
+		}).
+		AddEndorsement(ValueTriple{
+			Environment:  testCondEnv(),
+			Measurements: *NewMeasurements().Add(MustNewUintMeasurement(uint64(1)).SetFlagsTrue(FlagIsSecure)),
+		})
+}
+
+func testCondEndorseSeriesTriple() *CondEndorseSeriesTriple {
+	return NewCondEndorseSeriesTriple(ValueTriple{
+		Environment:  testCondEnv(),
+		Measurements: *NewMeasurements().Add(MustNewUintMeasurement(uint64(1)).SetSVN(1)),
+	}).AddSeries(
+		*NewMeasurements().Add(MustNewUintMeasurement(uint64(1)).SetMinSVN(5)),
+		*NewMeasurements().Add(MustNewUintMeasurement(uint64(1)).SetFlagsTrue(FlagIsSecure)),
+	)
+}
+
+func TestCondEndorseTriple_Valid(t *testing.T) {
+	assert.NoError(t, testCondEndorseTriple().Valid())
+
+	tv := NewCondEndorseTriple()
+	assert.EqualError(t, tv.Valid(), "conditions validation failed: no condition entries")
+
+	tv.AddCondition(ValueTriple{})
+	assert.EqualError(t, tv.Valid(), "conditions validation failed: error at index 0: "+
+		"environment validation failed: environment must not be empty")
+
+	tv = testCondEndorseTriple()
+	tv.Endorsements = *NewValueTriples()
+	assert.EqualError(t, tv.Valid(), "endorsements validation failed: no endorsement entries")
+}
+
+func TestCondEndorseSeriesTriple_Valid(t *testing.T) {
+	assert.NoError(t, testCondEndorseSeriesTriple().Valid())
+
+	tv := NewCondEndorseSeriesTriple(ValueTriple{})
+	assert.EqualError(t, tv.Valid(), "condition validation failed: "+
+		"environment validation failed: environment must not be empty")
+
+	tv.Condition = testCondEndorseSeriesTriple().Condition
+	assert.EqualError(t, tv.Valid(), "series validation failed: no series entries")
+
+	tv.AddSeries(*NewMeasurements(), *NewMeasurements())
+	assert.EqualError(t, tv.Valid(), "series validation failed: error at index 0: "+
+		"selection validation failed: no measurement entries")
+}
+
+func TestComid_CondEndorse_roundtrip(t *testing.T) {
+	c := NewComid().
+		SetTagIdentity(TestTagID, 0).
+		AddCondEndorsement(*testCondEndorseTriple()).
+		AddCondEndorsementSeries(*testCondEndorseSeriesTriple())
+	require.NotNil(t, c)
+	require.NoError(t, c.Valid())
+
+	data, err := c.ToCBOR()
+	require.NoError(t, err)
+
+	var raw map[int]any
+	require.NoError(t, dm.Unmarshal(data, &raw))
+	tripleMap, ok := raw[4].(map[any]any)
+	require.True(t, ok)
+	assert.Contains(t, tripleMap, uint64(8))
+	assert.Contains(t, tripleMap, uint64(10))
+
+	var fromCBOR Comid
+	require.NoError(t, fromCBOR.FromCBOR(data))
+	require.NoError(t, fromCBOR.Valid())
+	require.NotNil(t, fromCBOR.Triples.CondEndorse)
+	require.Len(t, fromCBOR.Triples.CondEndorse.Values, 1)
+	assert.Equal(t, &True, fromCBOR.Triples.CondEndorse.Values[0].
+		Endorsements.Values[0].Measurements.Values[0].Val.Flags.IsSecure)
+	require.NotNil(t, fromCBOR.Triples.CondEndorseSeries)
+	require.Len(t, fromCBOR.Triples.CondEndorseSeries.Values[0].Series.Values, 1)
+
+	data, err = c.ToJSON()
+	require.NoError(t, err)
+
+	var fromJSON Comid
+	require.NoError(t, fromJSON.FromJSON(data))
+	require.NoError(t, fromJSON.Valid())
+	require.NotNil(t, fromJSON.Triples.CondEndorse)
+	assert.Len(t, fromJSON.Triples.CondEndorse.Values[0].Conditions.Values, 1)
+	require.NotNil(t, fromJSON.Triples.CondEndorseSeries)
+	assert.Len(t, fromJSON.Triples.CondEndorseSeries.Values[0].Series.Values[0].Addition.Values, 1)
+}
+
+func TestTriples_CondEndorse_extensions(t *testing.T) {
+	triples := Triples{}
+
+	extMap := extensions.NewMap().
+		Add(ExtCondEndorseValue, &testMvalMatcher{}).
+		Add(ExtCondEndorseValueFlags, &struct{}{}).
+		Add(ExtCondEndorseSeriesValue, &testMvalMatcher{}).
+		Add(ExtCondEndorseSeriesValueFlags, &struct{}{})
+	require.NoError(t, triples.RegisterExtensions(extMap))
+
+	triples.AddCondEndorsement(*testCondEndorseTriple())
+	triples.AddCondEndorsementSeries(*testCondEndorseSeriesTriple())
+
+	ce := triples.CondEndorse.Values[0]
+	_, ok := ce.Conditions.Values[0].Measurements.Values[0].Val.GetExtensions().(*testMvalMatcher)
+	assert.True(t, ok)
+	_, ok = ce.Endorsements.Values[0].Measurements.Values[0].Val.GetExtensions().(*testMvalMatcher)
+	assert.True(t, ok)
+
+	ces := triples.CondEndorseSeries.Values[0]
+	_, ok = ces.Series.Values[0].Addition.Values[0].Val.GetExtensions().(*testMvalMatcher)
+	assert.True(t, ok)
+
+	// empty collections are omitted
+	empty := Triples{}
+	require.NoError(t, empty.RegisterExtensions(extMap))
+	data, err := empty.MarshalCBOR()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xa0}, data)
+}
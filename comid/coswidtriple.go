@@ -0,0 +1,65 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/veraison/swid"
+)
+
+// CoswidTriple stores a coswid-triple-record, which associates an environment
+// with the CoSWID tags that describe the software components running in it.
+// The CoSWID tags are identified by their tag-id, and are typically carried in
+// the same CoRIM as the CoMID.  Note that the CBOR serialization packs the
+// structure into an array.  Instead, when serializing to JSON, the structure is
+// converted into an object.
+//
+//	coswid-triple-record = [
+//	  environment-map
+//	  [ + concise-swid-tag-id ]
+//	]
+type CoswidTriple struct {
+	_           struct{}     `cbor:",toarray"`
+	Environment Environment  `json:"environment"`
+	TagIDs      []swid.TagID `json:"coswid-tags"`
+}
+
+// AddTagID adds the supplied CoSWID tag-id, which MUST be of type string or
+// [16]byte, to the target CoswidTriple
+func (o *CoswidTriple) AddTagID(tagID interface{}) *CoswidTriple {
+	if o != nil {
+		id := swid.NewTagID(tagID)
+		if id == nil {
+			return nil
+		}
+		o.TagIDs = append(o.TagIDs, *id)
+	}
+	return o
+}
+
+func (o CoswidTriple) Valid() error {
+	if err := o.Environment.Valid(); err != nil {
+		return fmt.Errorf("environment validation failed: %w", err)
+	}
+
+	if len(o.TagIDs) == 0 {
+		return errors.New("tag-ids validation failed: no CoSWID tag-ids")
+	}
+
+	for i, id := range o.TagIDs {
+		if id == (swid.TagID{}) {
+			return fmt.Errorf("tag-ids validation failed: empty tag-id at index %d", i)
+		}
+	}
+
+	return nil
+}
+
+type CoswidTriples []CoswidTriple
+
+func NewCoswidTriples() *CoswidTriples {
+	return &CoswidTriples{}
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/swid"
+)
+
+func TestCoswidTriple_Valid(t *testing.T) {
+	ct := CoswidTriple{}
+	assert.EqualError(t, ct.Valid(), "environment validation failed: environment must not be empty")
+
+	ct.Environment = Environment{Instance: MustNewUEIDInstance(TestUEID)}
+	assert.EqualError(t, ct.Valid(), "tag-ids validation failed: no CoSWID tag-ids")
+
+	ct.TagIDs = []swid.TagID{{}}
+	assert.EqualError(t, ct.Valid(), "tag-ids validation failed: empty tag-id at index 0")
+
+	ct.TagIDs = nil
+	require.NotNil(t, ct.AddTagID("com.acme.fw-1.0"))
+	require.NotNil(t, ct.AddTagID(TestUUIDString))
+	assert.NoError(t, ct.Valid())
+
+	assert.Nil(t, ct.AddTagID(""))
+}
+
+func TestComid_CoswidTriple_roundtrip(t *testing.T) {
+	ct := CoswidTriple{Environment: Environment{Class: NewClassUUID(TestUUID)}}
+	require.NotNil(t, ct.AddTagID("com.acme.fw-1.0"))
+
+	c := NewComid().
+		SetTagIdentity(TestTagID, 0).
+		AddCoswidTriple(ct)
+	require.NotNil(t, c)
+	require.NoError(t, c.Valid())
+
+	data, err := c.ToCBOR()
+	require.NoError(t, err)
+
+	var fromCBOR Comid
+	require.NoError(t, fromCBOR.FromCBOR(data))
+	require.NotNil(t, fromCBOR.Triples.CoswidTriples)
+	require.Len(t, *fromCBOR.Triples.CoswidTriples, 1)
+	assert.Equal(t, "com.acme.fw-1.0", (*fromCBOR.Triples.CoswidTriples)[0].TagIDs[0].String())
+
+	data, err = c.ToJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"coswid-triples":[{"environment":`)
+
+	var fromJSON Comid
+	require.NoError(t, fromJSON.FromJSON(data))
+	require.NoError(t, fromJSON.Valid())
+	assert.Equal(t, "com.acme.fw-1.0", (*fromJSON.Triples.CoswidTriples)[0].TagIDs[0].String())
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DomainMembershipTriple stores a domain-membership-triple-record, which states
+// that the member environments are part of the domain identified by the
+// domain environment (e.g., the components of a composite device).  Note that
+// the CBOR serialization packs the structure into an array.  Instead, when
+// serializing to JSON, the structure is converted into an object.  Both the
+// domain and its members are identified by an environment-map.
+//
+//	domain-membership-triple-record = [
+//	  domain-id: domain-type
+//	  members: [ + domain-type ]
+//	]
+//
+//	domain-type = environment-map
+type DomainMembershipTriple struct {
+	_       struct{}      `cbor:",toarray"`
+	Domain  Environment   `json:"domain"`
+	Members []Environment `json:"members"`
+}
+
+func (o DomainMembershipTriple) Valid() error {
+	if err := o.Domain.Valid(); err != nil {
+		return fmt.Errorf("domain validation failed: %w", err)
+	}
+
+	if len(o.Members) == 0 {
+		return errors.New("members validation failed: no member environments")
+	}
+
+	for i, m := range o.Members {
+		if err := m.Valid(); err != nil {
+			return fmt.Errorf("member at index %d validation failed: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+type DomainMembershipTriples []DomainMembershipTriple
+
+func NewDomainMembershipTriples() *DomainMembershipTriples {
+	return &DomainMembershipTriples{}
+}
+
+// DomainDependencyTriple stores a domain-dependency-triple-record, which
+// states that the trustworthiness of the domain depends on that of each of the
+// trustee domains.  Note that the CBOR serialization packs the structure into
+// an array.  Instead, when serializing to JSON, the structure is converted into
+// an object.  Both the domain and its trustees are identified by an
+// environment-map.
+//
+//	domain-dependency-triple-record = [
+//	  domain-id: domain-type
+//	  trustees: [ + domain-type ]
+//	]
+//
+//	domain-type = environment-map
+type DomainDependencyTriple struct {
+	_        struct{}      `cbor:",toarray"`
+	Domain   Environment   `json:"domain"`
+	Trustees []Environment `json:"trustees"`
+}
+
+func (o DomainDependencyTriple) Valid() error {
+	if err := o.Domain.Valid(); err != nil {
+		return fmt.Errorf("domain validation failed: %w", err)
+	}
+
+	if len(o.Trustees) == 0 {
+		return errors.New("trustees validation failed: no trustee domains")
+	}
+
+	for i, t := range o.Trustees {
+		if err := t.Valid(); err != nil {
+			return fmt.Errorf("trustee at index %d validation failed: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+type DomainDependencyTriples []DomainDependencyTriple
+
+func NewDomainDependencyTriples() *DomainDependencyTriples {
+	return &DomainDependencyTriples{}
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDomainMembershipTriple_Valid(t *testing.T) {
+	tvs := []struct {
+		triple  DomainMembershipTriple
+		testerr string
+	}{
+		{
+			triple:  DomainMembershipTriple{},
+			testerr: "domain validation failed: environment must not be empty",
+		},
+		{
+			triple: DomainMembershipTriple{
+				Domain: Environment{Group: MustNewUUIDGroup(TestUUID)},
+			},
+			testerr: "members validation failed: no member environments",
+		},
+		{
+			triple: DomainMembershipTriple{
+				Domain:  Environment{Group: MustNewUUIDGroup(TestUUID)},
+				Members: []Environment{{Instance: MustNewUEIDInstance(TestUEID)}, {}},
+			},
+			testerr: "member at index 1 validation failed: environment must not be empty",
+		},
+		{
+			triple: DomainMembershipTriple{
+				Domain:  Environment{Group: MustNewUUIDGroup(TestUUID)},
+				Members: []Environment{{Instance: MustNewUEIDInstance(TestUEID)}},
+			},
+		},
+	}
+
+	for _, tv := range tvs {
+		err := tv.triple.Valid()
+		if tv.testerr == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tv.testerr)
+		}
+	}
+}
+
+func TestDomainDependencyTriple_Valid(t *testing.T) {
+	dt := DomainDependencyTriple{Domain: Environment{Group: MustNewUUIDGroup(TestUUID)}}
+	assert.EqualError(t, dt.Valid(), "trustees validation failed: no trustee domains")
+
+	dt.Trustees = []Environment{{}}
+	assert.EqualError(t, dt.Valid(),
+		"trustee at index 0 validation failed: environment must not be empty")
+
+	dt.Trustees = []Environment{{Class: NewClassUUID(TestUUID)}}
+	assert.NoError(t, dt.Valid())
+}
+
+func TestComid_DomainTriples_roundtrip(t *testing.T) {
+	soc := Environment{Class: NewClassUUID(TestUUID).SetModel("SoC")}
+	nic := Environment{Class: NewClassUUID(TestUUID).SetModel("NIC")}
+	device := Environment{Group: MustNewUUIDGroup(TestUUID)}
+
+	c := NewComid().
+		SetTagIdentity(TestTagID, 0).
+		AddDomainMembership(DomainMembershipTriple{
+			Domain:  device,
+			Members: []Environment{soc, nic},
+		}).
+		AddDomainDependency(DomainDependencyTriple{
+			Domain:   nic,
+			Trustees: []Environment{soc},
+		})
+	require.NotNil(t, c)
+	require.NoError(t, c.Valid())
+
+	data, err := c.ToCBOR()
+	require.NoError(t, err)
+
+	var raw map[int]any
+	require.NoError(t, dm.Unmarshal(data, &raw))
+	tripleMap, ok := raw[4].(map[any]any)
+	require.True(t, ok)
+	assert.Contains(t, tripleMap, uint64(4))
+	assert.Contains(t, tripleMap, uint64(5))
+
+	var fromCBOR Comid
+	require.NoError(t, fromCBOR.FromCBOR(data))
+	require.NotNil(t, fromCBOR.Triples.DomainMemberships)
+	require.Len(t, *fromCBOR.Triples.DomainMemberships, 1)
+	assert.Len(t, (*fromCBOR.Triples.DomainMemberships)[0].Members, 2)
+	require.NotNil(t, fromCBOR.Triples.DomainDependencies)
+	assert.True(t, nic.Match((*fromCBOR.Triples.DomainDependencies)[0].Domain))
+
+	data, err = c.ToJSON()
+	require.NoError(t, err)
+
+	var fromJSON Comid
+	require.NoError(t, fromJSON.FromJSON(data))
+	require.NoError(t, fromJSON.Valid())
+	require.NotNil(t, fromJSON.Triples.DomainDependencies)
+	assert.Len(t, (*fromJSON.Triples.DomainDependencies)[0].Trustees, 1)
+
+	c.Triples.DomainMemberships = &DomainMembershipTriples{{Domain: device}}
+	assert.EqualError(t, c.Valid(), "triples validation failed: domain membership at index 0: "+
+		"members validation failed: no member environments")
+}
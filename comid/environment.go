@@ -4,6 +4,7 @@
 package comid
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 )
@@ -45,6 +46,35 @@ func (o Environment) Valid() error {
 	return nil
 }
 
+// Match returns true if the supplied (claimed) Environment is covered by the
+// target, which is treated as the reference.  A class, instance or group that
+// is not set in the reference acts as a wildcard.  Otherwise, the claimed
+// Environment must carry the same element: class fields are compared as
+// described in Class.Match, while instance and group are compared by type and
+// value bytes (which means that profile-defined types registered via
+// RegisterInstanceType and RegisterGroupType are also supported).
+func (o Environment) Match(claimed Environment) bool {
+	if o.Class != nil {
+		if claimed.Class == nil || !o.Class.Match(*claimed.Class) {
+			return false
+		}
+	}
+
+	if o.Instance != nil {
+		if claimed.Instance == nil || !o.Instance.Equal(*claimed.Instance) {
+			return false
+		}
+	}
+
+	if o.Group != nil {
+		if claimed.Group == nil || !o.Group.Equal(*claimed.Group) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // ToCBOR serializes the target Environment to CBOR (if the Environment is "valid")
 func (o Environment) ToCBOR() ([]byte, error) {
 	if err := o.Valid(); err != nil {
@@ -80,3 +110,16 @@ func (o Environment) ToJSON() ([]byte, error) {
 
 	return json.Marshal(&o)
 }
+
+// typeChoiceEqual compares two type choice values (ClassID, Instance or Group
+// values) by type name and value bytes.  Two nil values are equal.
+func typeChoiceEqual(a, b interface {
+	Type() string
+	Bytes() []byte
+}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return a.Type() == b.Type() && bytes.Equal(a.Bytes(), b.Bytes())
+}
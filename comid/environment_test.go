@@ -219,3 +219,99 @@ func TestEnviroment_JSON(t *testing.T) {
 	err = outEnv.FromJSON([]byte(`{"class": 7}`))
 	assert.EqualError(t, err, "json: cannot unmarshal number into Go struct field Environment.class of type comid.Class")
 }
+
+func TestEnvironment_Match(t *testing.T) {
+	decodedInstance := MustNewUEIDInstance(TestUEID)
+	data, err := decodedInstance.MarshalCBOR()
+	require.NoError(t, err)
+	require.NoError(t, decodedInstance.UnmarshalCBOR(data))
+
+	evidence := Environment{
+		Class: NewClassUUID(TestUUID).
+			SetVendor("ACME Ltd.").
+			SetModel("RoadRunner").
+			SetLayer(1),
+		Instance: decodedInstance,
+		Group:    MustNewUUIDGroup(TestUUID),
+	}
+
+	tvs := []struct {
+		desc     string
+		ref      Environment
+		expected bool
+	}{
+		{
+			desc:     "class id only",
+			ref:      Environment{Class: NewClassUUID(TestUUID)},
+			expected: true,
+		},
+		{
+			desc:     "vendor and model only",
+			ref:      Environment{Class: &Class{Vendor: evidence.Class.Vendor, Model: evidence.Class.Model}},
+			expected: true,
+		},
+		{
+			desc:     "different vendor",
+			ref:      Environment{Class: NewClassUUID(TestUUID).SetVendor("EMCA Ltd.")},
+			expected: false,
+		},
+		{
+			desc:     "index not in evidence",
+			ref:      Environment{Class: NewClassUUID(TestUUID).SetIndex(0)},
+			expected: false,
+		},
+		{
+			desc:     "different class id type",
+			ref:      Environment{Class: NewClassImplID(TestImplID)},
+			expected: false,
+		},
+		{
+			desc:     "instance only",
+			ref:      Environment{Instance: MustNewUEIDInstance(TestUEID)},
+			expected: true,
+		},
+		{
+			desc:     "same bytes, different instance type",
+			ref:      Environment{Instance: &Instance{TaggedBytes(TestUEID)}},
+			expected: false,
+		},
+		{
+			desc: "everything",
+			ref: Environment{
+				Class:    NewClassUUID(TestUUID).SetLayer(1),
+				Instance: MustNewUEIDInstance(TestUEID),
+				Group:    MustNewUUIDGroup(TestUUIDString),
+			},
+			expected: true,
+		},
+		{
+			desc:     "different group",
+			ref:      Environment{Group: MustNewUUIDGroup("69e027b2-7157-4758-bcb4-d9f167fe49ea")},
+			expected: false,
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			assert.Equal(t, tv.expected, tv.ref.Match(evidence))
+		})
+	}
+
+	// evidence missing elements required by the reference
+	ref := Environment{Instance: MustNewUEIDInstance(TestUEID)}
+	assert.False(t, ref.Match(Environment{Class: NewClassUUID(TestUUID)}))
+	assert.True(t, Environment{}.Match(evidence))
+}
+
+func TestEnvironment_Match_extension_types(t *testing.T) {
+	inst := testInstance("foo")
+	grp := testGroup(7)
+
+	ref := Environment{Instance: &Instance{&inst}, Group: &Group{grp}}
+	evidence := Environment{Instance: &Instance{inst}, Group: &Group{&grp}}
+	assert.True(t, ref.Match(evidence))
+
+	other := testInstance("bar")
+	evidence.Instance = &Instance{&other}
+	assert.False(t, ref.Match(evidence))
+}
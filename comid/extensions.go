@@ -16,6 +16,11 @@ const (
 	ExtEndorsedValueFlags  extensions.Point = "EndorsedValueFlags"
 	ExtMval                extensions.Point = "Mval"
 	ExtFlags               extensions.Point = "Flags"
+
+	ExtCondEndorseValue            extensions.Point = "CondEndorseValue"
+	ExtCondEndorseValueFlags       extensions.Point = "CondEndorseValueFlags"
+	ExtCondEndorseSeriesValue      extensions.Point = "CondEndorseSeriesValue"
+	ExtCondEndorseSeriesValueFlags extensions.Point = "CondEndorseSeriesValueFlags"
 )
 
 type IComidConstrainer interface {
@@ -30,6 +35,13 @@ type IMvalConstrainer interface {
 	ConstrainMval(*Mval) error
 }
 
+// IMvalMatcher may be implemented by Mval extensions that need to take part in
+// reference value matching (see Mval.Match).  The method is invoked on the
+// reference's extensions and is passed the claimed measurement-values-map.
+type IMvalMatcher interface {
+	MatchMval(claimed *Mval) error
+}
+
 type IEntityConstrainer interface {
 	ConstrainEntity(*Entity) error
 }
@@ -95,6 +107,21 @@ func (o *Extensions) validMval(triples *Mval) error {
 	return nil
 }
 
+func (o *Extensions) matchMval(claimed *Mval) error {
+	if !o.HaveExtensions() {
+		return nil
+	}
+
+	ev, ok := o.IMapValue.(IMvalMatcher)
+	if ok {
+		if err := ev.MatchMval(claimed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (o *Extensions) validEntity(triples *Entity) error {
 	if !o.HaveExtensions() {
 		return nil
@@ -59,6 +59,12 @@ func (o Group) Bytes() []byte {
 	return o.Value.Bytes()
 }
 
+// Equal returns true if the target Group and the supplied one are of the same
+// type and have the same value bytes.
+func (o Group) Equal(other Group) bool {
+	return typeChoiceEqual(o.Value, other.Value)
+}
+
 // MarshalCBOR serializes the target group to CBOR
 func (o Group) MarshalCBOR() ([]byte, error) {
 	return em.Marshal(o.Value)
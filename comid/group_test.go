@@ -190,3 +190,14 @@ func TestGroup_UnmarshalJSON_Bytes_NOK(t *testing.T) {
 		})
 	}
 }
+
+func TestGroup_Equal(t *testing.T) {
+	a := MustNewUUIDGroup(TestUUID)
+	b := &Group{TaggedUUID(TestUUID)}
+	c, err := NewBytesGroup(TestUUID[:])
+	require.NoError(t, err)
+
+	assert.True(t, a.Equal(*b))
+	assert.False(t, a.Equal(*c))
+	assert.False(t, a.Equal(Group{}))
+}
@@ -57,6 +57,12 @@ func (o Instance) Bytes() []byte {
 	return o.Value.Bytes()
 }
 
+// Equal returns true if the target Instance and the supplied one are of the
+// same type and have the same value bytes.
+func (o Instance) Equal(other Instance) bool {
+	return typeChoiceEqual(o.Value, other.Value)
+}
+
 // MarshalCBOR serializes the target instance to CBOR
 func (o Instance) MarshalCBOR() ([]byte, error) {
 	return em.Marshal(o.Value)
@@ -217,3 +217,14 @@ func TestInstance_UnmarshalJSON_Bytes_NOK(t *testing.T) {
 		})
 	}
 }
+
+func TestInstance_Equal(t *testing.T) {
+	a := MustNewUEIDInstance(TestUEID)
+	b := &Instance{TaggedUEID(TestUEID)}
+	c, err := NewBytesInstance([]byte(TestUEID))
+	require.NoError(t, err)
+
+	assert.True(t, a.Equal(*b))
+	assert.False(t, a.Equal(*c))
+	assert.False(t, a.Equal(Instance{}))
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/veraison/eat"
+)
+
+// KeyTripleType identifies the triples list a KeyTriple has been sourced from
+type KeyTripleType int
+
+const (
+	// KeyTripleAttestVerif is an attest-key-triple (Triples.AttestVerifKeys)
+	KeyTripleAttestVerif KeyTripleType = iota
+	// KeyTripleDevIdentity is an identity-triple (Triples.DevIdentityKeys)
+	KeyTripleDevIdentity
+)
+
+// String returns the string representation of the KeyTripleType
+func (o KeyTripleType) String() string {
+	switch o {
+	case KeyTripleAttestVerif:
+		return "attester-verification-keys"
+	case KeyTripleDevIdentity:
+		return "dev-identity-keys"
+	default:
+		return fmt.Sprintf("KeyTripleType(%d)", o)
+	}
+}
+
+// KeyIndexEntry is a key triple stored in a KeyIndex, together with the
+// identity of the CoMID that has asserted it
+type KeyIndexEntry struct {
+	Type        KeyTripleType
+	Environment Environment
+	VerifKeys   CryptoKeys
+	Source      TagIdentity
+}
+
+// KeyIndex indexes the attester verification and device identity keys of a
+// number of CoMIDs, so that the keys associated with an environment can be
+// looked up without scanning each CoMID's triples.  Triples that are bound to
+// an instance are bucketed by the instance's type and value; the remaining
+// ones (e.g., bound to a class or a group) are kept in a separate list that is
+// searched on each lookup.
+type KeyIndex struct {
+	entries  []KeyIndexEntry
+	byInst   [2]map[string][]int
+	noInst   [2][]int
+	numComid int
+}
+
+// NewKeyIndex instantiates an empty KeyIndex
+func NewKeyIndex() *KeyIndex {
+	return &KeyIndex{
+		byInst: [2]map[string][]int{
+			KeyTripleAttestVerif: make(map[string][]int),
+			KeyTripleDevIdentity: make(map[string][]int),
+		},
+	}
+}
+
+// AddComid adds the attest-key-triples and identity-triples of the supplied
+// CoMID to the target index.  The CoMID is validated before being added.
+func (o *KeyIndex) AddComid(c *Comid) error {
+	if o == nil {
+		return errors.New("nil KeyIndex")
+	}
+
+	if c == nil {
+		return errors.New("nil CoMID")
+	}
+
+	if err := c.Valid(); err != nil {
+		return fmt.Errorf("invalid CoMID: %w", err)
+	}
+
+	o.addKeyTriples(KeyTripleAttestVerif, c.Triples.AttestVerifKeys, c.TagIdentity)
+	o.addKeyTriples(KeyTripleDevIdentity, c.Triples.DevIdentityKeys, c.TagIdentity)
+	o.numComid++
+
+	return nil
+}
+
+func (o *KeyIndex) addKeyTriples(typ KeyTripleType, kts *KeyTriples, source TagIdentity) {
+	if kts == nil {
+		return
+	}
+
+	for _, kt := range *kts {
+		i := len(o.entries)
+
+		o.entries = append(o.entries, KeyIndexEntry{
+			Type:        typ,
+			Environment: kt.Environment,
+			VerifKeys:   kt.VerifKeys,
+			Source:      source,
+		})
+
+		if kt.Environment.Instance != nil {
+			k := instanceIndexKey(*kt.Environment.Instance)
+			o.byInst[typ][k] = append(o.byInst[typ][k], i)
+		} else {
+			o.noInst[typ] = append(o.noInst[typ], i)
+		}
+	}
+}
+
+// Len returns the number of key triples in the target index
+func (o KeyIndex) Len() int {
+	return len(o.entries)
+}
+
+// NumComids returns the number of CoMIDs that have been added to the target
+// index
+func (o KeyIndex) NumComids() int {
+	return o.numComid
+}
+
+// Lookup returns the entries of the specified type whose environment matches
+// the supplied one (see Environment.Match), i.e., the keys that may be used to
+// verify evidence (or identity claims) from env.  Entries are returned in the
+// order in which they were added.
+func (o KeyIndex) Lookup(typ KeyTripleType, env Environment) []KeyIndexEntry {
+	if typ != KeyTripleAttestVerif && typ != KeyTripleDevIdentity {
+		return nil
+	}
+
+	candidates := o.noInst[typ]
+
+	// a triple bound to an instance can only match an environment that
+	// carries the same instance
+	if env.Instance != nil {
+		candidates = mergeIndexes(candidates, o.byInst[typ][instanceIndexKey(*env.Instance)])
+	}
+
+	var ret []KeyIndexEntry
+
+	for _, i := range candidates {
+		if o.entries[i].Environment.Match(env) {
+			ret = append(ret, o.entries[i])
+		}
+	}
+
+	return ret
+}
+
+// LookupInstance returns the entries of the specified type that are bound to
+// the supplied instance, irrespective of any class or group that they may also
+// be bound to
+func (o KeyIndex) LookupInstance(typ KeyTripleType, inst Instance) []KeyIndexEntry {
+	if typ != KeyTripleAttestVerif && typ != KeyTripleDevIdentity {
+		return nil
+	}
+
+	idx := o.byInst[typ][instanceIndexKey(inst)]
+	if len(idx) == 0 {
+		return nil
+	}
+
+	ret := make([]KeyIndexEntry, 0, len(idx))
+
+	for _, i := range idx {
+		ret = append(ret, o.entries[i])
+	}
+
+	return ret
+}
+
+// LookupUEID is like LookupInstance for an instance identified by the supplied
+// UEID
+func (o KeyIndex) LookupUEID(typ KeyTripleType, ueid eat.UEID) ([]KeyIndexEntry, error) {
+	inst, err := NewUEIDInstance(ueid)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.LookupInstance(typ, *inst), nil
+}
+
+func instanceIndexKey(inst Instance) string {
+	if inst.Value == nil {
+		return ""
+	}
+
+	return inst.Type() + ":" + string(inst.Bytes())
+}
+
+// mergeIndexes merges two sorted lists of indexes into a new sorted list,
+// dropping any index that appears in both
+func mergeIndexes(a, b []int) []int {
+	ret := make([]int, 0, len(a)+len(b))
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			ret = append(ret, a[i])
+			i++
+		case a[i] > b[j]:
+			ret = append(ret, b[j])
+			j++
+		default:
+			ret = append(ret, a[i])
+			i++
+			j++
+		}
+	}
+
+	ret = append(ret, a[i:]...)
+	ret = append(ret, b[j:]...)
+
+	return ret
+}
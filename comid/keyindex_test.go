@@ -0,0 +1,129 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/eat"
+)
+
+func testKeyIndex(t *testing.T) *KeyIndex {
+	byInstance := NewComid().
+		SetTagIdentity("urn:example:instance-keys", 1).
+		AddAttestVerifKey(KeyTriple{
+			Environment: Environment{Instance: MustNewUEIDInstance(TestUEID)},
+			VerifKeys:   *NewCryptoKeys().Add(MustNewPKIXBase64Key(TestECPubKey)),
+		}).
+		AddDevIdentityKey(KeyTriple{
+			Environment: Environment{
+				Class:    NewClassUUID(TestUUID),
+				Instance: MustNewUEIDInstance(TestUEID),
+			},
+			VerifKeys: *NewCryptoKeys().Add(MustNewPKIXBase64Cert(TestCert)),
+		})
+	require.NotNil(t, byInstance)
+
+	byClass := NewComid().
+		SetTagIdentity("urn:example:class-keys", 0).
+		AddAttestVerifKey(KeyTriple{
+			Environment: Environment{Class: NewClassUUID(TestUUID)},
+			VerifKeys:   *NewCryptoKeys().Add(MustNewPKIXBase64Cert(TestCert)),
+		}).
+		AddAttestVerifKey(KeyTriple{
+			Environment: Environment{
+				Instance: MustNewUEIDInstance(eat.UEID(MustHexDecode(t, "02cafecafecafe"))),
+			},
+			VerifKeys: *NewCryptoKeys().Add(MustNewPKIXBase64Key(TestECPubKey)),
+		})
+	require.NotNil(t, byClass)
+
+	idx := NewKeyIndex()
+	require.NoError(t, idx.AddComid(byInstance))
+	require.NoError(t, idx.AddComid(byClass))
+
+	return idx
+}
+
+func TestKeyIndex_Lookup(t *testing.T) {
+	idx := testKeyIndex(t)
+	assert.Equal(t, 4, idx.Len())
+	assert.Equal(t, 2, idx.NumComids())
+
+	env := Environment{
+		Class:    NewClassUUID(TestUUID).SetVendor("ACME Ltd."),
+		Instance: MustNewUEIDInstance(TestUEID),
+	}
+
+	res := idx.Lookup(KeyTripleAttestVerif, env)
+	require.Len(t, res, 2)
+	assert.Equal(t, "urn:example:instance-keys", res[0].Source.TagID.String())
+	assert.Equal(t, uint(1), res[0].Source.TagVersion)
+	assert.Equal(t, KeyTripleAttestVerif, res[0].Type)
+	assert.Equal(t, "urn:example:class-keys", res[1].Source.TagID.String())
+
+	res = idx.Lookup(KeyTripleDevIdentity, env)
+	require.Len(t, res, 1)
+	assert.Equal(t, "urn:example:instance-keys", res[0].Source.TagID.String())
+
+	// class-only environment is not matched by instance-bound triples
+	res = idx.Lookup(KeyTripleAttestVerif, Environment{Class: NewClassUUID(TestUUID)})
+	require.Len(t, res, 1)
+	assert.Equal(t, "urn:example:class-keys", res[0].Source.TagID.String())
+
+	assert.Empty(t, idx.Lookup(KeyTripleDevIdentity, Environment{Group: MustNewUUIDGroup(TestUUID)}))
+	assert.Empty(t, idx.Lookup(KeyTripleType(5), env))
+}
+
+func TestKeyIndex_LookupInstance(t *testing.T) {
+	idx := testKeyIndex(t)
+
+	res := idx.LookupInstance(KeyTripleDevIdentity, *MustNewUEIDInstance(TestUEID))
+	require.Len(t, res, 1)
+	assert.Equal(t, "urn:example:instance-keys", res[0].Source.TagID.String())
+
+	res, err := idx.LookupUEID(KeyTripleAttestVerif, eat.UEID(MustHexDecode(t, "02cafecafecafe")))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, "urn:example:class-keys", res[0].Source.TagID.String())
+
+	res = idx.LookupInstance(KeyTripleAttestVerif, *MustNewUUIDInstance(TestUUID))
+	assert.Empty(t, res)
+
+	_, err = idx.LookupUEID(KeyTripleAttestVerif, eat.UEID{0xff})
+	assert.Error(t, err)
+}
+
+func TestKeyIndex_AddComid_invalid(t *testing.T) {
+	idx := NewKeyIndex()
+
+	assert.EqualError(t, idx.AddComid(nil), "nil CoMID")
+	assert.EqualError(t, idx.AddComid(NewComid()),
+		"invalid CoMID: tag-identity validation failed: empty tag-id")
+
+	var nilIdx *KeyIndex
+	assert.EqualError(t, nilIdx.AddComid(NewComid()), "nil KeyIndex")
+
+	assert.Equal(t, "dev-identity-keys", KeyTripleDevIdentity.String())
+	assert.Equal(t, "KeyTripleType(5)", KeyTripleType(5).String())
+}
+
+func Test_mergeIndexes(t *testing.T) {
+	tvs := []struct {
+		a, b, expected []int
+	}{
+		{a: nil, b: nil, expected: []int{}},
+		{a: []int{1, 3}, b: nil, expected: []int{1, 3}},
+		{a: nil, b: []int{0, 2}, expected: []int{0, 2}},
+		{a: []int{0, 2, 4}, b: []int{1, 3, 5, 6}, expected: []int{0, 1, 2, 3, 4, 5, 6}},
+		{a: []int{1, 2, 5}, b: []int{2, 5, 7}, expected: []int{1, 2, 5, 7}},
+		{a: []int{3}, b: []int{3}, expected: []int{3}},
+	}
+
+	for _, tv := range tvs {
+		assert.Equal(t, tv.expected, mergeIndexes(tv.a, tv.b))
+	}
+}
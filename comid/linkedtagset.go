@@ -0,0 +1,430 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/veraison/swid"
+)
+
+// RemovalReason describes why a CoMID is not part of the effective set
+// computed by ResolveLinkedTags
+type RemovalReason int
+
+const (
+	// RemovedSuperseded is used for a CoMID that has a newer TagVersion of
+	// the same tag-id in the set
+	RemovedSuperseded RemovalReason = iota
+	// RemovedDuplicate is used for a CoMID with the same tag-id and
+	// TagVersion as one that appears earlier in the set
+	RemovedDuplicate
+	// RemovedReplaced is used for a CoMID whose tag-id is the target of a
+	// "replaces" link
+	RemovedReplaced
+	// RemovedBaseRemoved is used for a supplement none of whose base CoMIDs
+	// is effective
+	RemovedBaseRemoved
+)
+
+func (o RemovalReason) String() string {
+	switch o {
+	case RemovedSuperseded:
+		return "superseded"
+	case RemovedDuplicate:
+		return "duplicate"
+	case RemovedReplaced:
+		return "replaced"
+	case RemovedBaseRemoved:
+		return "base removed"
+	default:
+		return fmt.Sprintf("RemovalReason(%d)", o)
+	}
+}
+
+// RemovedTag is a CoMID that is not part of the effective set
+type RemovedTag struct {
+	Comid  *Comid
+	Reason RemovalReason
+	// By is the identity of the CoMID that caused the removal (i.e., the
+	// newer version, the earlier duplicate, the replacing CoMID, or one of
+	// the removed bases)
+	By TagIdentity
+}
+
+// LinkDiagnosticKind identifies the type of problem reported by a
+// LinkDiagnostic
+type LinkDiagnosticKind int
+
+const (
+	// DiagDanglingLink is used for a linked-tag whose target is not in the set
+	DiagDanglingLink LinkDiagnosticKind = iota
+	// DiagReplacementCycle is used for CoMIDs that (directly or indirectly)
+	// replace each other.  The "replaces" links that form the cycle are
+	// ignored.
+	DiagReplacementCycle
+	// DiagDuplicateTag is used for a CoMID with the same tag-id and
+	// TagVersion as one that appears earlier in the set
+	DiagDuplicateTag
+)
+
+func (o LinkDiagnosticKind) String() string {
+	switch o {
+	case DiagDanglingLink:
+		return "dangling link"
+	case DiagReplacementCycle:
+		return "replacement cycle"
+	case DiagDuplicateTag:
+		return "duplicate tag"
+	default:
+		return fmt.Sprintf("LinkDiagnosticKind(%d)", o)
+	}
+}
+
+// LinkDiagnostic reports a problem with the linked-tags of a set of CoMIDs
+type LinkDiagnostic struct {
+	Kind LinkDiagnosticKind
+	// Source is the CoMID the problem was found in
+	Source TagIdentity
+	// Rel and Target are the relation and the (missing) target of a dangling
+	// link
+	Rel    Rel
+	Target swid.TagID
+	// Cycle lists the CoMIDs that form a replacement cycle
+	Cycle []TagIdentity
+}
+
+func (o LinkDiagnostic) String() string {
+	switch o.Kind {
+	case DiagDanglingLink:
+		return fmt.Sprintf("%s: %s %s unknown tag %s", o.Kind, o.Source, o.Rel, o.Target.String())
+	case DiagReplacementCycle:
+		ids := make([]string, 0, len(o.Cycle))
+		for _, c := range o.Cycle {
+			ids = append(ids, c.String())
+		}
+		return fmt.Sprintf("%s: %s", o.Kind, strings.Join(ids, ", "))
+	default:
+		return fmt.Sprintf("%s: %s", o.Kind, o.Source)
+	}
+}
+
+// EffectiveTag is a CoMID in the effective set, together with the effective
+// CoMIDs that supplement it
+type EffectiveTag struct {
+	Comid       *Comid
+	Supplements []*Comid
+}
+
+// LinkedTagSet is the result of ResolveLinkedTags
+type LinkedTagSet struct {
+	// Effective lists the CoMIDs that are in force, in input order.
+	// Supplements are listed both on their own and attached to their bases.
+	Effective []EffectiveTag
+	// Removed lists the CoMIDs that are not in force, in input order
+	Removed []RemovedTag
+	// Diagnostics lists the problems found with the linked-tags
+	Diagnostics []LinkDiagnostic
+}
+
+// Comids returns the effective CoMIDs
+func (o LinkedTagSet) Comids() []*Comid {
+	ret := make([]*Comid, 0, len(o.Effective))
+	for _, e := range o.Effective {
+		ret = append(ret, e.Comid)
+	}
+	return ret
+}
+
+// ResolveLinkedTags computes the effective set of the supplied CoMIDs,
+// according to their tag identities and linked-tags:
+//
+//   - of the CoMIDs that share a tag-id, only the one with the highest
+//     TagVersion is considered;
+//   - a CoMID that is the target of a "replaces" link is removed, whatever
+//     its TagVersion, and whether or not the replacing CoMID is itself
+//     replaced;
+//   - a CoMID that "supplements" another is attached to it, and is removed if
+//     none of its bases is effective.
+//
+// Links from a CoMID to its own tag-id are ignored.  Links to tag-ids that are
+// not in the set, as well as replacement cycles, are reported as diagnostics.
+// An error is returned only if one of the CoMIDs is nil or has an invalid
+// tag-identity or linked-tags.
+func ResolveLinkedTags(tags ...*Comid) (*LinkedTagSet, error) {
+	r := linkResolver{
+		tags:    tags,
+		latest:  make(map[string]int),
+		removed: make(map[int]RemovedTag),
+	}
+
+	if err := r.selectVersions(); err != nil {
+		return nil, err
+	}
+
+	r.collectLinks()
+	r.applyReplaces()
+	r.applySupplements()
+
+	return r.result(), nil
+}
+
+type linkResolver struct {
+	tags []*Comid
+
+	// index of the latest version of each tag-id
+	latest map[string]int
+	// indices of the latest versions, in input order
+	live []int
+
+	// replaces and bases map an index in live to the indices (in tags) of
+	// its link targets
+	replaces map[int][]int
+	bases    map[int][]int
+
+	removed     map[int]RemovedTag
+	diagnostics []LinkDiagnostic
+}
+
+func (o *linkResolver) selectVersions() error {
+	for i, t := range o.tags {
+		if t == nil {
+			return fmt.Errorf("nil CoMID at index %d", i)
+		}
+
+		if err := t.TagIdentity.Valid(); err != nil {
+			return fmt.Errorf("CoMID at index %d: tag-identity validation failed: %w", i, err)
+		}
+
+		if t.LinkedTags != nil {
+			if err := t.LinkedTags.Valid(); err != nil {
+				return fmt.Errorf("CoMID at index %d: linked-tags validation failed: %w", i, err)
+			}
+		}
+
+		key := t.TagIdentity.TagID.String()
+
+		j, ok := o.latest[key]
+		if !ok {
+			o.latest[key] = i
+			continue
+		}
+
+		prev := o.tags[j]
+
+		switch {
+		case t.TagIdentity.TagVersion > prev.TagIdentity.TagVersion:
+			o.removed[j] = RemovedTag{prev, RemovedSuperseded, t.TagIdentity}
+			o.latest[key] = i
+		case t.TagIdentity.TagVersion < prev.TagIdentity.TagVersion:
+			o.removed[i] = RemovedTag{t, RemovedSuperseded, prev.TagIdentity}
+		default:
+			o.removed[i] = RemovedTag{t, RemovedDuplicate, prev.TagIdentity}
+			o.diagnostics = append(o.diagnostics, LinkDiagnostic{
+				Kind:   DiagDuplicateTag,
+				Source: t.TagIdentity,
+			})
+		}
+	}
+
+	for i, t := range o.tags {
+		if o.latest[t.TagIdentity.TagID.String()] == i {
+			o.live = append(o.live, i)
+		}
+	}
+
+	return nil
+}
+
+func (o *linkResolver) collectLinks() {
+	o.replaces = make(map[int][]int)
+	o.bases = make(map[int][]int)
+
+	for _, i := range o.live {
+		t := o.tags[i]
+		if t.LinkedTags == nil {
+			continue
+		}
+
+		for _, lt := range *t.LinkedTags {
+			key := lt.LinkedTagID.String()
+			if key == t.TagIdentity.TagID.String() {
+				continue
+			}
+
+			j, ok := o.latest[key]
+			if !ok {
+				o.diagnostics = append(o.diagnostics, LinkDiagnostic{
+					Kind:   DiagDanglingLink,
+					Source: t.TagIdentity,
+					Rel:    lt.Rel,
+					Target: lt.LinkedTagID,
+				})
+				continue
+			}
+
+			switch lt.Rel {
+			case RelReplaces:
+				o.replaces[i] = append(o.replaces[i], j)
+			case RelSupplements:
+				o.bases[i] = append(o.bases[i], j)
+			}
+		}
+	}
+}
+
+// applyReplaces removes the targets of "replaces" links, ignoring the links
+// within a replacement cycle (i.e., a strongly connected component of the
+// replacement graph)
+func (o *linkResolver) applyReplaces() {
+	comp := o.replacementComponents()
+
+	for _, i := range o.live {
+		for _, j := range o.replaces[i] {
+			if comp[i] == comp[j] {
+				continue
+			}
+
+			if _, ok := o.removed[j]; !ok {
+				o.removed[j] = RemovedTag{o.tags[j], RemovedReplaced, o.tags[i].TagIdentity}
+			}
+		}
+	}
+}
+
+// replacementComponents computes the strongly connected components of the
+// replacement graph using Tarjan's algorithm, reports the non-trivial ones as
+// cycles, and returns the component of each (live) CoMID
+func (o *linkResolver) replacementComponents() map[int]int {
+	var (
+		next    int
+		stack   []int
+		index   = make(map[int]int)
+		low     = make(map[int]int)
+		onStack = make(map[int]bool)
+		comp    = make(map[int]int)
+		visit   func(int)
+	)
+
+	visit = func(v int) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range o.replaces[v] {
+			if _, ok := index[w]; !ok {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+
+		if low[v] != index[v] {
+			return
+		}
+
+		var members []int
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			comp[w] = v
+			members = append(members, w)
+			if w == v {
+				break
+			}
+		}
+
+		if len(members) > 1 {
+			o.reportCycle(members)
+		}
+	}
+
+	for _, i := range o.live {
+		if _, ok := index[i]; !ok {
+			visit(i)
+		}
+	}
+
+	return comp
+}
+
+func (o *linkResolver) reportCycle(members []int) {
+	sort.Ints(members)
+
+	cycle := make([]TagIdentity, 0, len(members))
+	for _, m := range members {
+		cycle = append(cycle, o.tags[m].TagIdentity)
+	}
+
+	o.diagnostics = append(o.diagnostics, LinkDiagnostic{
+		Kind:   DiagReplacementCycle,
+		Source: cycle[0],
+		Cycle:  cycle,
+	})
+}
+
+// applySupplements removes the supplements whose bases have all been removed,
+// until no more supplements can be removed
+func (o *linkResolver) applySupplements() {
+	for changed := true; changed; {
+		changed = false
+
+		for _, i := range o.live {
+			if _, ok := o.removed[i]; ok || len(o.bases[i]) == 0 {
+				continue
+			}
+
+			if o.anyEffective(o.bases[i]) {
+				continue
+			}
+
+			base := o.tags[o.bases[i][0]]
+			o.removed[i] = RemovedTag{o.tags[i], RemovedBaseRemoved, base.TagIdentity}
+			changed = true
+		}
+	}
+}
+
+func (o *linkResolver) anyEffective(indices []int) bool {
+	for _, j := range indices {
+		if _, ok := o.removed[j]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *linkResolver) result() *LinkedTagSet {
+	ret := LinkedTagSet{Diagnostics: o.diagnostics}
+	pos := make(map[int]int)
+
+	for i, t := range o.tags {
+		if r, ok := o.removed[i]; ok {
+			ret.Removed = append(ret.Removed, r)
+			continue
+		}
+
+		pos[i] = len(ret.Effective)
+		ret.Effective = append(ret.Effective, EffectiveTag{Comid: t})
+	}
+
+	for _, i := range o.live {
+		if _, ok := pos[i]; !ok {
+			continue
+		}
+
+		for _, b := range o.bases[i] {
+			if p, ok := pos[b]; ok {
+				ret.Effective[p].Supplements = append(ret.Effective[p].Supplements, o.tags[i])
+			}
+		}
+	}
+
+	return &ret
+}
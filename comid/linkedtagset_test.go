@@ -0,0 +1,138 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func linkedTestComid(t *testing.T, id string, version uint) *Comid {
+	c := NewComid().SetTagIdentity(id, version)
+	require.NotNil(t, c)
+	return c
+}
+
+func removedIDs(s *LinkedTagSet) map[string]RemovalReason {
+	ret := make(map[string]RemovalReason)
+	for _, r := range s.Removed {
+		ret[r.Comid.TagIdentity.String()] = r.Reason
+	}
+	return ret
+}
+
+func TestResolveLinkedTags_versions(t *testing.T) {
+	v1 := linkedTestComid(t, "urn:example:base", 1)
+	v3 := linkedTestComid(t, "urn:example:base", 3)
+	v2 := linkedTestComid(t, "urn:example:base", 2)
+	dup := linkedTestComid(t, "urn:example:base", 3)
+
+	s, err := ResolveLinkedTags(v1, v3, v2, dup)
+	require.NoError(t, err)
+
+	assert.Equal(t, []*Comid{v3}, s.Comids())
+	assert.Equal(t, map[string]RemovalReason{
+		"urn:example:base (version 1)": RemovedSuperseded,
+		"urn:example:base (version 2)": RemovedSuperseded,
+		"urn:example:base (version 3)": RemovedDuplicate,
+	}, removedIDs(s))
+
+	require.Len(t, s.Diagnostics, 1)
+	assert.Equal(t, "duplicate tag: urn:example:base (version 3)", s.Diagnostics[0].String())
+}
+
+func TestResolveLinkedTags_replaces(t *testing.T) {
+	a := linkedTestComid(t, "urn:example:a", 0)
+	b := linkedTestComid(t, "urn:example:b", 0).AddLinkedTag("urn:example:a", RelReplaces)
+	c := linkedTestComid(t, "urn:example:c", 0).AddLinkedTag("urn:example:b", RelReplaces)
+	// a newer version of a is still replaced
+	a2 := linkedTestComid(t, "urn:example:a", 1)
+
+	s, err := ResolveLinkedTags(a, b, c, a2)
+	require.NoError(t, err)
+
+	assert.Equal(t, []*Comid{c}, s.Comids())
+	assert.Equal(t, map[string]RemovalReason{
+		"urn:example:a (version 0)": RemovedSuperseded,
+		"urn:example:a (version 1)": RemovedReplaced,
+		"urn:example:b (version 0)": RemovedReplaced,
+	}, removedIDs(s))
+	assert.Empty(t, s.Diagnostics)
+}
+
+func TestResolveLinkedTags_supplements(t *testing.T) {
+	base := linkedTestComid(t, "urn:example:base", 0)
+	sup := linkedTestComid(t, "urn:example:sup", 0).AddLinkedTag("urn:example:base", RelSupplements)
+	supsup := linkedTestComid(t, "urn:example:supsup", 0).AddLinkedTag("urn:example:sup", RelSupplements)
+
+	s, err := ResolveLinkedTags(base, sup, supsup)
+	require.NoError(t, err)
+
+	require.Len(t, s.Effective, 3)
+	assert.Equal(t, []*Comid{sup}, s.Effective[0].Supplements)
+	assert.Equal(t, []*Comid{supsup}, s.Effective[1].Supplements)
+	assert.Empty(t, s.Effective[2].Supplements)
+
+	// replacing the base removes the supplements, transitively
+	repl := linkedTestComid(t, "urn:example:repl", 0).AddLinkedTag("urn:example:base", RelReplaces)
+
+	s, err = ResolveLinkedTags(base, sup, supsup, repl)
+	require.NoError(t, err)
+
+	assert.Equal(t, []*Comid{repl}, s.Comids())
+	assert.Equal(t, map[string]RemovalReason{
+		"urn:example:base (version 0)":   RemovedReplaced,
+		"urn:example:sup (version 0)":    RemovedBaseRemoved,
+		"urn:example:supsup (version 0)": RemovedBaseRemoved,
+	}, removedIDs(s))
+}
+
+func TestResolveLinkedTags_diagnostics(t *testing.T) {
+	a := linkedTestComid(t, "urn:example:a", 0).
+		AddLinkedTag("urn:example:b", RelReplaces).
+		AddLinkedTag("urn:example:missing", RelSupplements)
+	b := linkedTestComid(t, "urn:example:b", 0).AddLinkedTag("urn:example:c", RelReplaces)
+	c := linkedTestComid(t, "urn:example:c", 0).AddLinkedTag("urn:example:a", RelReplaces)
+	d := linkedTestComid(t, "urn:example:d", 0).AddLinkedTag("urn:example:d", RelReplaces)
+
+	s, err := ResolveLinkedTags(a, b, c, d)
+	require.NoError(t, err)
+
+	// links within the cycle are ignored, as are self links
+	assert.Equal(t, []*Comid{a, b, c, d}, s.Comids())
+	assert.Empty(t, s.Removed)
+
+	require.Len(t, s.Diagnostics, 2)
+	assert.Equal(t, DiagDanglingLink, s.Diagnostics[0].Kind)
+	assert.Equal(t,
+		"dangling link: urn:example:a (version 0) supplements unknown tag urn:example:missing",
+		s.Diagnostics[0].String())
+	assert.Equal(t, DiagReplacementCycle, s.Diagnostics[1].Kind)
+	assert.Equal(t,
+		"replacement cycle: urn:example:a (version 0), urn:example:b (version 0), urn:example:c (version 0)",
+		s.Diagnostics[1].String())
+
+	// a tag outside the cycle that replaces one of its members still applies
+	e := linkedTestComid(t, "urn:example:e", 0).AddLinkedTag("urn:example:c", RelReplaces)
+
+	s, err = ResolveLinkedTags(a, b, c, e)
+	require.NoError(t, err)
+	assert.Equal(t, []*Comid{a, b, e}, s.Comids())
+}
+
+func TestResolveLinkedTags_errors(t *testing.T) {
+	_, err := ResolveLinkedTags(linkedTestComid(t, "urn:example:a", 0), nil)
+	assert.EqualError(t, err, "nil CoMID at index 1")
+
+	_, err = ResolveLinkedTags(NewComid())
+	assert.EqualError(t, err, "CoMID at index 0: tag-identity validation failed: empty tag-id")
+
+	c := linkedTestComid(t, "urn:example:a", 0)
+	c.LinkedTags = &LinkedTags{*NewLinkedTag()}
+	_, err = ResolveLinkedTags(c)
+	assert.EqualError(t, err, "CoMID at index 0: linked-tags validation failed: "+
+		"invalid linked-tag entry at index 0: tag-id must be set in linked-tag")
+}
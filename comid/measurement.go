@@ -354,7 +354,10 @@ type Mval struct {
 	SerialNumber       *string             `cbor:"8,keyasint,omitempty" json:"serial-number,omitempty"`
 	UEID               *eat.UEID           `cbor:"9,keyasint,omitempty" json:"ueid,omitempty"`
 	UUID               *UUID               `cbor:"10,keyasint,omitempty" json:"uuid,omitempty"`
+	Name               *string             `cbor:"11,keyasint,omitempty" json:"name,omitempty"`
+	CryptoKeys         *CryptoKeys         `cbor:"13,keyasint,omitempty" json:"cryptokeys,omitempty"`
 	IntegrityRegisters *IntegrityRegisters `cbor:"14,keyasint,omitempty" json:"integrity-registers,omitempty"`
+	RawInt             *RawInt             `cbor:"15,keyasint,omitempty" json:"raw-int,omitempty"`
 	Extensions
 }
 
@@ -439,7 +442,10 @@ func (o Mval) Valid() error {
 		o.SerialNumber == nil &&
 		o.UEID == nil &&
 		o.UUID == nil &&
-		o.IntegrityRegisters == nil {
+		o.Name == nil &&
+		o.CryptoKeys == nil &&
+		o.IntegrityRegisters == nil &&
+		o.RawInt == nil {
 		return fmt.Errorf("no measurement value set")
 	}
 
@@ -483,6 +489,20 @@ func (o Mval) Valid() error {
 
 	// raw value and raw-value-mask have no specific semantics here
 
+	// Validate crypto keys
+	if o.CryptoKeys != nil {
+		if err := o.CryptoKeys.Valid(); err != nil {
+			return fmt.Errorf("cryptokeys validation failed: %w", err)
+		}
+	}
+
+	// Validate raw int
+	if o.RawInt != nil {
+		if err := o.RawInt.Valid(); err != nil {
+			return fmt.Errorf("raw-int validation failed: %w", err)
+		}
+	}
+
 	// Validate extensions (custom logic implemented in validMval())
 	return o.Extensions.validMval(&o)
 }
@@ -788,6 +808,61 @@ func (o *Measurement) SetUUID(u UUID) *Measurement {
 	return o
 }
 
+// SetName sets the supplied name in the measurement-values-map of the target
+// measurement
+func (o *Measurement) SetName(name string) *Measurement {
+	if o != nil {
+		if name == "" {
+			return nil
+		}
+		o.Val.Name = &name
+	}
+	return o
+}
+
+// AddCryptoKey adds the supplied key to the cryptokeys in the
+// measurement-values-map of the target measurement
+func (o *Measurement) AddCryptoKey(k *CryptoKey) *Measurement {
+	if o != nil {
+		if k == nil || k.Valid() != nil {
+			return nil
+		}
+
+		if o.Val.CryptoKeys == nil {
+			o.Val.CryptoKeys = NewCryptoKeys()
+		}
+		o.Val.CryptoKeys.Add(k)
+	}
+	return o
+}
+
+// SetRawInt sets the supplied integer as the raw-int in the
+// measurement-values-map of the target measurement
+func (o *Measurement) SetRawInt(v int64) *Measurement {
+	if o != nil {
+		ri, err := NewIntRawInt(v)
+		if err != nil {
+			return nil
+		}
+		o.Val.RawInt = ri
+	}
+	return o
+}
+
+// SetRawIntRange sets the supplied integer range as the raw-int in the
+// measurement-values-map of the target measurement.  A nil lower (or upper)
+// bound stands for negative (or positive) infinity.
+func (o *Measurement) SetRawIntRange(lower, upper *int64) *Measurement {
+	if o != nil {
+		ri, err := NewIntRangeRawInt(IntRange{Min: lower, Max: upper})
+		if err != nil {
+			return nil
+		}
+		o.Val.RawInt = ri
+	}
+	return o
+}
+
 // nolint:gocritic
 func (o Measurement) Valid() error {
 	if o.Key != nil && o.Key.IsSet() {
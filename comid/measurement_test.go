@@ -698,3 +698,44 @@ func TestMval_Valid(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestMeasurement_name_cryptokeys_raw_int_roundtrip(t *testing.T) {
+	tv := MustNewUintMeasurement(uint64(1)).
+		SetName("attestation-key").
+		AddCryptoKey(MustNewPKIXBase64Key(TestECPubKey)).
+		SetRawIntRange(int64Ptr(-5), int64Ptr(5))
+	require.NotNil(t, tv)
+	require.NoError(t, tv.Valid())
+
+	data, err := tv.Val.MarshalCBOR()
+	require.NoError(t, err)
+
+	var raw map[int]any
+	require.NoError(t, dm.Unmarshal(data, &raw))
+	assert.Equal(t, "attestation-key", raw[11])
+	assert.Contains(t, raw, 13)
+	assert.Contains(t, raw, 15)
+
+	var fromCBOR Mval
+	require.NoError(t, fromCBOR.UnmarshalCBOR(data))
+	assert.Equal(t, tv.Val.Name, fromCBOR.Name)
+	assert.Equal(t, tv.Val.RawInt, fromCBOR.RawInt)
+	assert.True(t, tv.Match(Measurement{Key: tv.Key, Val: fromCBOR}).Matched())
+
+	data, err = tv.Val.MarshalJSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"name":"attestation-key"`)
+	assert.Contains(t, string(data), `"raw-int":{"type":"int-range","value":{"min":-5,"max":5}}`)
+
+	var fromJSON Mval
+	require.NoError(t, fromJSON.UnmarshalJSON(data))
+	assert.Equal(t, tv.Val.RawInt, fromJSON.RawInt)
+	assert.True(t, tv.Match(Measurement{Key: tv.Key, Val: fromJSON}).Matched())
+
+	assert.Nil(t, MustNewUintMeasurement(uint64(1)).SetName(""))
+	assert.Nil(t, MustNewUintMeasurement(uint64(1)).AddCryptoKey(nil))
+	assert.Nil(t, MustNewUintMeasurement(uint64(1)).SetRawIntRange(int64Ptr(1), int64Ptr(0)))
+
+	invalid := Mval{CryptoKeys: NewCryptoKeys()}
+	assert.EqualError(t, invalid.Valid(), "cryptokeys validation failed: no keys to validate")
+}
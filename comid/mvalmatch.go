@@ -0,0 +1,472 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// MvalMismatch describes a single measurement-values-map entry for which the
+// claimed value does not satisfy the reference value.
+type MvalMismatch struct {
+	// Field is the name of the mismatching entry, using the same naming as
+	// the JSON serialization of Mval (e.g., "svn", "digests", "flags").
+	Field string
+	// Reason is a human readable description of the mismatch.
+	Reason string
+}
+
+// String returns a printable representation of the mismatch
+func (o MvalMismatch) String() string {
+	return fmt.Sprintf("%s: %s", o.Field, o.Reason)
+}
+
+// MvalMatchResult stores the outcome of matching a claimed measurement against
+// a reference measurement.  An empty Mismatches list means that the claimed
+// measurement satisfies the reference.
+type MvalMatchResult struct {
+	Mismatches []MvalMismatch
+}
+
+// Matched returns true if no mismatches have been recorded
+func (o MvalMatchResult) Matched() bool {
+	return len(o.Mismatches) == 0
+}
+
+// Err returns nil if the match succeeded, or an error listing all the
+// mismatching fields otherwise
+func (o MvalMatchResult) Err() error {
+	if o.Matched() {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(o.Mismatches))
+	for _, m := range o.Mismatches {
+		msgs = append(msgs, m.String())
+	}
+
+	return fmt.Errorf("measurement mismatch: %s", strings.Join(msgs, "; "))
+}
+
+func (o *MvalMatchResult) add(field string, err error) {
+	if err != nil {
+		o.Mismatches = append(o.Mismatches, MvalMismatch{Field: field, Reason: err.Error()})
+	}
+}
+
+var errNotInEvidence = errors.New("not present in evidence")
+
+// Match compares the supplied claimed measurement-values-map (typically
+// extracted from evidence) against the target, which is treated as the
+// reference.  Only the entries set in the reference are considered, and each
+// of them must be satisfied by the claimed values:
+//
+//   - version: version string and scheme must be equal
+//   - svn: an exact-value reference must equal the claimed SVN, a min-value
+//     reference must be less than or equal to the claimed SVN
+//   - digests: there must be at least one algorithm in common, and for every
+//     common algorithm the claimed value must be one of the reference values
+//   - flags: every flag set in the reference must have the same value in the
+//     claims; flags that are not set in the reference (nil) are ignored
+//   - raw-value: the claimed value must be equal to the reference, after
+//     applying raw-value-mask (if present) to both
+//   - mac-addr, ip-addr, serial-number, ueid, uuid: must be equal
+//   - name: must be equal
+//   - cryptokeys: each key in the reference must be present in the claims
+//   - integrity-registers: each register in the reference must be present in
+//     the claims with digests matching as described above
+//   - raw-int: an integer reference must equal the claimed integer, an
+//     int-range reference must contain the claimed integer (or range)
+//
+// Extension fields are compared only if the reference's extensions implement
+// IMvalMatcher.
+// nolint:gocritic
+func (o Mval) Match(claimed Mval) MvalMatchResult {
+	var res MvalMatchResult
+
+	if o.Ver != nil {
+		res.add("version", matchVersion(*o.Ver, claimed.Ver))
+	}
+
+	if o.SVN != nil {
+		res.add("svn", matchSVN(*o.SVN, claimed.SVN))
+	}
+
+	if o.Digests != nil {
+		if claimed.Digests == nil {
+			res.add("digests", errNotInEvidence)
+		} else {
+			res.add("digests", matchDigests(*o.Digests, *claimed.Digests))
+		}
+	}
+
+	if o.Flags != nil {
+		matchFlags(&res, o.Flags, claimed.Flags)
+	}
+
+	if o.RawValue != nil {
+		res.add("raw-value", matchRawValue(*o.RawValue, o.RawValueMask, claimed.RawValue))
+	}
+
+	if o.MACAddr != nil {
+		if claimed.MACAddr == nil {
+			res.add("mac-addr", errNotInEvidence)
+		} else if !bytes.Equal(*o.MACAddr, *claimed.MACAddr) {
+			res.add("mac-addr", mismatchf(
+				net.HardwareAddr(*o.MACAddr), net.HardwareAddr(*claimed.MACAddr)))
+		}
+	}
+
+	if o.IPAddr != nil {
+		if claimed.IPAddr == nil {
+			res.add("ip-addr", errNotInEvidence)
+		} else if !o.IPAddr.Equal(*claimed.IPAddr) {
+			res.add("ip-addr", mismatchf(*o.IPAddr, *claimed.IPAddr))
+		}
+	}
+
+	if o.SerialNumber != nil {
+		if claimed.SerialNumber == nil {
+			res.add("serial-number", errNotInEvidence)
+		} else if *o.SerialNumber != *claimed.SerialNumber {
+			res.add("serial-number", mismatchf(*o.SerialNumber, *claimed.SerialNumber))
+		}
+	}
+
+	if o.UEID != nil {
+		if claimed.UEID == nil {
+			res.add("ueid", errNotInEvidence)
+		} else if !bytes.Equal(*o.UEID, *claimed.UEID) {
+			res.add("ueid", mismatchf(UEID(*o.UEID), UEID(*claimed.UEID)))
+		}
+	}
+
+	if o.UUID != nil {
+		if claimed.UUID == nil {
+			res.add("uuid", errNotInEvidence)
+		} else if *o.UUID != *claimed.UUID {
+			res.add("uuid", mismatchf(*o.UUID, *claimed.UUID))
+		}
+	}
+
+	if o.Name != nil {
+		if claimed.Name == nil {
+			res.add("name", errNotInEvidence)
+		} else if *o.Name != *claimed.Name {
+			res.add("name", mismatchf(*o.Name, *claimed.Name))
+		}
+	}
+
+	if o.CryptoKeys != nil {
+		res.add("cryptokeys", matchCryptoKeys(*o.CryptoKeys, claimed.CryptoKeys))
+	}
+
+	if o.IntegrityRegisters != nil {
+		res.add("integrity-registers",
+			matchIntegrityRegisters(*o.IntegrityRegisters, claimed.IntegrityRegisters))
+	}
+
+	if o.RawInt != nil {
+		if claimed.RawInt == nil {
+			res.add("raw-int", errNotInEvidence)
+		} else {
+			res.add("raw-int", o.RawInt.Match(*claimed.RawInt))
+		}
+	}
+
+	res.add("extensions", o.Extensions.matchMval(&claimed))
+
+	return res
+}
+
+// Equal returns true if the target Mkey and the supplied one have the same
+// type and value.  Two unset keys are considered equal.
+func (o *Mkey) Equal(other *Mkey) bool {
+	if o == nil || !o.IsSet() {
+		return other == nil || !other.IsSet()
+	}
+
+	if other == nil || !other.IsSet() {
+		return false
+	}
+
+	return o.Type() == other.Type() && o.Value.String() == other.Value.String()
+}
+
+// Match compares the supplied claimed measurement against the target, which
+// is treated as the reference.  If the reference has a key, the claimed
+// measurement must have the same key.  Measurement values are matched as
+// described in Mval.Match.
+// nolint:gocritic
+func (o Measurement) Match(claimed Measurement) MvalMatchResult {
+	var res MvalMatchResult
+
+	if o.Key != nil && o.Key.IsSet() && !o.Key.Equal(claimed.Key) {
+		if claimed.Key == nil || !claimed.Key.IsSet() {
+			res.add("key", errNotInEvidence)
+		} else {
+			res.add("key", mismatchf(o.Key.Value, claimed.Key.Value))
+		}
+
+		return res
+	}
+
+	ret := o.Val.Match(claimed.Val)
+	res.Mismatches = append(res.Mismatches, ret.Mismatches...)
+
+	return res
+}
+
+func mismatchf(expected, got any) error {
+	return fmt.Errorf("expected %v, got %v", expected, got)
+}
+
+func matchVersion(ref Version, claimed *Version) error {
+	if claimed == nil {
+		return errNotInEvidence
+	}
+
+	if ref.Version != claimed.Version {
+		return mismatchf(ref.Version, claimed.Version)
+	}
+
+	if ref.Scheme.String() != claimed.Scheme.String() {
+		return fmt.Errorf("expected scheme %s, got %s", ref.Scheme, claimed.Scheme)
+	}
+
+	return nil
+}
+
+// svnValue extracts the numeric value from the base SVN types, regardless of
+// whether they are held by value (as when decoded) or by reference (as when
+// constructed via NewSVN).
+func svnValue(v ISVNValue) (uint64, bool) {
+	switch t := v.(type) {
+	case TaggedSVN:
+		return uint64(t), true
+	case *TaggedSVN:
+		return uint64(*t), true
+	case TaggedMinSVN:
+		return uint64(t), true
+	case *TaggedMinSVN:
+		return uint64(*t), true
+	default:
+		return 0, false
+	}
+}
+
+func matchSVN(ref SVN, claimed *SVN) error {
+	if claimed == nil || claimed.Value == nil {
+		return errNotInEvidence
+	}
+
+	if ref.Value == nil {
+		return errors.New("reference SVN value not set")
+	}
+
+	refVal, refOK := svnValue(ref.Value)
+	claimedVal, claimedOK := svnValue(claimed.Value)
+
+	if !refOK || !claimedOK {
+		// profile-defined SVN types: fall back to type and value equality
+		if ref.Value.Type() != claimed.Value.Type() ||
+			ref.Value.String() != claimed.Value.String() {
+			return fmt.Errorf("expected %s %s, got %s %s",
+				ref.Value.Type(), ref.Value, claimed.Value.Type(), claimed.Value)
+		}
+		return nil
+	}
+
+	switch ref.Value.Type() {
+	case ExactValueType:
+		if claimed.Value.Type() != ExactValueType {
+			return fmt.Errorf("expected %s, got %s", ExactValueType, claimed.Value.Type())
+		}
+
+		if refVal != claimedVal {
+			return mismatchf(refVal, claimedVal)
+		}
+	case MinValueType:
+		if claimedVal < refVal {
+			return fmt.Errorf("expected at least %d, got %d", refVal, claimedVal)
+		}
+	}
+
+	return nil
+}
+
+func matchDigests(ref, claimed Digests) error {
+	refByAlg := make(map[uint64][][]byte)
+	for _, d := range ref {
+		refByAlg[d.HashAlgID] = append(refByAlg[d.HashAlgID], d.HashValue)
+	}
+
+	common := 0
+
+	for _, d := range claimed {
+		refVals, ok := refByAlg[d.HashAlgID]
+		if !ok {
+			continue
+		}
+
+		common++
+
+		if !containsBytes(refVals, d.HashValue) {
+			return fmt.Errorf("%s digest %x does not match reference",
+				d.AlgIDToString(), d.HashValue)
+		}
+	}
+
+	if common == 0 {
+		return errors.New("no digest algorithm in common with reference")
+	}
+
+	return nil
+}
+
+func containsBytes(list [][]byte, v []byte) bool {
+	for _, e := range list {
+		if bytes.Equal(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+var flagNames = map[Flag]string{
+	FlagIsConfigured:         "is-configured",
+	FlagIsSecure:             "is-secure",
+	FlagIsRecovery:           "is-recovery",
+	FlagIsDebug:              "is-debug",
+	FlagIsReplayProtected:    "is-replay-protected",
+	FlagIsIntegrityProtected: "is-integrity-protected",
+	FlagIsRuntimeMeasured:    "is-runtime-meas",
+	FlagIsImmutable:          "is-immutable",
+	FlagIsTcb:                "is-tcb",
+}
+
+func matchFlags(res *MvalMatchResult, ref, claimed *FlagsMap) {
+	for flag := FlagIsConfigured; flag <= FlagIsTcb; flag++ {
+		refVal := ref.Get(flag)
+		if refVal == nil {
+			continue // "don't care"
+		}
+
+		field := "flags." + flagNames[flag]
+
+		var claimedVal *bool
+		if claimed != nil {
+			claimedVal = claimed.Get(flag)
+		}
+
+		if claimedVal == nil {
+			res.add(field, errNotInEvidence)
+		} else if *refVal != *claimedVal {
+			res.add(field, mismatchf(*refVal, *claimedVal))
+		}
+	}
+}
+
+func matchRawValue(ref RawValue, mask *[]byte, claimed *RawValue) error {
+	if claimed == nil {
+		return errNotInEvidence
+	}
+
+	refBytes, err := ref.GetBytes()
+	if err != nil {
+		return fmt.Errorf("reference: %w", err)
+	}
+
+	claimedBytes, err := claimed.GetBytes()
+	if err != nil {
+		return fmt.Errorf("claimed: %w", err)
+	}
+
+	if mask == nil {
+		if !bytes.Equal(refBytes, claimedBytes) {
+			return mismatchf(fmt.Sprintf("%x", refBytes), fmt.Sprintf("%x", claimedBytes))
+		}
+		return nil
+	}
+
+	m := *mask
+
+	if len(m) != len(refBytes) || len(m) != len(claimedBytes) {
+		return fmt.Errorf(
+			"length mismatch: mask %d, reference %d, claimed %d",
+			len(m), len(refBytes), len(claimedBytes),
+		)
+	}
+
+	for i := range m {
+		if refBytes[i]&m[i] != claimedBytes[i]&m[i] {
+			return fmt.Errorf("masked value differs at byte %d", i)
+		}
+	}
+
+	return nil
+}
+
+// normalizeRegisterIndex maps the supported uint index types onto uint64 so
+// that indexes created by AddDigest (uint) can be compared with the decoded
+// ones (uint64).
+func normalizeRegisterIndex(i IRegisterIndex) IRegisterIndex {
+	if u, ok := i.(uint); ok {
+		return uint64(u)
+	}
+	return i
+}
+
+func matchIntegrityRegisters(ref IntegrityRegisters, claimed *IntegrityRegisters) error {
+	if claimed == nil {
+		return errNotInEvidence
+	}
+
+	claimedRegs := make(map[IRegisterIndex]Digests, len(claimed.IndexMap))
+	for k, v := range claimed.IndexMap {
+		idx := normalizeRegisterIndex(k)
+		claimedRegs[idx] = append(claimedRegs[idx], v...)
+	}
+
+	for k, refDigests := range ref.IndexMap {
+		claimedDigests, ok := claimedRegs[normalizeRegisterIndex(k)]
+		if !ok {
+			return fmt.Errorf("register %v: %w", k, errNotInEvidence)
+		}
+
+		if err := matchDigests(refDigests, claimedDigests); err != nil {
+			return fmt.Errorf("register %v: %w", k, err)
+		}
+	}
+
+	return nil
+}
+
+func matchCryptoKeys(ref CryptoKeys, claimed *CryptoKeys) error {
+	if claimed == nil {
+		return errNotInEvidence
+	}
+
+	for i, rk := range ref {
+		found := false
+
+		for _, ck := range *claimed {
+			if rk != nil && ck != nil &&
+				rk.Type() == ck.Type() && rk.String() == ck.String() {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("key at index %d not present in evidence", i)
+		}
+	}
+
+	return nil
+}
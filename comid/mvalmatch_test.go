@@ -0,0 +1,319 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/swid"
+)
+
+func TestMval_Match_svn(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		ref      *SVN
+		claimed  *SVN
+		expected []MvalMismatch
+	}{
+		{
+			desc:    "exact equal",
+			ref:     MustNewTaggedSVN(5),
+			claimed: MustNewTaggedSVN(5),
+		},
+		{
+			desc:     "exact different",
+			ref:      MustNewTaggedSVN(5),
+			claimed:  MustNewTaggedSVN(6),
+			expected: []MvalMismatch{{"svn", "expected 5, got 6"}},
+		},
+		{
+			desc:    "min satisfied",
+			ref:     MustNewTaggedMinSVN(5),
+			claimed: MustNewTaggedSVN(7),
+		},
+		{
+			desc:     "min not satisfied",
+			ref:      MustNewTaggedMinSVN(5),
+			claimed:  MustNewTaggedSVN(4),
+			expected: []MvalMismatch{{"svn", "expected at least 5, got 4"}},
+		},
+		{
+			desc:     "exact vs min claim",
+			ref:      MustNewTaggedSVN(5),
+			claimed:  MustNewTaggedMinSVN(5),
+			expected: []MvalMismatch{{"svn", "expected exact-value, got min-value"}},
+		},
+		{
+			desc:     "missing",
+			ref:      MustNewTaggedSVN(5),
+			expected: []MvalMismatch{{"svn", "not present in evidence"}},
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			ref := Mval{SVN: tv.ref}
+			claimed := Mval{SVN: tv.claimed}
+
+			res := ref.Match(claimed)
+			assert.Equal(t, tv.expected, res.Mismatches)
+			assert.Equal(t, len(tv.expected) == 0, res.Matched())
+		})
+	}
+}
+
+func TestMval_Match_svn_decoded(t *testing.T) {
+	ref := Mval{SVN: MustNewTaggedMinSVN(2)}
+	data, err := ref.MarshalCBOR()
+	require.NoError(t, err)
+
+	var decoded Mval
+	require.NoError(t, decoded.UnmarshalCBOR(data))
+
+	claimed := Mval{SVN: MustNewTaggedSVN(3)}
+	assert.True(t, decoded.Match(claimed).Matched())
+}
+
+func TestMval_Match_digests(t *testing.T) {
+	ref := NewDigests().
+		AddDigest(swid.Sha256, MustHexDecode(t, "e45b72f5c0c0b572db4d8d3ab7e97f368ff74e62347a824decb67a84e5224d75")).
+		AddDigest(swid.Sha256, MustHexDecode(t, "0000000000000000000000000000000000000000000000000000000000000000"))
+
+	tvs := []struct {
+		desc    string
+		claimed *Digests
+		reason  string
+	}{
+		{
+			desc: "one of the alternatives",
+			claimed: NewDigests().
+				AddDigest(swid.Sha256, MustHexDecode(t, "e45b72f5c0c0b572db4d8d3ab7e97f368ff74e62347a824decb67a84e5224d75")),
+		},
+		{
+			desc: "extra algorithm ignored",
+			claimed: NewDigests().
+				AddDigest(swid.Sha256_32, MustHexDecode(t, "deadbeef")).
+				AddDigest(swid.Sha256, MustHexDecode(t, "0000000000000000000000000000000000000000000000000000000000000000")),
+		},
+		{
+			desc: "different value",
+			claimed: NewDigests().
+				AddDigest(swid.Sha256, MustHexDecode(t, "1111111111111111111111111111111111111111111111111111111111111111")),
+			reason: "sha-256 digest 1111111111111111111111111111111111111111111111111111111111111111 does not match reference",
+		},
+		{
+			desc: "no common algorithm",
+			claimed: NewDigests().
+				AddDigest(swid.Sha256_32, MustHexDecode(t, "deadbeef")),
+			reason: "no digest algorithm in common with reference",
+		},
+		{
+			desc:   "missing",
+			reason: "not present in evidence",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			res := Mval{Digests: ref}.Match(Mval{Digests: tv.claimed})
+			if tv.reason == "" {
+				assert.True(t, res.Matched())
+			} else {
+				assert.Equal(t, []MvalMismatch{{"digests", tv.reason}}, res.Mismatches)
+			}
+		})
+	}
+}
+
+func TestMval_Match_flags(t *testing.T) {
+	ref := NewFlagsMap()
+	ref.SetTrue(FlagIsSecure)
+	ref.SetFalse(FlagIsDebug)
+
+	claimed := NewFlagsMap()
+	claimed.SetTrue(FlagIsSecure, FlagIsDebug, FlagIsConfigured)
+
+	res := Mval{Flags: ref}.Match(Mval{Flags: claimed})
+	assert.Equal(t, []MvalMismatch{{"flags.is-debug", "expected false, got true"}}, res.Mismatches)
+
+	claimed.SetFalse(FlagIsDebug)
+	assert.True(t, Mval{Flags: ref}.Match(Mval{Flags: claimed}).Matched())
+
+	res = Mval{Flags: ref}.Match(Mval{})
+	assert.Equal(t, []MvalMismatch{
+		{"flags.is-secure", "not present in evidence"},
+		{"flags.is-debug", "not present in evidence"},
+	}, res.Mismatches)
+
+	// a reference without any flag set does not constrain the claims
+	assert.True(t, Mval{Flags: NewFlagsMap()}.Match(Mval{}).Matched())
+}
+
+func TestMval_Match_raw_value(t *testing.T) {
+	ref := MustNewUUIDMeasurement(TestUUID).
+		SetRawValueBytes([]byte{0x01, 0x02, 0x03, 0x04}, []byte{0xff, 0xff, 0x00, 0x00})
+
+	claimed := MustNewUUIDMeasurement(TestUUID).
+		SetRawValueBytes([]byte{0x01, 0x02, 0xaa, 0xbb}, nil)
+
+	assert.True(t, ref.Match(*claimed).Matched())
+
+	claimed.SetRawValueBytes([]byte{0x01, 0x03, 0x03, 0x04}, nil)
+	res := ref.Match(*claimed)
+	assert.Equal(t, []MvalMismatch{{"raw-value", "masked value differs at byte 1"}}, res.Mismatches)
+
+	claimed.SetRawValueBytes([]byte{0x01, 0x02}, nil)
+	res = ref.Match(*claimed)
+	assert.Equal(t, []MvalMismatch{
+		{"raw-value", "length mismatch: mask 4, reference 4, claimed 2"},
+	}, res.Mismatches)
+
+	unmasked := MustNewUUIDMeasurement(TestUUID).SetRawValueBytes([]byte{0x01, 0x02}, nil)
+	assert.True(t, unmasked.Match(*claimed).Matched())
+}
+
+func TestMval_Match_identifiers(t *testing.T) {
+	ref := Mval{}
+	ref.MACAddr = (*MACaddr)(&TestMACaddr)
+	ref.IPAddr = &TestIPaddr
+	sn := "S/N 1234"
+	ref.SerialNumber = &sn
+	ueid := TestUEID
+	ref.UEID = &ueid
+	u := TestUUID
+	ref.UUID = &u
+
+	claimed := ref
+	ipv4InV6 := net.ParseIP("2001:0db8:0000:0000:0000:0000:0000:0068")
+	claimed.IPAddr = &ipv4InV6
+
+	assert.True(t, ref.Match(claimed).Matched())
+
+	otherSN := "S/N 4321"
+	otherMAC := MACaddr{0x00, 0x00, 0x5e, 0x00, 0x53, 0x01}
+	claimed.SerialNumber = &otherSN
+	claimed.MACAddr = &otherMAC
+	claimed.UUID = nil
+
+	res := ref.Match(claimed)
+	assert.Equal(t, []MvalMismatch{
+		{"mac-addr", "expected 02:00:5e:10:00:00:00:01, got 00:00:5e:00:53:01"},
+		{"serial-number", "expected S/N 1234, got S/N 4321"},
+		{"uuid", "not present in evidence"},
+	}, res.Mismatches)
+	assert.EqualError(t, res.Err(), "measurement mismatch: "+
+		"mac-addr: expected 02:00:5e:10:00:00:00:01, got 00:00:5e:00:53:01; "+
+		"serial-number: expected S/N 1234, got S/N 4321; "+
+		"uuid: not present in evidence")
+}
+
+func TestMval_Match_integrity_registers(t *testing.T) {
+	d1 := NewDigests().AddDigest(swid.Sha256_32, []byte{0x01, 0x02, 0x03, 0x04})
+	d2 := NewDigests().AddDigest(swid.Sha256_32, []byte{0x05, 0x06, 0x07, 0x08})
+
+	ref := NewIntegrityRegisters()
+	require.NoError(t, ref.AddDigests(uint(0), *d1))
+
+	claimed := NewIntegrityRegisters()
+	require.NoError(t, claimed.AddDigests(uint64(0), *d1))
+	require.NoError(t, claimed.AddDigests("extra", *d2))
+
+	// reference registers are a subset of the claimed ones
+	assert.True(t, Mval{IntegrityRegisters: ref}.Match(Mval{IntegrityRegisters: claimed}).Matched())
+
+	require.NoError(t, ref.AddDigests("pcr", *d2))
+	res := Mval{IntegrityRegisters: ref}.Match(Mval{IntegrityRegisters: claimed})
+	assert.Equal(t, []MvalMismatch{
+		{"integrity-registers", "register pcr: not present in evidence"},
+	}, res.Mismatches)
+}
+
+func TestMeasurement_Match_key(t *testing.T) {
+	ref := MustNewUintMeasurement(uint64(1)).SetSVN(1)
+
+	claimed := MustNewUintMeasurement(uint64(1)).SetSVN(1)
+	assert.True(t, ref.Match(*claimed).Matched())
+
+	claimed = MustNewUintMeasurement(uint64(2)).SetSVN(1)
+	res := ref.Match(*claimed)
+	assert.Equal(t, []MvalMismatch{{"key", "expected 1, got 2"}}, res.Mismatches)
+
+	res = ref.Match(Measurement{Val: claimed.Val})
+	assert.Equal(t, []MvalMismatch{{"key", "not present in evidence"}}, res.Mismatches)
+
+	// a reference without a key matches on the values alone
+	keyless := Measurement{Val: ref.Val}
+	assert.True(t, keyless.Match(*claimed).Matched())
+}
+
+type testMvalMatcher struct {
+	Foo *string `cbor:"-1,keyasint,omitempty" json:"foo,omitempty"`
+}
+
+func (o *testMvalMatcher) MatchMval(claimed *Mval) error {
+	v, err := claimed.GetString("foo")
+	if err != nil {
+		return err
+	}
+
+	if v != *o.Foo {
+		return mismatchf(*o.Foo, v)
+	}
+
+	return nil
+}
+
+func TestMval_Match_extensions(t *testing.T) {
+	foo := "bar"
+	ref := Mval{}
+	require.NoError(t, ref.RegisterExtensions(extensions.NewMap().Add(ExtMval, &testMvalMatcher{Foo: &foo})))
+
+	claimed := Mval{}
+	require.NoError(t, claimed.RegisterExtensions(extensions.NewMap().Add(ExtMval, &testMvalMatcher{Foo: &foo})))
+
+	assert.True(t, ref.Match(claimed).Matched())
+
+	baz := "baz"
+	claimed.GetExtensions().(*testMvalMatcher).Foo = &baz
+	res := ref.Match(claimed)
+	assert.Equal(t, []MvalMismatch{{"extensions", "expected bar, got baz"}}, res.Mismatches)
+}
+
+func TestMval_Match_name_cryptokeys_raw_int(t *testing.T) {
+	ref := MustNewUintMeasurement(uint64(1)).
+		SetName("boot-loader").
+		AddCryptoKey(MustNewPKIXBase64Key(TestECPubKey)).
+		SetRawIntRange(int64Ptr(2), nil)
+	require.NotNil(t, ref)
+
+	claimed := MustNewUintMeasurement(uint64(1)).
+		SetName("boot-loader").
+		AddCryptoKey(MustNewPKIXBase64Cert(TestCert)).
+		AddCryptoKey(MustNewPKIXBase64Key(TestECPubKey)).
+		SetRawInt(3)
+	require.NotNil(t, claimed)
+
+	assert.True(t, ref.Match(*claimed).Matched())
+
+	claimed.SetName("kernel").SetRawInt(1)
+	claimed.Val.CryptoKeys = NewCryptoKeys().Add(MustNewPKIXBase64Cert(TestCert))
+
+	res := ref.Match(*claimed)
+	assert.Equal(t, []MvalMismatch{
+		{"name", "expected boot-loader, got kernel"},
+		{"cryptokeys", "key at index 0 not present in evidence"},
+		{"raw-int", "1 not in range [2, +inf]"},
+	}, res.Mismatches)
+
+	res = ref.Match(*MustNewUintMeasurement(uint64(1)).SetSVN(1))
+	assert.Equal(t, []MvalMismatch{
+		{"name", "not present in evidence"},
+		{"cryptokeys", "not present in evidence"},
+		{"raw-int", "not present in evidence"},
+	}, res.Mismatches)
+}
@@ -0,0 +1,321 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/veraison/corim/encoding"
+	"github.com/veraison/corim/extensions"
+)
+
+// RawInt stores a raw-int-type-choice, i.e., either an integer or an integer
+// range, used to express numeric measurements
+//
+//	raw-int-type-choice = int / tagged-int-range
+type RawInt struct {
+	Value IRawIntValue
+}
+
+// NewRawInt creates a new RawInt of the specified type and value. The type must
+// be one of "int" or "int-range".
+func NewRawInt(val any, typ string) (*RawInt, error) {
+	factory, ok := rawIntValueRegister[typ]
+	if !ok {
+		return nil, fmt.Errorf("unknown raw-int type: %s", typ)
+	}
+
+	return factory(val)
+}
+
+// Valid returns nil if the RawInt is valid or an error describing the problem,
+// if it is not.
+func (o RawInt) Valid() error {
+	if o.Value == nil {
+		return errors.New("no value set")
+	}
+
+	return o.Value.Valid()
+}
+
+// Type returns the type of the RawInt
+func (o RawInt) Type() string {
+	if o.Value == nil {
+		return ""
+	}
+
+	return o.Value.Type()
+}
+
+// String returns a printable representation of the RawInt
+func (o RawInt) String() string {
+	if o.Value == nil {
+		return ""
+	}
+
+	return o.Value.String()
+}
+
+// MarshalCBOR returns the CBOR encoding of the RawInt
+func (o RawInt) MarshalCBOR() ([]byte, error) {
+	return em.Marshal(o.Value)
+}
+
+// UnmarshalCBOR populates the RawInt from the provided CBOR bytes.  An untagged
+// integer is decoded as RawIntValue, and a tagged int-range as TaggedIntRange.
+func (o *RawInt) UnmarshalCBOR(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("empty raw-int")
+	}
+
+	// major type 6 (tag)
+	if data[0]>>5 == 6 {
+		var r TaggedIntRange
+		if err := dm.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		o.Value = &r
+		return nil
+	}
+
+	var i RawIntValue
+	if err := dm.Unmarshal(data, &i); err != nil {
+		return err
+	}
+	o.Value = &i
+
+	return nil
+}
+
+// UnmarshalJSON deserializes the supplied JSON object into the target RawInt
+// The RawInt object must have the following shape:
+//
+//	{
+//	  "type": "<RAW_INT_TYPE>",
+//	  "value": <RAW_INT_VALUE>
+//	}
+//
+// where <RAW_INT_TYPE> must be one of "int" or "int-range".  For "int",
+// <RAW_INT_VALUE> is an integer (JSON number), for "int-range" it is an object
+// with "min" and "max" integer members, where null stands for (negative or
+// positive) infinity.
+func (o *RawInt) UnmarshalJSON(data []byte) error {
+	var tnv encoding.TypeAndValue
+
+	if err := json.Unmarshal(data, &tnv); err != nil {
+		return fmt.Errorf("raw-int decoding failure: %w", err)
+	}
+
+	decoded, err := NewRawInt(nil, tnv.Type)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(tnv.Value, &decoded.Value); err != nil {
+		return fmt.Errorf("invalid raw-int %s: %w", tnv.Type, err)
+	}
+
+	if err := decoded.Value.Valid(); err != nil {
+		return fmt.Errorf("invalid raw-int %s: %w", tnv.Type, err)
+	}
+
+	o.Value = decoded.Value
+
+	return nil
+}
+
+// MarshalJSON serializes the RawInt into a JSON object
+func (o RawInt) MarshalJSON() ([]byte, error) {
+	return extensions.TypeChoiceValueMarshalJSON(o.Value)
+}
+
+// Match returns nil if the supplied claimed value is compatible with the
+// target, which is treated as the reference: an integer reference must be
+// equal to the claimed integer, and an integer range must contain the claimed
+// integer (or range).
+func (o RawInt) Match(claimed RawInt) error {
+	switch ref := o.Value.(type) {
+	case *RawIntValue:
+		c, ok := claimed.Value.(*RawIntValue)
+		if !ok {
+			return mismatchf(o.Type(), claimed.Type())
+		}
+		if *ref != *c {
+			return mismatchf(*ref, *c)
+		}
+	case *TaggedIntRange:
+		switch c := claimed.Value.(type) {
+		case *RawIntValue:
+			if !IntRange(*ref).Contains(int64(*c)) {
+				return fmt.Errorf("%d not in range %s", *c, ref)
+			}
+		case *TaggedIntRange:
+			if !IntRange(*ref).ContainsRange(IntRange(*c)) {
+				return fmt.Errorf("range %s not within %s", c, ref)
+			}
+		default:
+			return mismatchf(o.Type(), claimed.Type())
+		}
+	default:
+		return fmt.Errorf("unsupported raw-int type %q", o.Type())
+	}
+
+	return nil
+}
+
+// IRawIntValue is the interface that must be implemented by all RawInt values.
+type IRawIntValue interface {
+	extensions.ITypeChoiceValue
+}
+
+const IntRangeType = "int-range"
+
+// RawIntValue is an integer raw-int
+type RawIntValue int64
+
+func NewIntRawInt(val any) (*RawInt, error) {
+	var ret RawIntValue
+
+	switch t := val.(type) {
+	case nil:
+	case int:
+		ret = RawIntValue(t)
+	case int64:
+		ret = RawIntValue(t)
+	case uint64:
+		if t > math.MaxInt64 {
+			return nil, fmt.Errorf("int value out of range: %d", t)
+		}
+		ret = RawIntValue(t)
+	case RawIntValue:
+		ret = t
+	default:
+		return nil, fmt.Errorf("unexpected type for int: %T", t)
+	}
+
+	return &RawInt{&ret}, nil
+}
+
+func (o RawIntValue) String() string {
+	return fmt.Sprint(int64(o))
+}
+
+func (o RawIntValue) Type() string {
+	return IntType
+}
+
+func (o RawIntValue) Valid() error {
+	return nil
+}
+
+// IntRange is a closed range of integers.  A nil Min stands for negative
+// infinity, and a nil Max for positive infinity.
+//
+//	int-range = [min: int / negative-inf, max: int / positive-inf]
+type IntRange struct {
+	_   struct{} `cbor:",toarray"`
+	Min *int64   `json:"min"`
+	Max *int64   `json:"max"`
+}
+
+// Contains returns true if the supplied integer is within the target range
+func (o IntRange) Contains(v int64) bool {
+	if o.Min != nil && v < *o.Min {
+		return false
+	}
+
+	if o.Max != nil && v > *o.Max {
+		return false
+	}
+
+	return true
+}
+
+// ContainsRange returns true if the supplied range is within the target range
+func (o IntRange) ContainsRange(other IntRange) bool {
+	if o.Min != nil && (other.Min == nil || *other.Min < *o.Min) {
+		return false
+	}
+
+	if o.Max != nil && (other.Max == nil || *other.Max > *o.Max) {
+		return false
+	}
+
+	return true
+}
+
+// Valid returns an error if the lower bound of the range is greater than its
+// upper bound
+func (o IntRange) Valid() error {
+	if o.Min != nil && o.Max != nil && *o.Min > *o.Max {
+		return fmt.Errorf("min (%d) greater than max (%d)", *o.Min, *o.Max)
+	}
+
+	return nil
+}
+
+func (o IntRange) String() string {
+	lo, hi := "-inf", "+inf"
+
+	if o.Min != nil {
+		lo = fmt.Sprint(*o.Min)
+	}
+
+	if o.Max != nil {
+		hi = fmt.Sprint(*o.Max)
+	}
+
+	return fmt.Sprintf("[%s, %s]", lo, hi)
+}
+
+// TaggedIntRange is an int-range, tagged with CBOR tag 564
+type TaggedIntRange IntRange
+
+// NewIntRangeRawInt creates a new int-range RawInt.  The supplied value must be
+// an IntRange (or a pointer to one), or nil for the unbounded range.
+func NewIntRangeRawInt(val any) (*RawInt, error) {
+	var ret TaggedIntRange
+
+	switch t := val.(type) {
+	case nil:
+	case IntRange:
+		ret = TaggedIntRange(t)
+	case *IntRange:
+		ret = TaggedIntRange(*t)
+	case TaggedIntRange:
+		ret = t
+	default:
+		return nil, fmt.Errorf("unexpected type for int-range: %T", t)
+	}
+
+	if err := ret.Valid(); err != nil {
+		return nil, err
+	}
+
+	return &RawInt{&ret}, nil
+}
+
+func (o TaggedIntRange) String() string {
+	return IntRange(o).String()
+}
+
+func (o TaggedIntRange) Type() string {
+	return IntRangeType
+}
+
+func (o TaggedIntRange) Valid() error {
+	return IntRange(o).Valid()
+}
+
+// IRawIntFactory defines the signature for the factory functions that create
+// a RawInt of the corresponding type choice.
+type IRawIntFactory func(val any) (*RawInt, error)
+
+var rawIntValueRegister = map[string]IRawIntFactory{
+	IntType:      NewIntRawInt,
+	IntRangeType: NewIntRangeRawInt,
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package comid
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func TestRawInt_CBOR_roundtrip(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		val      *RawInt
+		expected []byte
+	}{
+		{
+			desc:     "int",
+			val:      &RawInt{func() *RawIntValue { v := RawIntValue(-2); return &v }()},
+			expected: []byte{0x21},
+		},
+		{
+			desc: "bounded range",
+			val:  &RawInt{&TaggedIntRange{Min: int64Ptr(1), Max: int64Ptr(10)}},
+			// 564([1, 10])
+			expected: []byte{0xd9, 0x02, 0x34, 0x82, 0x01, 0x0a},
+		},
+		{
+			desc: "unbounded max",
+			val:  &RawInt{&TaggedIntRange{Min: int64Ptr(-1)}},
+			// 564([-1, null])
+			expected: []byte{0xd9, 0x02, 0x34, 0x82, 0x20, 0xf6},
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			data, err := tv.val.MarshalCBOR()
+			require.NoError(t, err)
+			assert.Equal(t, tv.expected, data)
+
+			var actual RawInt
+			require.NoError(t, actual.UnmarshalCBOR(data))
+			assert.Equal(t, tv.val, &actual)
+		})
+	}
+}
+
+func TestRawInt_JSON_roundtrip(t *testing.T) {
+	ri, err := NewIntRangeRawInt(IntRange{Max: int64Ptr(7)})
+	require.NoError(t, err)
+
+	data, err := ri.MarshalJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"int-range","value":{"min":null,"max":7}}`, string(data))
+
+	var actual RawInt
+	require.NoError(t, actual.UnmarshalJSON(data))
+	assert.Equal(t, "[-inf, 7]", actual.String())
+
+	require.NoError(t, actual.UnmarshalJSON([]byte(`{"type":"int","value":5}`)))
+	assert.Equal(t, IntType, actual.Type())
+	assert.Equal(t, "5", actual.String())
+
+	err = actual.UnmarshalJSON([]byte(`{"type":"int-range","value":{"min":3,"max":1}}`))
+	assert.EqualError(t, err, "invalid raw-int int-range: min (3) greater than max (1)")
+
+	err = actual.UnmarshalJSON([]byte(`{"type":"float","value":1.5}`))
+	assert.EqualError(t, err, "unknown raw-int type: float")
+}
+
+func TestRawInt_Match(t *testing.T) {
+	five := mustNewRawInt(t, int64(5), IntType)
+	six := mustNewRawInt(t, int64(6), IntType)
+	upTo5 := mustNewRawInt(t, IntRange{Max: int64Ptr(5)}, IntRangeType)
+	oneTo4 := mustNewRawInt(t, IntRange{Min: int64Ptr(1), Max: int64Ptr(4)}, IntRangeType)
+	from1 := mustNewRawInt(t, IntRange{Min: int64Ptr(1)}, IntRangeType)
+
+	assert.NoError(t, five.Match(*five))
+	assert.EqualError(t, five.Match(*six), "expected 5, got 6")
+	assert.EqualError(t, five.Match(*upTo5), "expected int, got int-range")
+
+	assert.NoError(t, upTo5.Match(*five))
+	assert.EqualError(t, upTo5.Match(*six), "6 not in range [-inf, 5]")
+	assert.NoError(t, upTo5.Match(*oneTo4))
+	assert.EqualError(t, upTo5.Match(*from1), "range [1, +inf] not within [-inf, 5]")
+}
+
+func mustNewRawInt(t *testing.T, val any, typ string) *RawInt {
+	ret, err := NewRawInt(val, typ)
+	require.NoError(t, err)
+	return ret
+}
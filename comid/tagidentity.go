@@ -21,3 +21,9 @@ func (o TagIdentity) Valid() error {
 
 	return nil
 }
+
+// String returns a printable representation of the TagIdentity, e.g.
+// "urn:example:tag (version 2)"
+func (o TagIdentity) String() string {
+	return fmt.Sprintf("%s (version %d)", o.TagID.String(), o.TagVersion)
+}
@@ -16,6 +16,13 @@ type Triples struct {
 	DevIdentityKeys *KeyTriples   `cbor:"2,keyasint,omitempty" json:"dev-identity-keys,omitempty"`
 	AttestVerifKeys *KeyTriples   `cbor:"3,keyasint,omitempty" json:"attester-verification-keys,omitempty"`
 
+	DomainDependencies *DomainDependencyTriples `cbor:"4,keyasint,omitempty" json:"domain-dependencies,omitempty"`
+	DomainMemberships  *DomainMembershipTriples `cbor:"5,keyasint,omitempty" json:"domain-memberships,omitempty"`
+	CoswidTriples      *CoswidTriples           `cbor:"6,keyasint,omitempty" json:"coswid-triples,omitempty"`
+
+	CondEndorseSeries *CondEndorseSeriesTriples `cbor:"8,keyasint,omitempty" json:"conditional-endorsement-series,omitempty"`
+	CondEndorse       *CondEndorseTriples       `cbor:"10,keyasint,omitempty" json:"conditional-endorsement,omitempty"`
+
 	Extensions
 }
 
@@ -23,6 +30,8 @@ type Triples struct {
 func (o *Triples) RegisterExtensions(exts extensions.Map) error {
 	refValExts := extensions.NewMap()
 	endValExts := extensions.NewMap()
+	condEndExts := extensions.NewMap()
+	condSeriesExts := extensions.NewMap()
 
 	for p, v := range exts {
 		switch p {
@@ -36,6 +45,14 @@ func (o *Triples) RegisterExtensions(exts extensions.Map) error {
 			endValExts[ExtMval] = v
 		case ExtEndorsedValueFlags:
 			endValExts[ExtFlags] = v
+		case ExtCondEndorseValue:
+			condEndExts[ExtMval] = v
+		case ExtCondEndorseValueFlags:
+			condEndExts[ExtFlags] = v
+		case ExtCondEndorseSeriesValue:
+			condSeriesExts[ExtMval] = v
+		case ExtCondEndorseSeriesValueFlags:
+			condSeriesExts[ExtFlags] = v
 		default:
 			return fmt.Errorf("%w: %q", extensions.ErrUnexpectedPoint, p)
 		}
@@ -61,6 +78,26 @@ func (o *Triples) RegisterExtensions(exts extensions.Map) error {
 		}
 	}
 
+	if len(condEndExts) != 0 {
+		if o.CondEndorse == nil {
+			o.CondEndorse = NewCondEndorseTriples()
+		}
+
+		if err := o.CondEndorse.RegisterExtensions(condEndExts); err != nil {
+			return err
+		}
+	}
+
+	if len(condSeriesExts) != 0 {
+		if o.CondEndorseSeries == nil {
+			o.CondEndorseSeries = NewCondEndorseSeriesTriples()
+		}
+
+		if err := o.CondEndorseSeries.RegisterExtensions(condSeriesExts); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -90,6 +127,14 @@ func (o Triples) MarshalCBOR() ([]byte, error) {
 		o.EndorsedValues = nil
 	}
 
+	if o.CondEndorse != nil && o.CondEndorse.IsEmpty() {
+		o.CondEndorse = nil
+	}
+
+	if o.CondEndorseSeries != nil && o.CondEndorseSeries.IsEmpty() {
+		o.CondEndorseSeries = nil
+	}
+
 	return encoding.SerializeStructToCBOR(em, o)
 }
 
@@ -114,6 +159,14 @@ func (o Triples) MarshalJSON() ([]byte, error) {
 		o.EndorsedValues = nil
 	}
 
+	if o.CondEndorse != nil && o.CondEndorse.IsEmpty() {
+		o.CondEndorse = nil
+	}
+
+	if o.CondEndorseSeries != nil && o.CondEndorseSeries.IsEmpty() {
+		o.CondEndorseSeries = nil
+	}
+
 	return encoding.SerializeStructToJSON(o)
 }
 
@@ -123,7 +176,12 @@ func (o Triples) Valid() error {
 	if (o.ReferenceValues == nil || o.ReferenceValues.IsEmpty()) &&
 		(o.EndorsedValues == nil || o.EndorsedValues.IsEmpty()) &&
 		(o.AttestVerifKeys == nil || len(*o.AttestVerifKeys) == 0) &&
-		(o.DevIdentityKeys == nil || len(*o.DevIdentityKeys) == 0) {
+		(o.DevIdentityKeys == nil || len(*o.DevIdentityKeys) == 0) &&
+		(o.DomainDependencies == nil || len(*o.DomainDependencies) == 0) &&
+		(o.DomainMemberships == nil || len(*o.DomainMemberships) == 0) &&
+		(o.CoswidTriples == nil || len(*o.CoswidTriples) == 0) &&
+		(o.CondEndorse == nil || o.CondEndorse.IsEmpty()) &&
+		(o.CondEndorseSeries == nil || o.CondEndorseSeries.IsEmpty()) {
 		return fmt.Errorf("triples struct must not be empty")
 	}
 
@@ -155,6 +213,42 @@ func (o Triples) Valid() error {
 		}
 	}
 
+	if o.DomainDependencies != nil {
+		for i, dt := range *o.DomainDependencies {
+			if err := dt.Valid(); err != nil {
+				return fmt.Errorf("domain dependency at index %d: %w", i, err)
+			}
+		}
+	}
+
+	if o.DomainMemberships != nil {
+		for i, mt := range *o.DomainMemberships {
+			if err := mt.Valid(); err != nil {
+				return fmt.Errorf("domain membership at index %d: %w", i, err)
+			}
+		}
+	}
+
+	if o.CoswidTriples != nil {
+		for i, ct := range *o.CoswidTriples {
+			if err := ct.Valid(); err != nil {
+				return fmt.Errorf("coswid triple at index %d: %w", i, err)
+			}
+		}
+	}
+
+	if o.CondEndorse != nil {
+		if err := o.CondEndorse.Valid(); err != nil {
+			return fmt.Errorf("conditional endorsements: %w", err)
+		}
+	}
+
+	if o.CondEndorseSeries != nil {
+		if err := o.CondEndorseSeries.Valid(); err != nil {
+			return fmt.Errorf("conditional endorsement series: %w", err)
+		}
+	}
+
 	return o.Extensions.validTriples(&o)
 }
 
@@ -197,3 +291,63 @@ func (o *Triples) AddDevIdentityKey(val KeyTriple) *Triples {
 
 	return o
 }
+
+func (o *Triples) AddDomainDependency(val DomainDependencyTriple) *Triples {
+	if o != nil {
+		if o.DomainDependencies == nil {
+			o.DomainDependencies = NewDomainDependencyTriples()
+		}
+
+		*o.DomainDependencies = append(*o.DomainDependencies, val)
+	}
+
+	return o
+}
+
+func (o *Triples) AddDomainMembership(val DomainMembershipTriple) *Triples {
+	if o != nil {
+		if o.DomainMemberships == nil {
+			o.DomainMemberships = NewDomainMembershipTriples()
+		}
+
+		*o.DomainMemberships = append(*o.DomainMemberships, val)
+	}
+
+	return o
+}
+
+func (o *Triples) AddCoswidTriple(val CoswidTriple) *Triples {
+	if o != nil {
+		if o.CoswidTriples == nil {
+			o.CoswidTriples = NewCoswidTriples()
+		}
+
+		*o.CoswidTriples = append(*o.CoswidTriples, val)
+	}
+
+	return o
+}
+
+func (o *Triples) AddCondEndorsement(val CondEndorseTriple) *Triples {
+	if o != nil {
+		if o.CondEndorse == nil {
+			o.CondEndorse = NewCondEndorseTriples()
+		}
+
+		o.CondEndorse.Add(&val)
+	}
+
+	return o
+}
+
+func (o *Triples) AddCondEndorsementSeries(val CondEndorseSeriesTriple) *Triples {
+	if o != nil {
+		if o.CondEndorseSeries == nil {
+			o.CondEndorseSeries = NewCondEndorseSeriesTriples()
+		}
+
+		o.CondEndorseSeries.Add(&val)
+	}
+
+	return o
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"reflect"
+
+	cose "github.com/veraison/go-cose"
+)
+
+// ErrAlgorithmPolicy is returned (wrapped) when a signature algorithm or a
+// verification key is rejected by an AlgorithmPolicy
+var ErrAlgorithmPolicy = errors.New("rejected by algorithm policy")
+
+// AlgorithmPolicy restricts the COSE algorithms and the key sizes that are
+// accepted when verifying a signed-corim (or the MAC of a MAC-protected
+// CoRIM, to which only Algorithms applies).  A nil policy accepts any
+// algorithm supported by go-cose (or, for RSASSA-PKCS1-v1_5, by this
+// package), with a key of any size.
+type AlgorithmPolicy struct {
+	// Algorithms, if not empty, lists the accepted algorithms
+	Algorithms []cose.Algorithm
+	// MinRSABits is the minimum size, in bits, of the modulus of RSA keys
+	MinRSABits int
+	// MinECBits is the minimum size, in bits, of the curve of ECDSA keys.
+	// Ed25519 keys count as 256 bits.
+	MinECBits int
+}
+
+// Check returns an error wrapping ErrAlgorithmPolicy if the supplied algorithm
+// or public key are not accepted by the policy
+func (o *AlgorithmPolicy) Check(alg cose.Algorithm, pk crypto.PublicKey) error {
+	if o == nil {
+		return nil
+	}
+
+	if err := o.checkAlgorithm(alg); err != nil {
+		return err
+	}
+
+	var (
+		kty     string
+		bits    int
+		minBits int
+	)
+
+	switch v := pk.(type) {
+	case *rsa.PublicKey:
+		kty, bits, minBits = "RSA", v.N.BitLen(), o.MinRSABits
+	case *ecdsa.PublicKey:
+		kty, bits, minBits = "EC", v.Curve.Params().BitSize, o.MinECBits
+	case ed25519.PublicKey:
+		kty, bits, minBits = "EC", 256, o.MinECBits
+	default:
+		return fmt.Errorf("%w: unknown public key type %v", ErrAlgorithmPolicy, reflect.TypeOf(pk))
+	}
+
+	if bits < minBits {
+		return fmt.Errorf("%w: %s key size %d is below %d bits", ErrAlgorithmPolicy, kty, bits, minBits)
+	}
+
+	return nil
+}
+
+// checkAlgorithm returns an error wrapping ErrAlgorithmPolicy if the supplied
+// algorithm is not accepted by the policy
+func (o *AlgorithmPolicy) checkAlgorithm(alg cose.Algorithm) error {
+	if o == nil || len(o.Algorithms) == 0 || o.allows(alg) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: algorithm %s is not allowed", ErrAlgorithmPolicy, signatureAlgorithmName(alg))
+}
+
+func (o *AlgorithmPolicy) allows(alg cose.Algorithm) bool {
+	for _, a := range o.Algorithms {
+		if a == alg {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/eat"
+	cose "github.com/veraison/go-cose"
+)
+
+func TestAlgorithmPolicy_Check(t *testing.T) {
+	ecKey, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	edKey, err := NewPublicKeyFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	rsaKey, err := NewPublicKeyFromPEM(readTestKey(t, "rsa-2048-pub.pem"))
+	require.NoError(t, err)
+
+	var nilPolicy *AlgorithmPolicy
+	assert.NoError(t, nilPolicy.Check(cose.AlgorithmES256, ecKey))
+
+	policy := &AlgorithmPolicy{
+		Algorithms: []cose.Algorithm{cose.AlgorithmES384, cose.AlgorithmEd25519, cose.AlgorithmPS256},
+		MinRSABits: 3072,
+		MinECBits:  256,
+	}
+
+	assert.NoError(t, policy.Check(cose.AlgorithmEd25519, edKey))
+
+	err = policy.Check(cose.AlgorithmES256, ecKey)
+	assert.ErrorIs(t, err, ErrAlgorithmPolicy)
+	assert.EqualError(t, err, "rejected by algorithm policy: algorithm ES256 is not allowed")
+
+	err = policy.Check(cose.AlgorithmPS256, rsaKey)
+	assert.ErrorIs(t, err, ErrAlgorithmPolicy)
+	assert.EqualError(t, err, "rejected by algorithm policy: RSA key size 2048 is below 3072 bits")
+
+	policy = &AlgorithmPolicy{MinECBits: 384}
+
+	err = policy.Check(cose.AlgorithmES256, ecKey)
+	assert.EqualError(t, err, "rejected by algorithm policy: EC key size 256 is below 384 bits")
+
+	err = policy.Check(cose.AlgorithmES256, []byte("not a key"))
+	assert.EqualError(t, err, "rejected by algorithm policy: unknown public key type []uint8")
+}
+
+func TestSignedCorim_Verify_AlgorithmPolicy(t *testing.T) {
+	signer, err := NewSignerFromPEM(readTestKey(t, "rsa-2048-pkcs1.pem"), KeyOptions{})
+	require.NoError(t, err)
+
+	pk, err := NewPublicKeyFromPEM(readTestKey(t, "rsa-2048-pub.pem"))
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+
+	cbor, err := SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	var SignedCorimOut SignedCorim
+
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+	assert.NoError(t, SignedCorimOut.Verify(pk))
+
+	SignedCorimOut.AlgorithmPolicy = &AlgorithmPolicy{MinRSABits: 3072}
+	assert.ErrorIs(t, SignedCorimOut.Verify(pk), ErrAlgorithmPolicy)
+	assert.ErrorIs(t, SignedCorimOut.VerifyWithOptions(pk, VerifyOptions{}), ErrAlgorithmPolicy)
+}
+
+func TestSignedCorim_VerifySigners_AlgorithmPolicy(t *testing.T) {
+	var SignedCorimOut SignedCorim
+	require.NoError(t, SignedCorimOut.FromCOSE(signVendorAndOEM(t, NewMeta().SetSigner("OEM", nil))))
+
+	// the vendor's ES256 signature is rejected
+	SignedCorimOut.AlgorithmPolicy = &AlgorithmPolicy{Algorithms: []cose.Algorithm{cose.AlgorithmES384}}
+
+	verified, err := SignedCorimOut.VerifySigners(testMultiSignerResolver(t), SignerPolicyAny, VerifyOptions{})
+	require.NoError(t, err)
+	require.Len(t, verified, 1)
+	assert.Equal(t, "OEM", verified[0].Meta.Signer.Name)
+
+	_, err = SignedCorimOut.VerifySigners(testMultiSignerResolver(t), SignerPolicyAll, VerifyOptions{})
+	assert.ErrorIs(t, err, ErrAlgorithmPolicy)
+	assert.EqualError(t, err, "signature 0: rejected by algorithm policy: algorithm ES256 is not allowed")
+}
+
+func TestProfile_AlgorithmPolicy(t *testing.T) {
+	profileID, err := eat.NewProfile("http://example.com/strict-profile")
+	require.NoError(t, err)
+
+	policy := &AlgorithmPolicy{Algorithms: []cose.Algorithm{cose.AlgorithmES384, cose.AlgorithmES512}}
+
+	require.NoError(t, RegisterProfileWithAlgorithmPolicy(profileID, nil, policy))
+	defer UnregisterProfile(profileID)
+
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	pk, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+	SignedCorimIn.UnsignedCorim.Profile = profileID
+
+	cbor, err := SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	// the policy of the profile applies to the decoded signed-corim
+	SignedCorimOut, err := UnmarshalSignedCorimFromCBOR(cbor)
+	require.NoError(t, err)
+	assert.Equal(t, policy, SignedCorimOut.AlgorithmPolicy)
+
+	err = SignedCorimOut.Verify(pk)
+	assert.EqualError(t, err, "rejected by algorithm policy: algorithm ES256 is not allowed")
+
+	// profiles registered without a policy accept any algorithm
+	otherID, err := eat.NewProfile("http://example.com/lenient-profile")
+	require.NoError(t, err)
+
+	require.NoError(t, RegisterProfile(otherID, nil))
+	defer UnregisterProfile(otherID)
+
+	SignedCorimIn.UnsignedCorim.Profile = otherID
+
+	cbor, err = SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	SignedCorimOut, err = UnmarshalSignedCorimFromCBOR(cbor)
+	require.NoError(t, err)
+	assert.Nil(t, SignedCorimOut.AlgorithmPolicy)
+	assert.NoError(t, SignedCorimOut.Verify(pk))
+}
+
+func TestVerifyOptions_AlgorithmPolicy(t *testing.T) {
+	pk, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	// a verifier that requires ES384, for CoRIMs with no profile
+	opts := VerifyOptions{
+		AlgorithmPolicy: &AlgorithmPolicy{Algorithms: []cose.Algorithm{cose.AlgorithmES384}},
+	}
+
+	const rejected = "rejected by algorithm policy: algorithm ES256 is not allowed"
+
+	SignedCorimOut := signWithKeyID(t, testES256Key, []byte("vendor"), "ACME Ltd.")
+	require.Nil(t, SignedCorimOut.UnsignedCorim.Profile)
+	require.Nil(t, SignedCorimOut.AlgorithmPolicy)
+
+	assert.NoError(t, SignedCorimOut.Verify(pk))
+	assert.EqualError(t, SignedCorimOut.VerifyWithOptions(pk, opts), rejected)
+	assert.EqualError(t, SignedCorimOut.VerifyWithResolver(testMultiSignerResolver(t), opts), rejected)
+
+	// the verifier's policy overrides the SignedCorim's
+	SignedCorimOut.AlgorithmPolicy = &AlgorithmPolicy{Algorithms: []cose.Algorithm{cose.AlgorithmES512}}
+	assert.EqualError(t, SignedCorimOut.VerifyWithOptions(pk, VerifyOptions{}), rejected)
+	assert.NoError(t, SignedCorimOut.VerifyWithOptions(pk, VerifyOptions{AlgorithmPolicy: &AlgorithmPolicy{}}))
+
+	// COSE Sign: the vendor's ES256 signature is rejected
+	var multi SignedCorim
+	require.NoError(t, multi.FromCOSE(signVendorAndOEM(t, NewMeta().SetSigner("OEM", nil))))
+
+	_, err = multi.VerifySigners(testMultiSignerResolver(t), SignerPolicyAll, opts)
+	assert.EqualError(t, err, "signature 0: "+rejected)
+
+	// x5chain
+	root := newTestCert(t, "root", nil, nil)
+	leaf := newTestCert(t, "leaf", root, nil)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	var chained SignedCorim
+	require.NoError(t, chained.FromCOSE(signWithCerts(t, leaf)))
+
+	certOpts := VerifyOptions{Clock: func() time.Time { return testCertNow }}
+	assert.NoError(t, chained.VerifyChain(roots, certOpts))
+
+	certOpts.AlgorithmPolicy = opts.AlgorithmPolicy
+	assert.EqualError(t, chained.VerifyChain(roots, certOpts), rejected)
+}
@@ -0,0 +1,293 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	cose "github.com/veraison/go-cose"
+)
+
+// coseSignTag is the CBOR tag #6.98 of a COSE_Sign message
+var coseSignTag = []byte{0xd8, 0x62}
+
+// isCOSESign returns true if the supplied data is a tagged COSE_Sign message
+func isCOSESign(data []byte) bool {
+	return bytes.HasPrefix(data, coseSignTag)
+}
+
+// CorimSignature describes one of the signatures of a COSE Sign signed-corim
+type CorimSignature struct {
+	// Meta is the corim-meta-map in the protected header of the signature
+	Meta Meta
+	// KeyID is the kid header of the signature, if any
+	KeyID     []byte
+	Algorithm cose.Algorithm
+}
+
+// SignerPolicy controls which signatures of a COSE Sign signed-corim must be
+// verified for VerifySigners to succeed
+type SignerPolicy int
+
+const (
+	// SignerPolicyAny requires at least one of the signatures to verify
+	SignerPolicyAny SignerPolicy = iota
+	// SignerPolicyAll requires all of the signatures to verify
+	SignerPolicyAll
+)
+
+func (o SignerPolicy) String() string {
+	switch o {
+	case SignerPolicyAny:
+		return "any"
+	case SignerPolicyAll:
+		return "all"
+	default:
+		return fmt.Sprintf("SignerPolicy(%d)", o)
+	}
+}
+
+// AddSigner adds a signature by the supplied cose Signer, with the supplied
+// corim-meta-map and (optional) key identifier in its protected header, and
+// returns the serialized COSE Sign signed-corim.  On first use, the payload is
+// encoded from the UnsignedCorim field, which must be correctly populated.
+// Signers can be added incrementally, including to a signed-corim decoded with
+// FromCOSE, since the payload is never re-encoded.  AddSigner fails on a
+// SignedCorim that holds a COSE Sign1 message.
+// nolint:gocritic
+func (o *SignedCorim) AddSigner(signer cose.Signer, meta Meta, kid []byte) ([]byte, error) {
+	if signer == nil {
+		return nil, errors.New("nil signer")
+	}
+
+	if o.message != nil {
+		return nil, errors.New("cannot add a signer to a COSE Sign1 signed CoRIM")
+	}
+
+	if o.Detached {
+		return nil, errors.New("detached payload is only supported with COSE Sign1")
+	}
+
+	alg, err := signerAlgorithm(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	metaCBOR, err := meta.ToCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("failed CBOR encoding of CoRIM Meta: %w", err)
+	}
+
+	if o.signMessage == nil {
+		if err := o.initSignMessage(); err != nil {
+			return nil, err
+		}
+	}
+
+	protected, err := o.signMessage.Headers.MarshalProtected()
+	if err != nil {
+		return nil, fmt.Errorf("encoding protected header: %w", err)
+	}
+
+	sig := cose.NewSignature()
+	sig.Headers.Protected.SetAlgorithm(alg)
+	sig.Headers.Protected[HeaderLabelCorimMeta] = metaCBOR
+
+	if len(kid) != 0 {
+		sig.Headers.Protected[cose.HeaderLabelKeyID] = kid
+	}
+
+	err = sig.Sign(rand.Reader, signer, protected, o.signMessage.Payload, NoExternalData)
+	if err != nil {
+		return nil, fmt.Errorf("COSE Sign signature failed: %w", err)
+	}
+
+	o.signMessage.Signatures = append(o.signMessage.Signatures, sig)
+	o.Signatures = append(o.Signatures, CorimSignature{Meta: meta, KeyID: kid, Algorithm: alg})
+
+	wrap, err := o.signMessage.MarshalCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("signed-corim marshaling failed: %w", err)
+	}
+
+	return wrap, nil
+}
+
+func (o *SignedCorim) initSignMessage() error {
+	if err := o.UnsignedCorim.Valid(); err != nil {
+		return fmt.Errorf("failed validation of unsigned CoRIM: %w", err)
+	}
+
+	payload, err := o.UnsignedCorim.ToCBOR()
+	if err != nil {
+		return fmt.Errorf("failed CBOR encoding of unsigned CoRIM: %w", err)
+	}
+
+	m := cose.NewSignMessage()
+	m.Payload = payload
+	m.Headers.Protected[cose.HeaderLabelContentType] = ContentType
+
+	o.signMessage = m
+
+	return nil
+}
+
+// fromCOSESign decodes a COSE Sign signed-corim
+func (o *SignedCorim) fromCOSESign(buf []byte) error {
+	m := cose.NewSignMessage()
+
+	if err := m.UnmarshalCBOR(buf); err != nil {
+		return fmt.Errorf("failed CBOR decoding for COSE-Sign signed CoRIM: %w", err)
+	}
+
+	if err := checkContentType(m.Headers); err != nil {
+		return fmt.Errorf("processing COSE headers: %w", err)
+	}
+
+	sigs := make([]CorimSignature, 0, len(m.Signatures))
+
+	for i, s := range m.Signatures {
+		sig, err := decodeSignatureHdrs(s.Headers)
+		if err != nil {
+			return fmt.Errorf("processing COSE headers of signature %d: %w", i, err)
+		}
+		sigs = append(sigs, *sig)
+	}
+
+	if err := o.UnsignedCorim.FromCBOR(m.Payload); err != nil {
+		return fmt.Errorf("failed CBOR decoding of unsigned CoRIM: %w", err)
+	}
+
+	if err := o.UnsignedCorim.Valid(); err != nil {
+		return fmt.Errorf("failed validation of unsigned CoRIM: %w", err)
+	}
+
+	o.message, o.signMessage, o.Signatures = nil, m, sigs
+
+	// the fields that only apply to COSE Sign1 are reset, in case the
+	// SignedCorim was used before
+	o.Meta, o.KeyID, o.Detached = Meta{}, nil, false
+	o.SigningCert, o.IntermediateCerts = nil, nil
+	o.Countersignatures, o.TimestampToken = nil, nil
+
+	return nil
+}
+
+func decodeSignatureHdrs(hdr cose.Headers) (*CorimSignature, error) {
+	alg, err := hdr.Protected.Algorithm()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get signature algorithm: %w", err)
+	}
+
+	meta, err := decodeMetaHdr(hdr)
+	if err != nil {
+		return nil, err
+	}
+
+	kid, err := decodeKeyIDHdr(hdr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CorimSignature{Meta: *meta, KeyID: kid, Algorithm: alg}, nil
+}
+
+// VerifySigners verifies the signatures of the target COSE Sign SignedCorim
+// object, according to the supplied policy.  The verification keys of each
+// signature are looked up with the supplied KeyResolver, using the signature's
+// kid and corim-meta-map signer, and must be accepted, together with the
+// signature's algorithm, by the AlgorithmPolicy of the options, if set, or
+// else of the SignedCorim, if set.  The validity periods
+// of the signature and of the CoRIM are checked as VerifyWithOptions does.  On
+// success, the signatures that have been verified are returned.
+func (o *SignedCorim) VerifySigners(
+	r KeyResolver, policy SignerPolicy, opts VerifyOptions,
+) ([]CorimSignature, error) {
+	if r == nil {
+		return nil, errors.New("nil key resolver")
+	}
+
+	if o.signMessage == nil {
+		return nil, errors.New("no Sign message found")
+	}
+
+	if len(o.signMessage.Signatures) == 0 {
+		return nil, cose.ErrNoSignatures
+	}
+
+	protected, err := o.signMessage.Headers.MarshalProtected()
+	if err != nil {
+		return nil, fmt.Errorf("encoding protected header: %w", err)
+	}
+
+	var (
+		verified []CorimSignature
+		errs     []error
+		now      = opts.now()
+		algs     = opts.policy(o.AlgorithmPolicy)
+	)
+
+	for i, s := range o.signMessage.Signatures {
+		err := o.verifySignature(r, s, o.Signatures[i], protected, algs)
+		if err == nil {
+			err = checkValidity(o.Signatures[i].Meta.Validity, o.UnsignedCorim.RimValidity, now, opts.Skew)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("signature %d: %w", i, err)
+			if policy == SignerPolicyAll {
+				return nil, err
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		verified = append(verified, o.Signatures[i])
+	}
+
+	if len(verified) == 0 {
+		return nil, fmt.Errorf("no signature verified: %w", errors.Join(errs...))
+	}
+
+	return verified, nil
+}
+
+// nolint:gocritic
+func (o *SignedCorim) verifySignature(
+	r KeyResolver, s *cose.Signature, info CorimSignature, protected []byte, algs *AlgorithmPolicy,
+) error {
+	keys, err := r.ResolveKeys(info.KeyID, info.Meta.Signer)
+	if err != nil {
+		return fmt.Errorf("resolving verification keys: %w", err)
+	}
+
+	if len(keys) == 0 {
+		return ErrNoKey
+	}
+
+	for _, pk := range keys {
+		if err = algs.Check(info.Algorithm, pk); err != nil {
+			continue
+		}
+
+		var verifier cose.Verifier
+
+		verifier, err = newVerifier(info.Algorithm, pk)
+		if err != nil {
+			err = fmt.Errorf("unable to instantiate verifier: %w", err)
+			continue
+		}
+
+		err = s.Verify(verifier, protected, o.signMessage.Payload, NoExternalData)
+		if err == nil {
+			return nil
+		}
+	}
+
+	// report the error from the last key tried
+	return err
+}
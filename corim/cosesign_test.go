@@ -0,0 +1,188 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cose "github.com/veraison/go-cose"
+)
+
+func testMultiSignerResolver(t *testing.T) *JWKSetResolver {
+	r, err := NewJWKSetResolver(testJWKSet(t, map[string][]byte{
+		"vendor": testES256Key,
+		"oem":    testES384Key,
+	}))
+	require.NoError(t, err)
+
+	return r
+}
+
+// signVendorAndOEM returns a COSE Sign signed-corim with a first signature by
+// the silicon vendor, then a second signature by the OEM, added to the decoded
+// signed-corim
+func signVendorAndOEM(t *testing.T, oemMeta *Meta) []byte {
+	vendorSigner, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	oemSigner, err := NewSignerFromJWK(testES384Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+
+	cbor, err := SignedCorimIn.AddSigner(vendorSigner, *NewMeta().SetSigner("Silicon Vendor", nil), []byte("vendor"))
+	require.NoError(t, err)
+
+	var OEMCorim SignedCorim
+	require.NoError(t, OEMCorim.FromCOSE(cbor))
+	require.Len(t, OEMCorim.Signatures, 1)
+
+	cbor, err = OEMCorim.AddSigner(oemSigner, *oemMeta, []byte("oem"))
+	require.NoError(t, err)
+
+	return cbor
+}
+
+func TestSignedCorim_AddSigner_VerifySigners(t *testing.T) {
+	cbor := signVendorAndOEM(t, NewMeta().SetSigner("OEM", nil))
+
+	SignedCorimOut, err := UnmarshalSignedCorimFromCBOR(cbor)
+	require.NoError(t, err)
+	require.Len(t, SignedCorimOut.Signatures, 2)
+
+	assert.Equal(t, "Silicon Vendor", SignedCorimOut.Signatures[0].Meta.Signer.Name)
+	assert.Equal(t, []byte("vendor"), SignedCorimOut.Signatures[0].KeyID)
+	assert.Equal(t, cose.AlgorithmES256, SignedCorimOut.Signatures[0].Algorithm)
+	assert.Equal(t, "OEM", SignedCorimOut.Signatures[1].Meta.Signer.Name)
+	assert.Equal(t, cose.AlgorithmES384, SignedCorimOut.Signatures[1].Algorithm)
+
+	verified, err := SignedCorimOut.VerifySigners(testMultiSignerResolver(t), SignerPolicyAll, VerifyOptions{})
+	require.NoError(t, err)
+	assert.Len(t, verified, 2)
+
+	// the tagged-corim-type-choice prefix is accepted as well
+	require.NoError(t, SignedCorimOut.FromCOSE(append(corimTypeChoiceSigned, cbor...)))
+	assert.Len(t, SignedCorimOut.Signatures, 2)
+}
+
+func TestSignedCorim_VerifySigners_policy(t *testing.T) {
+	var SignedCorimOut SignedCorim
+	require.NoError(t, SignedCorimOut.FromCOSE(signVendorAndOEM(t, NewMeta().SetSigner("OEM", nil))))
+
+	// only the vendor key is known
+	r, err := NewJWKSetResolver(testJWKSet(t, map[string][]byte{"vendor": testES256Key}))
+	require.NoError(t, err)
+
+	verified, err := SignedCorimOut.VerifySigners(r, SignerPolicyAny, VerifyOptions{})
+	require.NoError(t, err)
+	require.Len(t, verified, 1)
+	assert.Equal(t, "Silicon Vendor", verified[0].Meta.Signer.Name)
+
+	_, err = SignedCorimOut.VerifySigners(r, SignerPolicyAll, VerifyOptions{})
+	assert.ErrorIs(t, err, ErrNoKey)
+	assert.EqualError(t, err, "signature 1: no verification key found")
+
+	// no key is known
+	r, err = NewJWKSetResolver(testJWKSet(t, map[string][]byte{"other": testEdDSAKey}))
+	require.NoError(t, err)
+
+	_, err = SignedCorimOut.VerifySigners(r, SignerPolicyAny, VerifyOptions{})
+	assert.EqualError(t, err, "no signature verified: signature 0: no verification key found\n"+
+		"signature 1: no verification key found")
+}
+
+func TestSignedCorim_VerifySigners_validity(t *testing.T) {
+	notAfter := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	var SignedCorimOut SignedCorim
+	require.NoError(t, SignedCorimOut.FromCOSE(signVendorAndOEM(t,
+		NewMeta().SetSigner("OEM", nil).SetValidity(notAfter, nil))))
+
+	opts := VerifyOptions{Clock: func() time.Time { return notAfter.Add(time.Hour) }}
+
+	_, err := SignedCorimOut.VerifySigners(testMultiSignerResolver(t), SignerPolicyAll, opts)
+	assert.ErrorIs(t, err, ErrExpired)
+
+	var verr *ValidityError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, ValiditySignature, verr.Scope)
+
+	verified, err := SignedCorimOut.VerifySigners(testMultiSignerResolver(t), SignerPolicyAny, opts)
+	require.NoError(t, err)
+	assert.Len(t, verified, 1)
+}
+
+func TestSignedCorim_Sign_vs_Sign1(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	pk, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	// COSE Sign1 cannot be used as COSE Sign, and vice versa
+	Sign1Corim := SignedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+	_, err = Sign1Corim.Sign(signer)
+	require.NoError(t, err)
+
+	_, err = Sign1Corim.AddSigner(signer, *metaGood(t), nil)
+	assert.EqualError(t, err, "cannot add a signer to a COSE Sign1 signed CoRIM")
+
+	_, err = Sign1Corim.VerifySigners(testMultiSignerResolver(t), SignerPolicyAny, VerifyOptions{})
+	assert.EqualError(t, err, "no Sign message found")
+
+	var SignCorim SignedCorim
+	require.NoError(t, SignCorim.FromCOSE(signVendorAndOEM(t, NewMeta().SetSigner("OEM", nil))))
+	assert.EqualError(t, SignCorim.Verify(pk), "COSE Sign signed CoRIM: use VerifySigners")
+
+	// a SignedCorim decoded from COSE Sign1 has no Signatures
+	cbor, err := Sign1Corim.Sign(signer)
+	require.NoError(t, err)
+	require.NoError(t, SignCorim.FromCOSE(cbor))
+	assert.Nil(t, SignCorim.Signatures)
+	assert.NoError(t, SignCorim.Verify(pk))
+}
+
+func TestSignedCorim_FromCOSE_Sign_resets_Sign1_fields(t *testing.T) {
+	leaf := newTestCert(t, "leaf", newTestCert(t, "root", nil, nil), nil)
+
+	var SignedCorimOut SignedCorim
+	require.NoError(t, SignedCorimOut.FromCOSE(signWithCerts(t, leaf)))
+	require.NotNil(t, SignedCorimOut.SigningCert)
+	require.Equal(t, "ACME Ltd.", SignedCorimOut.Meta.Signer.Name)
+
+	SignedCorimOut.KeyID = []byte("old")
+	SignedCorimOut.IntermediateCerts = []*x509.Certificate{leaf.cert}
+	SignedCorimOut.Countersignatures = []Countersignature{{KeyID: []byte("old")}}
+	SignedCorimOut.TimestampToken = []byte{0x30, 0x00}
+	SignedCorimOut.Detached = true
+
+	// decoding a COSE Sign signed-corim into the same SignedCorim
+	require.NoError(t, SignedCorimOut.FromCOSE(signVendorAndOEM(t, NewMeta().SetSigner("OEM", nil))))
+	require.Len(t, SignedCorimOut.Signatures, 2)
+
+	assert.Equal(t, Meta{}, SignedCorimOut.Meta)
+	assert.Nil(t, SignedCorimOut.KeyID)
+	assert.Nil(t, SignedCorimOut.SigningCert)
+	assert.Nil(t, SignedCorimOut.IntermediateCerts)
+	assert.Nil(t, SignedCorimOut.Countersignatures)
+	assert.Nil(t, SignedCorimOut.TimestampToken)
+	assert.False(t, SignedCorimOut.Detached)
+}
+
+func TestSignedCorim_AddSigner_fail(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	var SignedCorimIn SignedCorim
+
+	_, err = SignedCorimIn.AddSigner(nil, *metaGood(t), nil)
+	assert.EqualError(t, err, "nil signer")
+
+	_, err = SignedCorimIn.AddSigner(signer, *metaGood(t), nil)
+	assert.EqualError(t, err, "failed validation of unsigned CoRIM: empty id")
+}
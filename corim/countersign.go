@@ -0,0 +1,324 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	cose "github.com/veraison/go-cose"
+)
+
+// HeaderLabelCountersignature is the label of the (version 2) Countersignature
+// header parameter (RFC 9338)
+var HeaderLabelCountersignature = int64(11)
+
+// Countersignature describes a COSE countersignature (RFC 9338) carried in the
+// unprotected header of a COSE Sign1 signed-corim.  A countersignature signs
+// the signed-corim's protected header, payload and signature, and can thus be
+// added, e.g., by a notary, without invalidating the signature.
+type Countersignature struct {
+	// KeyID is the kid header of the countersignature, if any
+	KeyID     []byte
+	Algorithm cose.Algorithm
+	protected cbor.RawMessage
+	signature []byte
+}
+
+// coseCountersignature is the COSE_Countersignature structure
+type coseCountersignature struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   cbor.RawMessage
+	Unprotected cose.UnprotectedHeader
+	Signature   []byte
+}
+
+// AddCountersignature countersigns the target SignedCorim, which must hold a
+// signed COSE Sign1 message, with the supplied cose Signer and (optional) key
+// identifier, and returns the serialized signed-corim with the new
+// countersignature added to those already present.
+func (o *SignedCorim) AddCountersignature(signer cose.Signer, kid []byte) ([]byte, error) {
+	if signer == nil {
+		return nil, errors.New("nil signer")
+	}
+
+	if err := o.checkSigned(); err != nil {
+		return nil, err
+	}
+
+	alg, err := signerAlgorithm(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr := cose.ProtectedHeader{}
+	hdr.SetAlgorithm(alg)
+
+	if len(kid) != 0 {
+		hdr[cose.HeaderLabelKeyID] = kid
+	}
+
+	protected, err := hdr.MarshalCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("encoding countersignature protected header: %w", err)
+	}
+
+	tbs, err := o.countersignTBS(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := signer.Sign(rand.Reader, tbs)
+	if err != nil {
+		return nil, fmt.Errorf("COSE countersignature failed: %w", err)
+	}
+
+	countersigs := make([]Countersignature, 0, len(o.Countersignatures)+1)
+	countersigs = append(countersigs, o.Countersignatures...)
+	countersigs = append(countersigs, Countersignature{
+		KeyID:     kid,
+		Algorithm: alg,
+		protected: protected,
+		signature: sig,
+	})
+
+	wrap, err := o.setUnprotectedHdr(HeaderLabelCountersignature, countersignaturesHdr(countersigs))
+	if err != nil {
+		return nil, err
+	}
+
+	o.Countersignatures = countersigs
+
+	return wrap, nil
+}
+
+// checkSigned checks that the target SignedCorim holds a signed COSE Sign1
+// message, to which unprotected headers can be added
+func (o *SignedCorim) checkSigned() error {
+	if o.message == nil {
+		if o.signMessage != nil {
+			return errors.New("COSE Sign signed CoRIM: only COSE Sign1 is supported")
+		}
+		return errors.New("no Sign1 message found")
+	}
+
+	if len(o.message.Signature) == 0 {
+		return errors.New("Sign1 message is not signed")
+	}
+
+	return nil
+}
+
+// countersignTBS returns the encoded Countersign_structure for a (version 2)
+// countersignature with the supplied (encoded) protected header over the Sign1
+// message.  Since the signature of the Sign1 message is carried in
+// other_fields, the context is "CounterSignatureV2" (RFC 9338, Section 3.3).
+func (o *SignedCorim) countersignTBS(protected cbor.RawMessage) ([]byte, error) {
+	if o.message.Payload == nil {
+		return nil, errors.New("detached payload not attached")
+	}
+
+	bodyProtected, err := o.message.Headers.MarshalProtected()
+	if err != nil {
+		return nil, fmt.Errorf("encoding protected header: %w", err)
+	}
+
+	tbs, err := em.Marshal([]any{
+		"CounterSignatureV2",
+		cbor.RawMessage(bodyProtected),
+		protected,
+		NoExternalData,
+		o.message.Payload,
+		[][]byte{o.message.Signature},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding Countersign_structure: %w", err)
+	}
+
+	return tbs, nil
+}
+
+// countersignaturesHdr returns the value of the Countersignature header: a
+// single COSE_Countersignature, or an array of them if there are more than one
+func countersignaturesHdr(countersigs []Countersignature) any {
+	hdrs := make([]coseCountersignature, 0, len(countersigs))
+
+	for _, c := range countersigs {
+		hdrs = append(hdrs, coseCountersignature{
+			Protected:   c.protected,
+			Unprotected: cose.UnprotectedHeader{},
+			Signature:   c.signature,
+		})
+	}
+
+	if len(hdrs) == 1 {
+		return hdrs[0]
+	}
+
+	return hdrs
+}
+
+// processCountersignatures populates Countersignatures from the
+// Countersignature header, if present
+func (o *SignedCorim) processCountersignatures() error {
+	o.Countersignatures = nil
+
+	v, ok := o.message.Headers.Unprotected[HeaderLabelCountersignature]
+	if !ok {
+		return nil
+	}
+
+	data, err := em.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var hdrs []coseCountersignature
+
+	var single coseCountersignature
+	if err := dm.Unmarshal(data, &single); err == nil {
+		hdrs = []coseCountersignature{single}
+	} else if err := dm.Unmarshal(data, &hdrs); err != nil {
+		return fmt.Errorf("expecting COSE_Countersignature or array of COSE_Countersignature: %w", err)
+	}
+
+	if len(hdrs) == 0 {
+		return errors.New("empty countersignature array")
+	}
+
+	countersigs := make([]Countersignature, 0, len(hdrs))
+
+	for i, h := range hdrs {
+		c, err := decodeCountersignature(h)
+		if err != nil {
+			return fmt.Errorf("countersignature %d: %w", i, err)
+		}
+		countersigs = append(countersigs, *c)
+	}
+
+	o.Countersignatures = countersigs
+
+	return nil
+}
+
+// nolint:gocritic
+func decodeCountersignature(h coseCountersignature) (*Countersignature, error) {
+	var hdr cose.ProtectedHeader
+
+	if err := hdr.UnmarshalCBOR(h.Protected); err != nil {
+		return nil, fmt.Errorf("decoding protected header: %w", err)
+	}
+
+	alg, err := hdr.Algorithm()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get countersignature algorithm: %w", err)
+	}
+
+	kid, err := decodeKeyIDHdr(cose.Headers{Protected: hdr, Unprotected: h.Unprotected})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(h.Signature) == 0 {
+		return nil, errors.New("empty signature")
+	}
+
+	return &Countersignature{
+		KeyID:     kid,
+		Algorithm: alg,
+		protected: h.Protected,
+		signature: h.Signature,
+	}, nil
+}
+
+// VerifyCountersignatures verifies the countersignatures of the target COSE
+// Sign1 SignedCorim object, according to the supplied policy.  The
+// verification keys of each countersignature are looked up with the supplied
+// KeyResolver, using the countersignature's kid (the signer is empty), and
+// must be accepted, together with the countersignature's algorithm, by the
+// AlgorithmPolicy, if set.  The options' AlgorithmPolicy, if set, applies
+// instead of the SignedCorim's; the other options are not used.  On success,
+// the countersignatures that have been verified are returned.  The signature
+// of the signed-corim itself is not verified.
+func (o *SignedCorim) VerifyCountersignatures(
+	r KeyResolver, policy SignerPolicy, opts VerifyOptions,
+) ([]Countersignature, error) {
+	if r == nil {
+		return nil, errors.New("nil key resolver")
+	}
+
+	if err := o.checkSigned(); err != nil {
+		return nil, err
+	}
+
+	if len(o.Countersignatures) == 0 {
+		return nil, errors.New("no countersignature found")
+	}
+
+	var (
+		verified []Countersignature
+		errs     []error
+		algs     = opts.policy(o.AlgorithmPolicy)
+	)
+
+	for i, c := range o.Countersignatures {
+		if err := o.verifyCountersignature(r, c, algs); err != nil {
+			err = fmt.Errorf("countersignature %d: %w", i, err)
+			if policy == SignerPolicyAll {
+				return nil, err
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		verified = append(verified, c)
+	}
+
+	if len(verified) == 0 {
+		return nil, fmt.Errorf("no countersignature verified: %w", errors.Join(errs...))
+	}
+
+	return verified, nil
+}
+
+// nolint:gocritic
+func (o *SignedCorim) verifyCountersignature(r KeyResolver, c Countersignature, algs *AlgorithmPolicy) error {
+	keys, err := r.ResolveKeys(c.KeyID, Signer{})
+	if err != nil {
+		return fmt.Errorf("resolving verification keys: %w", err)
+	}
+
+	if len(keys) == 0 {
+		return ErrNoKey
+	}
+
+	tbs, err := o.countersignTBS(c.protected)
+	if err != nil {
+		return err
+	}
+
+	for _, pk := range keys {
+		if err = algs.Check(c.Algorithm, pk); err != nil {
+			continue
+		}
+
+		var verifier cose.Verifier
+
+		verifier, err = newVerifier(c.Algorithm, pk)
+		if err != nil {
+			err = fmt.Errorf("unable to instantiate verifier: %w", err)
+			continue
+		}
+
+		err = verifier.Verify(tbs, c.signature)
+		if err == nil {
+			return nil
+		}
+	}
+
+	// report the error from the last key tried
+	return err
+}
@@ -0,0 +1,245 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cose "github.com/veraison/go-cose"
+)
+
+// staticKeyResolver resolves any kid and signer to the same keys
+type staticKeyResolver []crypto.PublicKey
+
+func (o staticKeyResolver) ResolveKeys([]byte, Signer) ([]crypto.PublicKey, error) {
+	return o, nil
+}
+
+func testNotaryResolver(t *testing.T, keys map[string][]byte) *JWKSetResolver {
+	r, err := NewJWKSetResolver(testJWKSet(t, keys))
+	require.NoError(t, err)
+
+	return r
+}
+
+func TestSignedCorim_AddCountersignature_VerifyCountersignatures(t *testing.T) {
+	notary, err := NewSignerFromJWK(testES384Key)
+	require.NoError(t, err)
+
+	otherNotary, err := NewSignerFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	pk, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := signWithKeyID(t, testES256Key, []byte("1"), "ACME Ltd.")
+
+	cbor, err := SignedCorimIn.AddCountersignature(notary, []byte("notary"))
+	require.NoError(t, err)
+
+	var SignedCorimOut SignedCorim
+
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+	require.Len(t, SignedCorimOut.Countersignatures, 1)
+	assert.Equal(t, []byte("notary"), SignedCorimOut.Countersignatures[0].KeyID)
+	assert.Equal(t, cose.AlgorithmES384, SignedCorimOut.Countersignatures[0].Algorithm)
+
+	// the countersignature does not invalidate the signature
+	assert.NoError(t, SignedCorimOut.Verify(pk))
+
+	r := testNotaryResolver(t, map[string][]byte{"notary": testES384Key})
+
+	verified, err := SignedCorimOut.VerifyCountersignatures(r, SignerPolicyAll, VerifyOptions{})
+	require.NoError(t, err)
+	assert.Len(t, verified, 1)
+
+	// countersignatures are added to those already present
+	cbor, err = SignedCorimOut.AddCountersignature(otherNotary, []byte("other-notary"))
+	require.NoError(t, err)
+
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+	require.Len(t, SignedCorimOut.Countersignatures, 2)
+	assert.NoError(t, SignedCorimOut.Verify(pk))
+
+	verified, err = SignedCorimOut.VerifyCountersignatures(r, SignerPolicyAny, VerifyOptions{})
+	require.NoError(t, err)
+	require.Len(t, verified, 1)
+	assert.Equal(t, []byte("notary"), verified[0].KeyID)
+
+	_, err = SignedCorimOut.VerifyCountersignatures(r, SignerPolicyAll, VerifyOptions{})
+	assert.ErrorIs(t, err, ErrNoKey)
+	assert.EqualError(t, err, "countersignature 1: no verification key found")
+}
+
+func TestSignedCorim_VerifyCountersignatures_bad_signature(t *testing.T) {
+	notary, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := signWithKeyID(t, testES256Key, nil, "ACME Ltd.")
+
+	cbor, err := SignedCorimIn.AddCountersignature(notary, []byte("notary"))
+	require.NoError(t, err)
+
+	var SignedCorimOut SignedCorim
+
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+
+	// a different key of the same type
+	sec1Key, err := NewPublicKeyFromPEM(readTestKey(t, "ec-p256-pub.pem"))
+	require.NoError(t, err)
+
+	_, err = SignedCorimOut.VerifyCountersignatures(
+		staticKeyResolver{sec1Key}, SignerPolicyAny, VerifyOptions{},
+	)
+	assert.EqualError(t, err, "no countersignature verified: countersignature 0: verification error")
+
+	// the algorithm policy applies to countersignatures
+	SignedCorimOut.AlgorithmPolicy = &AlgorithmPolicy{Algorithms: []cose.Algorithm{cose.AlgorithmES384}}
+
+	_, err = SignedCorimOut.VerifyCountersignatures(
+		testNotaryResolver(t, map[string][]byte{"notary": testES256Key}), SignerPolicyAll, VerifyOptions{},
+	)
+	assert.ErrorIs(t, err, ErrAlgorithmPolicy)
+
+	// so does the verifier's own policy, which overrides the SignedCorim's
+	SignedCorimOut.AlgorithmPolicy = nil
+
+	_, err = SignedCorimOut.VerifyCountersignatures(
+		testNotaryResolver(t, map[string][]byte{"notary": testES256Key}), SignerPolicyAll,
+		VerifyOptions{AlgorithmPolicy: &AlgorithmPolicy{Algorithms: []cose.Algorithm{cose.AlgorithmES384}}},
+	)
+	assert.ErrorIs(t, err, ErrAlgorithmPolicy)
+
+	SignedCorimOut.AlgorithmPolicy = &AlgorithmPolicy{Algorithms: []cose.Algorithm{cose.AlgorithmES384}}
+
+	_, err = SignedCorimOut.VerifyCountersignatures(
+		testNotaryResolver(t, map[string][]byte{"notary": testES256Key}), SignerPolicyAll,
+		VerifyOptions{AlgorithmPolicy: &AlgorithmPolicy{Algorithms: []cose.Algorithm{cose.AlgorithmES256}}},
+	)
+	assert.NoError(t, err)
+}
+
+// testBstr returns the CBOR encoding of the supplied byte string
+func testBstr(b []byte) []byte {
+	var head []byte
+
+	switch n := len(b); {
+	case n < 24:
+		head = []byte{0x40 | byte(n)}
+	case n < 0x100:
+		head = []byte{0x58, byte(n)}
+	default:
+		head = []byte{0x59, byte(n >> 8), byte(n)}
+	}
+
+	return append(head, b...)
+}
+
+// testCountersignV2TBS assembles, byte by byte, the Countersign_structure of
+// a version 2 countersignature over a COSE_Sign1 (RFC 9338, Section 3.3).  The
+// protected headers are supplied already encoded as CBOR byte strings.
+//
+//	[ "CounterSignatureV2", body_protected, sign_protected, external_aad,
+//	  payload, [ signature ] ]
+func testCountersignV2TBS(bodyProtected, signProtected, payload, signature []byte) []byte {
+	tbs := []byte{0x86, 0x72}
+	tbs = append(tbs, "CounterSignatureV2"...)
+	tbs = append(tbs, bodyProtected...)
+	tbs = append(tbs, signProtected...)
+	tbs = append(tbs, 0x40)
+	tbs = append(tbs, testBstr(payload)...)
+	tbs = append(tbs, 0x81)
+	tbs = append(tbs, testBstr(signature)...)
+
+	return tbs
+}
+
+func TestSignedCorim_Countersignature_RFC9338_structure(t *testing.T) {
+	_, key, err := getAlgAndKeyFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	priv := key.(ed25519.PrivateKey)
+	pub := priv.Public().(ed25519.PublicKey)
+
+	notary, err := NewSignerFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	SignedCorimIn := signWithKeyID(t, testES256Key, []byte("1"), "ACME Ltd.")
+
+	cbor, err := SignedCorimIn.AddCountersignature(notary, []byte("notary"))
+	require.NoError(t, err)
+
+	var SignedCorimOut SignedCorim
+
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+	require.Len(t, SignedCorimOut.Countersignatures, 1)
+
+	bodyProtected, err := SignedCorimOut.message.Headers.MarshalProtected()
+	require.NoError(t, err)
+
+	payload := SignedCorimOut.message.Payload
+	signature := SignedCorimOut.message.Signature
+
+	// the countersignature produced is over the RFC 9338 structure
+	c := SignedCorimOut.Countersignatures[0]
+	tbs := testCountersignV2TBS(bodyProtected, c.protected, payload, signature)
+	assert.True(t, ed25519.Verify(pub, tbs, c.signature))
+
+	// a countersignature made over the RFC 9338 structure is accepted
+	signProtected := []byte{0x43, 0xa1, 0x01, 0x27} // << {1: -8 (EdDSA)} >>
+	sig := ed25519.Sign(priv, testCountersignV2TBS(bodyProtected, signProtected, payload, signature))
+
+	cbor, err = SignedCorimOut.setUnprotectedHdr(HeaderLabelCountersignature, coseCountersignature{
+		Protected:   signProtected,
+		Unprotected: cose.UnprotectedHeader{},
+		Signature:   sig,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+
+	verified, err := SignedCorimOut.VerifyCountersignatures(staticKeyResolver{pub}, SignerPolicyAll, VerifyOptions{})
+	require.NoError(t, err)
+	require.Len(t, verified, 1)
+	assert.Equal(t, cose.AlgorithmEd25519, verified[0].Algorithm)
+}
+
+func TestSignedCorim_AddCountersignature_fail(t *testing.T) {
+	notary, err := NewSignerFromJWK(testES384Key)
+	require.NoError(t, err)
+
+	var SignedCorimIn SignedCorim
+
+	_, err = SignedCorimIn.AddCountersignature(nil, nil)
+	assert.EqualError(t, err, "nil signer")
+
+	_, err = SignedCorimIn.AddCountersignature(notary, nil)
+	assert.EqualError(t, err, "no Sign1 message found")
+
+	_, err = SignedCorimIn.VerifyCountersignatures(testNotaryResolver(t, nil), SignerPolicyAny, VerifyOptions{})
+	assert.EqualError(t, err, "no Sign1 message found")
+
+	var SignCorim SignedCorim
+	require.NoError(t, SignCorim.FromCOSE(signVendorAndOEM(t, NewMeta().SetSigner("OEM", nil))))
+
+	_, err = SignCorim.AddCountersignature(notary, nil)
+	assert.EqualError(t, err, "COSE Sign signed CoRIM: only COSE Sign1 is supported")
+
+	SignedCorimIn = *signWithKeyID(t, testES256Key, nil, "ACME Ltd.")
+
+	_, err = SignedCorimIn.VerifyCountersignatures(testNotaryResolver(t, nil), SignerPolicyAny, VerifyOptions{})
+	assert.EqualError(t, err, "no countersignature found")
+
+	// a malformed countersignature header is rejected on decoding
+	cbor, err := SignedCorimIn.setUnprotectedHdr(HeaderLabelCountersignature, "bad")
+	require.NoError(t, err)
+
+	err = SignedCorimIn.FromCOSE(cbor)
+	assert.ErrorContains(t, err, "processing COSE headers: processing countersignatures: "+
+		"expecting COSE_Countersignature or array of COSE_Countersignature")
+}
@@ -0,0 +1,546 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	cose "github.com/veraison/go-cose"
+)
+
+// SignedContentType is the content type of a signed-corim.  It is conveyed in
+// the protected header of an encrypted CoRIM whose plaintext is a
+// signed-corim (i.e., sign-then-encrypt), while ContentType is used for an
+// encrypted unsigned-corim.
+var SignedContentType = "application/rim+cose"
+
+// COSE content encryption (RFC 9053, Section 4.1) and key wrap (RFC 9053,
+// Section 6.2.1) algorithms
+const (
+	AlgorithmA128GCM cose.Algorithm = 1
+	AlgorithmA192GCM cose.Algorithm = 2
+	AlgorithmA256GCM cose.Algorithm = 3
+	AlgorithmA128KW  cose.Algorithm = -3
+	AlgorithmA192KW  cose.Algorithm = -4
+	AlgorithmA256KW  cose.Algorithm = -5
+)
+
+// ErrNoDecryptionKey is returned when none of the keys needed to decrypt an
+// encrypted CoRIM can be found
+var ErrNoDecryptionKey = errors.New("no decryption key found")
+
+var (
+	// coseEncrypt0Tag is the CBOR tag #6.16 of a COSE_Encrypt0 message
+	coseEncrypt0Tag = []byte{0xd0}
+	// coseEncryptTag is the CBOR tag #6.96 of a COSE_Encrypt message
+	coseEncryptTag = []byte{0xd8, 0x60}
+)
+
+const gcmNonceSize = 12
+
+// Recipient is a recipient of a COSE_Encrypt encrypted CoRIM.  The content
+// encryption key is wrapped for each recipient with its key encryption key.
+type Recipient struct {
+	// KeyID, if set, is conveyed in the kid header of the recipient, and is
+	// used to look up the key encryption key on decryption
+	KeyID []byte
+	// Algorithm is the AES key wrap algorithm (AlgorithmA128KW,
+	// AlgorithmA192KW or AlgorithmA256KW)
+	Algorithm cose.Algorithm
+	// Key is the key encryption key
+	Key []byte
+}
+
+// EncryptOptions controls the encryption of a CoRIM.  If no Recipients are
+// set, a COSE_Encrypt0 message is produced with the supplied content
+// encryption Key.  Otherwise, a COSE_Encrypt message is produced with a random
+// content encryption key, wrapped for each of the Recipients.
+type EncryptOptions struct {
+	// Algorithm is the AES-GCM content encryption algorithm
+	// (AlgorithmA128GCM, AlgorithmA192GCM or AlgorithmA256GCM)
+	Algorithm cose.Algorithm
+	// Key is the content encryption key of a COSE_Encrypt0 message
+	Key []byte
+	// KeyID, if set, is conveyed in the kid header of a COSE_Encrypt0
+	// message
+	KeyID      []byte
+	Recipients []Recipient
+}
+
+// DecryptionKeyResolver looks up the symmetric keys used to decrypt an
+// encrypted CoRIM: the content encryption key of a COSE_Encrypt0 message, or
+// the key encryption key of a COSE_Encrypt recipient
+type DecryptionKeyResolver interface {
+	// ResolveDecryptionKey returns the key with the supplied kid (which may
+	// be empty) for the supplied algorithm, or nil if there is none
+	ResolveDecryptionKey(kid []byte, alg cose.Algorithm) ([]byte, error)
+}
+
+// SymmetricKeys is a DecryptionKeyResolver of keys indexed by kid.  The key of
+// a message or recipient with no kid is indexed by the empty string.
+type SymmetricKeys map[string][]byte
+
+// ResolveDecryptionKey returns the key with the supplied kid, whatever the
+// algorithm
+func (o SymmetricKeys) ResolveDecryptionKey(kid []byte, _ cose.Algorithm) ([]byte, error) {
+	return o[string(kid)], nil
+}
+
+// coseEncrypt0 is the COSE_Encrypt0 structure
+type coseEncrypt0 struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   cbor.RawMessage
+	Unprotected cose.UnprotectedHeader
+	Ciphertext  []byte
+}
+
+// coseEncrypt is the COSE_Encrypt structure
+type coseEncrypt struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   cbor.RawMessage
+	Unprotected cose.UnprotectedHeader
+	Ciphertext  []byte
+	Recipients  []coseRecipient
+}
+
+// coseRecipient is the COSE_recipient structure of a key wrap recipient
+type coseRecipient struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   cbor.RawMessage
+	Unprotected cose.UnprotectedHeader
+	Ciphertext  []byte
+}
+
+// Encrypt returns the target UnsignedCorim, which must be valid, encrypted
+// according to the supplied options
+// nolint:gocritic
+func (o *UnsignedCorim) Encrypt(opts EncryptOptions) ([]byte, error) {
+	if err := o.Valid(); err != nil {
+		return nil, fmt.Errorf("failed validation of unsigned CoRIM: %w", err)
+	}
+
+	plaintext, err := o.ToCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("failed CBOR encoding of unsigned CoRIM: %w", err)
+	}
+
+	return encrypt(plaintext, ContentType, opts)
+}
+
+// SignAndEncrypt signs the target SignedCorim with the supplied cose Signer
+// (see Sign), and returns the resulting signed-corim encrypted according to
+// the supplied options
+// nolint:gocritic
+func (o *SignedCorim) SignAndEncrypt(signer cose.Signer, opts EncryptOptions) ([]byte, error) {
+	signed, err := o.Sign(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	return EncryptSignedCorim(signed, opts)
+}
+
+// EncryptSignedCorim returns the supplied serialized signed-corim (e.g., a
+// multi-signer or a two-phase signed CoRIM) encrypted according to the
+// supplied options.  The signed-corim is neither decoded nor verified.
+// nolint:gocritic
+func EncryptSignedCorim(signed []byte, opts EncryptOptions) ([]byte, error) {
+	if !isSignedCorim(signed) {
+		return nil, errors.New("expecting a COSE Sign1 or COSE Sign signed-corim")
+	}
+
+	return encrypt(signed, SignedContentType, opts)
+}
+
+// nolint:gocritic
+func encrypt(plaintext []byte, contentType string, opts EncryptOptions) ([]byte, error) {
+	keySize, err := gcmKeySize(opts.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	cek := opts.Key
+
+	if len(opts.Recipients) != 0 {
+		if len(opts.Key) != 0 {
+			return nil, errors.New("content encryption key set with recipients")
+		}
+
+		cek = make([]byte, keySize)
+		if _, err := rand.Read(cek); err != nil {
+			return nil, fmt.Errorf("generating content encryption key: %w", err)
+		}
+	} else if len(cek) != keySize {
+		return nil, fmt.Errorf("expecting %d bytes content encryption key for %s, got %d",
+			keySize, algorithmName(opts.Algorithm), len(cek))
+	}
+
+	hdr := cose.ProtectedHeader{}
+	hdr.SetAlgorithm(opts.Algorithm)
+	hdr[cose.HeaderLabelContentType] = contentType
+
+	protected, err := hdr.MarshalCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("encoding protected header: %w", err)
+	}
+
+	nonce := make([]byte, gcmNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating IV: %w", err)
+	}
+
+	unprotected := cose.UnprotectedHeader{cose.HeaderLabelIV: nonce}
+
+	context := "Encrypt0"
+	if len(opts.Recipients) != 0 {
+		context = "Encrypt"
+	} else if len(opts.KeyID) != 0 {
+		unprotected[cose.HeaderLabelKeyID] = opts.KeyID
+	}
+
+	aad, err := encStructure(context, protected)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := aead.Seal(nil, nonce, plaintext, aad)
+
+	if len(opts.Recipients) == 0 {
+		wrap, err := marshalTagged(coseEncrypt0Tag, coseEncrypt0{
+			Protected:   protected,
+			Unprotected: unprotected,
+			Ciphertext:  ciphertext,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("encrypted CoRIM marshaling failed: %w", err)
+		}
+
+		return wrap, nil
+	}
+
+	recipients := make([]coseRecipient, 0, len(opts.Recipients))
+
+	for i, r := range opts.Recipients {
+		rcpt, err := wrapKey(cek, r)
+		if err != nil {
+			return nil, fmt.Errorf("recipient %d: %w", i, err)
+		}
+
+		recipients = append(recipients, *rcpt)
+	}
+
+	wrap, err := marshalTagged(coseEncryptTag, coseEncrypt{
+		Protected:   protected,
+		Unprotected: unprotected,
+		Ciphertext:  ciphertext,
+		Recipients:  recipients,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encrypted CoRIM marshaling failed: %w", err)
+	}
+
+	return wrap, nil
+}
+
+// wrapKey returns the COSE_recipient carrying the supplied content encryption
+// key wrapped for the supplied recipient
+func wrapKey(cek []byte, r Recipient) (*coseRecipient, error) {
+	kekSize, err := keyWrapKeySize(r.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(r.Key) != kekSize {
+		return nil, fmt.Errorf("expecting %d bytes key encryption key for %s, got %d",
+			kekSize, algorithmName(r.Algorithm), len(r.Key))
+	}
+
+	wrapped, err := aesKeyWrap(r.Key, cek)
+	if err != nil {
+		return nil, err
+	}
+
+	unprotected := cose.UnprotectedHeader{cose.HeaderLabelAlgorithm: r.Algorithm}
+
+	if len(r.KeyID) != 0 {
+		unprotected[cose.HeaderLabelKeyID] = r.KeyID
+	}
+
+	// the protected header of a key wrap recipient must be empty
+	return &coseRecipient{
+		Protected:   cbor.RawMessage{0x40},
+		Unprotected: unprotected,
+		Ciphertext:  wrapped,
+	}, nil
+}
+
+// isEncryptedCorim returns true if the supplied data is a COSE_Encrypt0
+// (#6.16) or COSE_Encrypt (#6.96) message
+func isEncryptedCorim(data []byte) bool {
+	return bytes.HasPrefix(data, coseEncrypt0Tag) || bytes.HasPrefix(data, coseEncryptTag)
+}
+
+// Decrypt decrypts the supplied COSE_Encrypt0 or COSE_Encrypt encrypted
+// CoRIM, looking up the keys with the supplied DecryptionKeyResolver, and
+// returns its plaintext and content type: ContentType for an unsigned-corim,
+// or SignedContentType for a signed-corim.  Encrypted CoRIMs can also be
+// decrypted and decoded in one go, honouring their profile, with
+// UnmarshalUnsignedCorimFromCBORWithKeys or
+// UnmarshalSignedCorimFromCBORWithKeys, according to their content type; the
+// variants without keys reject them.
+func Decrypt(buf []byte, keys DecryptionKeyResolver) ([]byte, string, error) {
+	if keys == nil {
+		return nil, "", ErrNoDecryptionKey
+	}
+
+	var (
+		protected   cbor.RawMessage
+		unprotected cose.UnprotectedHeader
+		ciphertext  []byte
+		recipients  []coseRecipient
+		context     string
+	)
+
+	switch {
+	case bytes.HasPrefix(buf, coseEncrypt0Tag):
+		var m coseEncrypt0
+		if err := dm.Unmarshal(buf[len(coseEncrypt0Tag):], &m); err != nil {
+			return nil, "", fmt.Errorf("failed CBOR decoding for COSE-Encrypt0 CoRIM: %w", err)
+		}
+
+		protected, unprotected, ciphertext, context = m.Protected, m.Unprotected, m.Ciphertext, "Encrypt0"
+	case bytes.HasPrefix(buf, coseEncryptTag):
+		var m coseEncrypt
+		if err := dm.Unmarshal(buf[len(coseEncryptTag):], &m); err != nil {
+			return nil, "", fmt.Errorf("failed CBOR decoding for COSE-Encrypt CoRIM: %w", err)
+		}
+
+		if len(m.Recipients) == 0 {
+			return nil, "", errors.New("no recipient found")
+		}
+
+		protected, unprotected, ciphertext, context = m.Protected, m.Unprotected, m.Ciphertext, "Encrypt"
+		recipients = m.Recipients
+	default:
+		return nil, "", errors.New("expecting a COSE Encrypt0 or COSE Encrypt message")
+	}
+
+	var hdr cose.ProtectedHeader
+	if err := hdr.UnmarshalCBOR(protected); err != nil {
+		return nil, "", fmt.Errorf("decoding protected header: %w", err)
+	}
+
+	alg, err := hdr.Algorithm()
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to get content encryption algorithm: %w", err)
+	}
+
+	keySize, err := gcmKeySize(alg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	contentType, ok := hdr[cose.HeaderLabelContentType].(string)
+	if !ok {
+		return nil, "", errors.New("missing mandatory content type")
+	}
+
+	if contentType != ContentType && contentType != SignedContentType {
+		return nil, "", fmt.Errorf("unexpected content type %q", contentType)
+	}
+
+	var cek []byte
+
+	if recipients != nil {
+		if cek, err = unwrapKey(recipients, keys); err != nil {
+			return nil, "", err
+		}
+	} else {
+		kid, err := decodeKeyIDHdr(cose.Headers{Protected: hdr, Unprotected: unprotected})
+		if err != nil {
+			return nil, "", err
+		}
+
+		if cek, err = keys.ResolveDecryptionKey(kid, alg); err != nil {
+			return nil, "", fmt.Errorf("resolving decryption key: %w", err)
+		}
+
+		if cek == nil {
+			return nil, "", ErrNoDecryptionKey
+		}
+	}
+
+	if len(cek) != keySize {
+		return nil, "", fmt.Errorf("expecting %d bytes content encryption key for %s, got %d",
+			keySize, algorithmName(alg), len(cek))
+	}
+
+	nonce, ok := unprotected[cose.HeaderLabelIV].([]byte)
+	if !ok || len(nonce) != gcmNonceSize {
+		return nil, "", errors.New("missing or invalid IV")
+	}
+
+	aad, err := encStructure(context, protected)
+	if err != nil {
+		return nil, "", err
+	}
+
+	aead, err := newGCM(cek)
+	if err != nil {
+		return nil, "", err
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, "", errors.New("decryption failed")
+	}
+
+	return plaintext, contentType, nil
+}
+
+// unwrapKey returns the content encryption key unwrapped from the first of the
+// supplied recipients whose key encryption key is found
+func unwrapKey(recipients []coseRecipient, keys DecryptionKeyResolver) ([]byte, error) {
+	var errs []error
+
+	for i, r := range recipients {
+		hdr := cose.ProtectedHeader(r.Unprotected)
+
+		kwAlg, err := hdr.Algorithm()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recipient %d: unable to get key wrap algorithm: %w", i, err))
+			continue
+		}
+
+		if _, err := keyWrapKeySize(kwAlg); err != nil {
+			errs = append(errs, fmt.Errorf("recipient %d: %w", i, err))
+			continue
+		}
+
+		kid, err := decodeKeyIDHdr(cose.Headers{Unprotected: r.Unprotected})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recipient %d: %w", i, err))
+			continue
+		}
+
+		kek, err := keys.ResolveDecryptionKey(kid, kwAlg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recipient %d: resolving key encryption key: %w", i, err))
+			continue
+		}
+
+		if kek == nil {
+			continue
+		}
+
+		cek, err := aesKeyUnwrap(kek, r.Ciphertext)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recipient %d: %w", i, err))
+			continue
+		}
+
+		return cek, nil
+	}
+
+	if len(errs) == 0 {
+		return nil, ErrNoDecryptionKey
+	}
+
+	return nil, fmt.Errorf("%w: %w", ErrNoDecryptionKey, errors.Join(errs...))
+}
+
+// encStructure returns the encoded Enc_structure, i.e., the additional
+// authenticated data, for the supplied context and encoded protected header
+func encStructure(context string, protected cbor.RawMessage) ([]byte, error) {
+	aad, err := em.Marshal([]any{context, protected, NoExternalData})
+	if err != nil {
+		return nil, fmt.Errorf("encoding Enc_structure: %w", err)
+	}
+
+	return aad, nil
+}
+
+// marshalTagged returns the supplied COSE structure encoded with the supplied
+// (encoded) CBOR tag
+func marshalTagged(tag []byte, v any) ([]byte, error) {
+	data, err := em.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte{}, tag...), data...), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func gcmKeySize(alg cose.Algorithm) (int, error) {
+	switch alg {
+	case AlgorithmA128GCM:
+		return 16, nil
+	case AlgorithmA192GCM:
+		return 24, nil
+	case AlgorithmA256GCM:
+		return 32, nil
+	default:
+		return 0, fmt.Errorf("unsupported content encryption algorithm %s", algorithmName(alg))
+	}
+}
+
+func keyWrapKeySize(alg cose.Algorithm) (int, error) {
+	switch alg {
+	case AlgorithmA128KW:
+		return 16, nil
+	case AlgorithmA192KW:
+		return 24, nil
+	case AlgorithmA256KW:
+		return 32, nil
+	default:
+		return 0, fmt.Errorf("unsupported key wrap algorithm %s", algorithmName(alg))
+	}
+}
+
+// algorithmName returns the name of the supplied algorithm, including the
+// encryption and MAC algorithms that go-cose does not know about
+func algorithmName(alg cose.Algorithm) string {
+	switch alg {
+	case AlgorithmHMAC256:
+		return "HMAC256"
+	case AlgorithmHMAC384:
+		return "HMAC384"
+	case AlgorithmHMAC512:
+		return "HMAC512"
+	case AlgorithmA128GCM:
+		return "A128GCM"
+	case AlgorithmA192GCM:
+		return "A192GCM"
+	case AlgorithmA256GCM:
+		return "A256GCM"
+	case AlgorithmA128KW:
+		return "A128KW"
+	case AlgorithmA192KW:
+		return "A192KW"
+	case AlgorithmA256KW:
+		return "A256KW"
+	default:
+		return alg.String()
+	}
+}
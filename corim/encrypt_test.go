@@ -0,0 +1,295 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/eat"
+	cose "github.com/veraison/go-cose"
+)
+
+var (
+	testCEK128 = []byte("0123456789abcdef")
+	testKEK128 = []byte("fedcba9876543210")
+	testKEK256 = []byte("0123456789abcdef0123456789abcdef")
+)
+
+func TestUnsignedCorim_Encrypt_Decrypt(t *testing.T) {
+	UnsignedCorimIn := unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	cbor, err := UnsignedCorimIn.Encrypt(EncryptOptions{
+		Algorithm: AlgorithmA128GCM,
+		Key:       testCEK128,
+		KeyID:     []byte("cek"),
+	})
+	require.NoError(t, err)
+	assert.True(t, isEncryptedCorim(cbor))
+
+	plaintext, contentType, err := Decrypt(cbor, SymmetricKeys{"cek": testCEK128})
+	require.NoError(t, err)
+	assert.Equal(t, ContentType, contentType)
+
+	UnsignedCorimOut, err := UnmarshalUnsignedCorimFromCBOR(plaintext)
+	require.NoError(t, err)
+	assert.Equal(t, UnsignedCorimIn.GetID(), UnsignedCorimOut.GetID())
+
+	UnsignedCorimOut, err = UnmarshalUnsignedCorimFromCBORWithKeys(cbor, SymmetricKeys{"cek": testCEK128})
+	require.NoError(t, err)
+	assert.Equal(t, UnsignedCorimIn.GetID(), UnsignedCorimOut.GetID())
+
+	// plaintext is accepted too
+	UnsignedCorimOut, err = UnmarshalUnsignedCorimFromCBORWithKeys(plaintext, nil)
+	require.NoError(t, err)
+	assert.Equal(t, UnsignedCorimIn.GetID(), UnsignedCorimOut.GetID())
+
+	// the variant without keys points at the one with keys
+	_, err = UnmarshalUnsignedCorimFromCBOR(cbor)
+	assert.ErrorIs(t, err, ErrNoDecryptionKey)
+	assert.EqualError(t, err,
+		"no decryption key found: encrypted CoRIM, use UnmarshalUnsignedCorimFromCBORWithKeys")
+
+	_, err = UnmarshalUnsignedCorimFromCBORWithKeys(cbor, nil)
+	assert.ErrorIs(t, err, ErrNoDecryptionKey)
+
+	// an encrypted unsigned-corim is not a signed-corim
+	_, err = UnmarshalSignedCorimFromCBORWithKeys(cbor, SymmetricKeys{"cek": testCEK128})
+	assert.EqualError(t, err,
+		`expecting encrypted content type "application/rim+cose", got "application/rim+cbor" instead`)
+
+	// the key is looked up by kid
+	_, _, err = Decrypt(cbor, SymmetricKeys{"": testCEK128})
+	assert.ErrorIs(t, err, ErrNoDecryptionKey)
+
+	_, _, err = Decrypt(cbor, SymmetricKeys{"cek": testKEK128})
+	assert.EqualError(t, err, "decryption failed")
+}
+
+func TestSignedCorim_SignAndEncrypt_recipients(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	pk, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{
+		UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR),
+		Meta:          *metaGood(t),
+	}
+
+	cbor, err := SignedCorimIn.SignAndEncrypt(signer, EncryptOptions{
+		Algorithm: AlgorithmA256GCM,
+		Recipients: []Recipient{
+			{KeyID: []byte("alice"), Algorithm: AlgorithmA128KW, Key: testKEK128},
+			{KeyID: []byte("bob"), Algorithm: AlgorithmA256KW, Key: testKEK256},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, isEncryptedCorim(cbor))
+
+	// either recipient can decrypt
+	for _, keys := range []SymmetricKeys{
+		{"alice": testKEK128},
+		{"bob": testKEK256},
+		{"carol": testKEK128, "bob": testKEK256},
+	} {
+		SignedCorimOut, err := UnmarshalSignedCorimFromCBORWithKeys(cbor, keys)
+		require.NoError(t, err)
+
+		assert.Equal(t, SignedCorimIn.UnsignedCorim.GetID(), SignedCorimOut.UnsignedCorim.GetID())
+		assert.Equal(t, SignedCorimIn.Meta.Signer.Name, SignedCorimOut.Meta.Signer.Name)
+		assert.NoError(t, SignedCorimOut.Verify(pk))
+	}
+
+	_, err = UnmarshalSignedCorimFromCBOR(cbor)
+	assert.ErrorIs(t, err, ErrNoDecryptionKey)
+	assert.EqualError(t, err,
+		"no decryption key found: encrypted CoRIM, use UnmarshalSignedCorimFromCBORWithKeys")
+
+	_, err = UnmarshalSignedCorimFromCBORWithKeys(cbor, SymmetricKeys{"carol": testKEK128})
+	assert.ErrorIs(t, err, ErrNoDecryptionKey)
+
+	// the wrong key encryption key for a recipient
+	_, err = UnmarshalSignedCorimFromCBORWithKeys(cbor, SymmetricKeys{"alice": []byte("0000000000000000")})
+	assert.ErrorIs(t, err, ErrNoDecryptionKey)
+	assert.ErrorContains(t, err, "recipient 0: key unwrap failed: integrity check")
+}
+
+func TestEncryptSignedCorim_multi_signer(t *testing.T) {
+	cbor, err := EncryptSignedCorim(signVendorAndOEM(t, NewMeta().SetSigner("OEM", nil)), EncryptOptions{
+		Algorithm: AlgorithmA128GCM,
+		Key:       testCEK128,
+	})
+	require.NoError(t, err)
+
+	SignedCorimOut, err := UnmarshalSignedCorimFromCBORWithKeys(cbor, SymmetricKeys{"": testCEK128})
+	require.NoError(t, err)
+	require.Len(t, SignedCorimOut.Signatures, 2)
+
+	verified, err := SignedCorimOut.VerifySigners(testMultiSignerResolver(t), SignerPolicyAll, VerifyOptions{})
+	require.NoError(t, err)
+	assert.Len(t, verified, 2)
+
+	_, err = EncryptSignedCorim(testGoodUnsignedCorimCBOR, EncryptOptions{Algorithm: AlgorithmA128GCM, Key: testCEK128})
+	assert.EqualError(t, err, "expecting a COSE Sign1 or COSE Sign signed-corim")
+}
+
+func TestUnmarshalSignedCorimFromCBORWithKeys_profile(t *testing.T) {
+	profileID, err := eat.NewProfile("http://example.com/confidential-profile")
+	require.NoError(t, err)
+
+	policy := &AlgorithmPolicy{Algorithms: []cose.Algorithm{cose.AlgorithmES256}}
+
+	require.NoError(t, RegisterProfileWithAlgorithmPolicy(profileID, nil, policy))
+	defer UnregisterProfile(profileID)
+
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+	SignedCorimIn.UnsignedCorim.Profile = profileID
+
+	cbor, err := SignedCorimIn.SignAndEncrypt(signer, EncryptOptions{Algorithm: AlgorithmA128GCM, Key: testCEK128})
+	require.NoError(t, err)
+
+	// the profile of the decrypted signed-corim applies
+	SignedCorimOut, err := UnmarshalSignedCorimFromCBORWithKeys(cbor, SymmetricKeys{"": testCEK128})
+	require.NoError(t, err)
+	assert.Equal(t, profileID, SignedCorimOut.UnsignedCorim.Profile)
+	assert.Equal(t, policy, SignedCorimOut.AlgorithmPolicy)
+}
+
+func TestUnmarshalUnsignedCorimFromCBORWithKeys_profile(t *testing.T) {
+	type corimExtensions struct {
+		Extension1 *string `cbor:"-1,keyasint,omitempty" json:"ext1,omitempty"`
+	}
+
+	profileID, err := eat.NewProfile("http://example.com/test-profile")
+	require.NoError(t, err)
+
+	require.NoError(t, RegisterProfile(profileID, extensions.NewMap().Add(ExtUnsignedCorim, &corimExtensions{})))
+	defer UnregisterProfile(profileID)
+
+	UnsignedCorimIn, err := UnmarshalUnsignedCorimFromCBOR(testUnsignedCorimWithExtensionsCBOR)
+	require.NoError(t, err)
+
+	cbor, err := UnsignedCorimIn.Encrypt(EncryptOptions{Algorithm: AlgorithmA128GCM, Key: testCEK128})
+	require.NoError(t, err)
+
+	// the profile of the decrypted unsigned-corim applies
+	UnsignedCorimOut, err := UnmarshalUnsignedCorimFromCBORWithKeys(cbor, SymmetricKeys{"": testCEK128})
+	require.NoError(t, err)
+	assert.Equal(t, profileID, UnsignedCorimOut.Profile)
+	assert.Equal(t, "foo", UnsignedCorimOut.Extensions.MustGetString("Extension1"))
+
+	// an encrypted signed-corim is not an unsigned-corim
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+
+	cbor, err = SignedCorimIn.SignAndEncrypt(signer, EncryptOptions{Algorithm: AlgorithmA128GCM, Key: testCEK128})
+	require.NoError(t, err)
+
+	_, err = UnmarshalUnsignedCorimFromCBORWithKeys(cbor, SymmetricKeys{"": testCEK128})
+	assert.EqualError(t, err,
+		`expecting encrypted content type "application/rim+cbor", got "application/rim+cose" instead`)
+}
+
+func TestEncrypt_fail(t *testing.T) {
+	u := unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	tvs := []struct {
+		name string
+		opts EncryptOptions
+		err  string
+	}{
+		{
+			name: "no algorithm",
+			opts: EncryptOptions{Key: testCEK128},
+			err:  "unsupported content encryption algorithm unknown algorithm value 0",
+		},
+		{
+			name: "signature algorithm",
+			opts: EncryptOptions{Algorithm: cose.AlgorithmES256, Key: testCEK128},
+			err:  "unsupported content encryption algorithm ES256",
+		},
+		{
+			name: "key size",
+			opts: EncryptOptions{Algorithm: AlgorithmA256GCM, Key: testCEK128},
+			err:  "expecting 32 bytes content encryption key for A256GCM, got 16",
+		},
+		{
+			name: "key with recipients",
+			opts: EncryptOptions{
+				Algorithm:  AlgorithmA128GCM,
+				Key:        testCEK128,
+				Recipients: []Recipient{{Algorithm: AlgorithmA128KW, Key: testKEK128}},
+			},
+			err: "content encryption key set with recipients",
+		},
+		{
+			name: "key wrap algorithm",
+			opts: EncryptOptions{
+				Algorithm:  AlgorithmA128GCM,
+				Recipients: []Recipient{{Algorithm: AlgorithmA128GCM, Key: testKEK128}},
+			},
+			err: "recipient 0: unsupported key wrap algorithm A128GCM",
+		},
+		{
+			name: "key encryption key size",
+			opts: EncryptOptions{
+				Algorithm:  AlgorithmA128GCM,
+				Recipients: []Recipient{{Algorithm: AlgorithmA256KW, Key: testKEK128}},
+			},
+			err: "recipient 0: expecting 32 bytes key encryption key for A256KW, got 16",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.name, func(t *testing.T) {
+			_, err := u.Encrypt(tv.opts)
+			assert.EqualError(t, err, tv.err)
+		})
+	}
+}
+
+func TestDecrypt_fail(t *testing.T) {
+	u := unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	cbor, err := u.Encrypt(EncryptOptions{Algorithm: AlgorithmA128GCM, Key: testCEK128})
+	require.NoError(t, err)
+
+	keys := SymmetricKeys{"": testCEK128}
+
+	_, _, err = Decrypt(cbor, nil)
+	assert.ErrorIs(t, err, ErrNoDecryptionKey)
+
+	// a tampered ciphertext
+	tampered := append([]byte{}, cbor...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	_, _, err = Decrypt(tampered, keys)
+	assert.EqualError(t, err, "decryption failed")
+
+	_, _, err = Decrypt(testGoodSignedCorimCBOR, keys)
+	assert.EqualError(t, err, "expecting a COSE Encrypt0 or COSE Encrypt message")
+
+	_, _, err = Decrypt(append([]byte{}, coseEncrypt0Tag...), keys)
+	assert.ErrorContains(t, err, "failed CBOR decoding for COSE-Encrypt0 CoRIM")
+
+	// a COSE_Encrypt message with no recipients
+	noRecipients, err := em.Marshal(coseEncrypt{
+		Protected:   []byte{0x40},
+		Unprotected: cose.UnprotectedHeader{},
+		Recipients:  []coseRecipient{},
+	})
+	require.NoError(t, err)
+
+	_, _, err = Decrypt(append(append([]byte{}, coseEncryptTag...), noRecipients...), keys)
+	assert.EqualError(t, err, "no recipient found")
+}
@@ -0,0 +1,512 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1" // nolint:gosec // hmacWithSHA1 is the PBKDF2 default PRF
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"reflect"
+
+	cose "github.com/veraison/go-cose"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// KeyOptions controls the loading of keys by NewSignerFromPEM and
+// NewSignerFromDER
+type KeyOptions struct {
+	// Password is used to decrypt an encrypted PKCS#8 private key
+	Password []byte
+	// Algorithm, if set, overrides the COSE algorithm that is otherwise
+	// chosen from the key type: ES256, ES384 or ES512 according to the curve
+	// of an ECDSA key, EdDSA for an Ed25519 key, and PS256 for an RSA key.  It
+	// must be compatible with the key: RSA keys may use either RSA PSS
+	// (PS256, PS384 or PS512) or RSASSA-PKCS1-v1_5 (AlgorithmRS256,
+	// AlgorithmRS384 or AlgorithmRS512).
+	Algorithm cose.Algorithm
+}
+
+// COSE RSASSA-PKCS1-v1_5 algorithms (RFC 8812, Section 2), which go-cose does
+// not implement.  Signers and verifiers for them are provided by this package.
+const (
+	AlgorithmRS256 cose.Algorithm = -257
+	AlgorithmRS384 cose.Algorithm = -258
+	AlgorithmRS512 cose.Algorithm = -259
+)
+
+// rsaPKCS1v15Hash returns the hash function of the supplied RSASSA-PKCS1-v1_5
+// algorithm, or false if alg is not one of them
+func rsaPKCS1v15Hash(alg cose.Algorithm) (crypto.Hash, bool) {
+	switch alg {
+	case AlgorithmRS256:
+		return crypto.SHA256, true
+	case AlgorithmRS384:
+		return crypto.SHA384, true
+	case AlgorithmRS512:
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+// signatureAlgorithmName is like algorithmName, but also names the
+// RSASSA-PKCS1-v1_5 algorithms
+func signatureAlgorithmName(alg cose.Algorithm) string {
+	switch alg {
+	case AlgorithmRS256:
+		return "RS256"
+	case AlgorithmRS384:
+		return "RS384"
+	case AlgorithmRS512:
+		return "RS512"
+	default:
+		return algorithmName(alg)
+	}
+}
+
+// rsaPKCS1v15Signer is a RSASSA-PKCS1-v1_5 cose.Signer
+type rsaPKCS1v15Signer struct {
+	alg  cose.Algorithm
+	hash crypto.Hash
+	key  crypto.Signer
+}
+
+func (o rsaPKCS1v15Signer) Algorithm() cose.Algorithm {
+	return o.alg
+}
+
+func (o rsaPKCS1v15Signer) Sign(rand io.Reader, content []byte) ([]byte, error) {
+	h := o.hash.New()
+	h.Write(content)
+
+	// with crypto.Hash as the options, an RSA key signs with PKCS #1 v1.5
+	return o.key.Sign(rand, h.Sum(nil), o.hash)
+}
+
+// rsaPKCS1v15Verifier is a RSASSA-PKCS1-v1_5 cose.Verifier
+type rsaPKCS1v15Verifier struct {
+	alg  cose.Algorithm
+	hash crypto.Hash
+	key  *rsa.PublicKey
+}
+
+func (o rsaPKCS1v15Verifier) Algorithm() cose.Algorithm {
+	return o.alg
+}
+
+func (o rsaPKCS1v15Verifier) Verify(content, signature []byte) error {
+	h := o.hash.New()
+	h.Write(content)
+
+	if err := rsa.VerifyPKCS1v15(o.key, o.hash, h.Sum(nil), signature); err != nil {
+		return cose.ErrVerification
+	}
+
+	return nil
+}
+
+// checkRSAKeySize enforces the minimum RSA key size of RFC 8230, Section 6.1,
+// as go-cose does for RSA PSS
+func checkRSAKeySize(key *rsa.PublicKey) error {
+	if key.N.BitLen() < 2048 {
+		return errors.New("RSA key must be at least 2048 bits long")
+	}
+
+	return nil
+}
+
+// newSigner is like cose.NewSigner, but also supports the RSASSA-PKCS1-v1_5
+// algorithms
+func newSigner(alg cose.Algorithm, key crypto.Signer) (cose.Signer, error) {
+	hash, ok := rsaPKCS1v15Hash(alg)
+	if !ok {
+		return cose.NewSigner(alg, key)
+	}
+
+	pk, ok := key.Public().(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", signatureAlgorithmName(alg), cose.ErrInvalidPrivKey)
+	}
+
+	if err := checkRSAKeySize(pk); err != nil {
+		return nil, err
+	}
+
+	return rsaPKCS1v15Signer{alg: alg, hash: hash, key: key}, nil
+}
+
+// newVerifier is like cose.NewVerifier, but also supports the
+// RSASSA-PKCS1-v1_5 algorithms
+func newVerifier(alg cose.Algorithm, key crypto.PublicKey) (cose.Verifier, error) {
+	hash, ok := rsaPKCS1v15Hash(alg)
+	if !ok {
+		return cose.NewVerifier(alg, key)
+	}
+
+	pk, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", signatureAlgorithmName(alg), cose.ErrInvalidPubKey)
+	}
+
+	if err := checkRSAKeySize(pk); err != nil {
+		return nil, err
+	}
+
+	return rsaPKCS1v15Verifier{alg: alg, hash: hash, key: pk}, nil
+}
+
+// NewSignerFromPEM returns a cose.Signer for the private key in the first PEM
+// block of the supplied data.  The block may be of type "PRIVATE KEY"
+// (PKCS#8), "ENCRYPTED PRIVATE KEY" (PKCS#8 encrypted with PBES2, which
+// requires a Password), "EC PRIVATE KEY" (SEC 1) or "RSA PRIVATE KEY"
+// (PKCS#1).  Legacy PEM encryption (RFC 1423) is not supported.
+func NewSignerFromPEM(data []byte, opts KeyOptions) (cose.Signer, error) {
+	block, err := decodePEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := parsePrivateKeyPEM(block, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSignerFromKey(key, opts.Algorithm)
+}
+
+// NewSignerFromDER returns a cose.Signer for the supplied DER encoded private
+// key, which may be in PKCS#8, SEC 1 or PKCS#1 format
+func NewSignerFromDER(der []byte, opts KeyOptions) (cose.Signer, error) {
+	key, err := parsePrivateKeyDER(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSignerFromKey(key, opts.Algorithm)
+}
+
+// NewPublicKeyFromPEM returns the public key in the first PEM block of the
+// supplied data.  The block may be of type "PUBLIC KEY" (PKIX), "RSA PUBLIC
+// KEY" (PKCS#1) or "CERTIFICATE" (X.509), or contain an unencrypted private
+// key, as for NewSignerFromPEM.
+func NewPublicKeyFromPEM(data []byte) (crypto.PublicKey, error) {
+	block, err := decodePEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var key crypto.PublicKey
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		var priv crypto.Signer
+		if priv, err = parsePrivateKeyPEM(block, nil); err == nil {
+			key = priv.Public()
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := algForPublicKey(key, 0); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func decodePEM(data []byte) (*pem.Block, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	return block, nil
+}
+
+func parsePrivateKeyPEM(block *pem.Block, password []byte) (crypto.Signer, error) {
+	if _, ok := block.Headers["DEK-Info"]; ok {
+		return nil, errors.New("legacy PEM encryption is not supported, use encrypted PKCS#8")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		return parsePKCS8PrivateKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		if len(password) == 0 {
+			return nil, errors.New("encrypted private key requires a password")
+		}
+
+		der, err := decryptPKCS8(block.Bytes, password)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting private key: %w", err)
+		}
+
+		return parsePKCS8PrivateKey(der)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
+
+func parsePrivateKeyDER(der []byte) (crypto.Signer, error) {
+	if key, err := parsePKCS8PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("unable to parse private key as PKCS#8, SEC 1 or PKCS#1")
+}
+
+func parsePKCS8PrivateKey(der []byte) (crypto.Signer, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unknown private key type %v", reflect.TypeOf(key))
+	}
+
+	return signer, nil
+}
+
+func newSignerFromKey(key crypto.Signer, override cose.Algorithm) (cose.Signer, error) {
+	alg, err := algForPublicKey(key.Public(), override)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSigner(alg, key)
+}
+
+// algForPublicKey returns the COSE algorithm for the supplied public key: the
+// override, if set and compatible with the key, or the default for the key
+// type
+func algForPublicKey(key crypto.PublicKey, override cose.Algorithm) (cose.Algorithm, error) {
+	var algs []cose.Algorithm
+
+	switch v := key.(type) {
+	case *ecdsa.PublicKey:
+		alg := ellipticCurveToAlg(v.Curve)
+		if alg == noAlg {
+			return noAlg, fmt.Errorf("unknown elliptic curve %v", v.Curve.Params().Name)
+		}
+		algs = []cose.Algorithm{alg}
+	case ed25519.PublicKey:
+		algs = []cose.Algorithm{cose.AlgorithmEd25519}
+	case *rsa.PublicKey:
+		algs = []cose.Algorithm{
+			cose.AlgorithmPS256, cose.AlgorithmPS384, cose.AlgorithmPS512,
+			AlgorithmRS256, AlgorithmRS384, AlgorithmRS512,
+		}
+	default:
+		return noAlg, fmt.Errorf("unknown public key type %v", reflect.TypeOf(key))
+	}
+
+	if override == 0 {
+		return algs[0], nil
+	}
+
+	for _, alg := range algs {
+		if alg == override {
+			return alg, nil
+		}
+	}
+
+	return noAlg, fmt.Errorf("algorithm %s is not compatible with %v key",
+		signatureAlgorithmName(override), reflect.TypeOf(key))
+}
+
+var (
+	oidPBES2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// encryptedPrivateKeyInfo is the PKCS#8 EncryptedPrivateKeyInfo (RFC 5958)
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pbes2Params is the PBES2-params (RFC 8018)
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params is the PBKDF2-params (RFC 8018)
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// decryptPKCS8 decrypts a PKCS#8 EncryptedPrivateKeyInfo that uses PBES2 with
+// PBKDF2 and AES-CBC, and returns the DER encoded PrivateKeyInfo
+func decryptPKCS8(der, password []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if err := unmarshalASN1(der, &info); err != nil {
+		return nil, fmt.Errorf("parsing EncryptedPrivateKeyInfo: %w", err)
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported encryption algorithm %s", info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if err := unmarshalASN1(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("parsing PBES2 parameters: %w", err)
+	}
+
+	keyLen, err := aesCBCKeyLen(params.EncryptionScheme.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := pbkdf2Key(params.KeyDerivationFunc, password, keyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	var iv []byte
+	if err := unmarshalASN1(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("parsing IV: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data := info.EncryptedData
+
+	if len(iv) != block.BlockSize() || len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, errors.New("invalid IV or encrypted data length")
+	}
+
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	return unpadPKCS7(plain, block.BlockSize())
+}
+
+func unmarshalASN1(der []byte, v any) error {
+	rest, err := asn1.Unmarshal(der, v)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) != 0 {
+		return errors.New("trailing data")
+	}
+
+	return nil
+}
+
+func aesCBCKeyLen(oid asn1.ObjectIdentifier) (int, error) {
+	switch {
+	case oid.Equal(oidAES128CBC):
+		return 16, nil
+	case oid.Equal(oidAES192CBC):
+		return 24, nil
+	case oid.Equal(oidAES256CBC):
+		return 32, nil
+	default:
+		return 0, fmt.Errorf("unsupported encryption scheme %s", oid)
+	}
+}
+
+func pbkdf2Key(kdf pkix.AlgorithmIdentifier, password []byte, keyLen int) ([]byte, error) {
+	if !kdf.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation function %s", kdf.Algorithm)
+	}
+
+	var params pbkdf2Params
+	if err := unmarshalASN1(kdf.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("parsing PBKDF2 parameters: %w", err)
+	}
+
+	if params.KeyLength != 0 && params.KeyLength != keyLen {
+		return nil, fmt.Errorf("PBKDF2 key length %d does not match cipher", params.KeyLength)
+	}
+
+	var h func() hash.Hash
+
+	switch prf := params.PRF.Algorithm; {
+	case len(prf) == 0, prf.Equal(oidHMACSHA1):
+		h = sha1.New
+	case prf.Equal(oidHMACSHA256):
+		h = sha256.New
+	case prf.Equal(oidHMACSHA384):
+		h = sha512.New384
+	case prf.Equal(oidHMACSHA512):
+		h = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 PRF %s", prf)
+	}
+
+	return pbkdf2.Key(password, params.Salt, params.IterationCount, keyLen, h), nil
+}
+
+func unpadPKCS7(data []byte, blockSize int) ([]byte, error) {
+	// a bad padding is the likely outcome of a wrong password
+	errBadPadding := errors.New("bad padding (wrong password?)")
+
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize {
+		return nil, errBadPadding
+	}
+
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, errBadPadding
+		}
+	}
+
+	return data[:len(data)-n], nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"embed"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cose "github.com/veraison/go-cose"
+)
+
+// see testcases/keys/regen.sh
+//
+//go:embed testcases/keys/*.pem
+var testKeys embed.FS
+
+var testKeyPassword = []byte("corim")
+
+func readTestKey(t *testing.T, name string) []byte {
+	data, err := testKeys.ReadFile("testcases/keys/" + name)
+	require.NoError(t, err)
+
+	return data
+}
+
+func signAndVerifyWithKeys(t *testing.T, signer cose.Signer, pubPEM []byte) {
+	pk, err := NewPublicKeyFromPEM(pubPEM)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+
+	cbor, err := SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	var SignedCorimOut SignedCorim
+
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+	assert.NoError(t, SignedCorimOut.Verify(pk))
+}
+
+func TestNewSignerFromPEM_ok(t *testing.T) {
+	tvs := []struct {
+		desc     string
+		key      string
+		password []byte
+		pub      string
+		alg      cose.Algorithm
+	}{
+		{"SEC 1 EC key", "ec-p256-sec1.pem", nil, "ec-p256-pub.pem", cose.AlgorithmES256},
+		{"PKCS#8 EC key", "ec-p256-pkcs8.pem", nil, "ec-p256-cert.pem", cose.AlgorithmES256},
+		{"encrypted PKCS#8 EC key", "ec-p256-pkcs8-enc.pem", testKeyPassword, "ec-p256-pub.pem", cose.AlgorithmES256},
+		{"PKCS#1 RSA key", "rsa-2048-pkcs1.pem", nil, "rsa-2048-pub.pem", cose.AlgorithmPS256},
+		{"PKCS#8 Ed25519 key", "ed25519-pkcs8.pem", nil, "ed25519-pkcs8.pem", cose.AlgorithmEd25519},
+		{"encrypted PKCS#8 Ed25519 key", "ed25519-pkcs8-enc.pem", testKeyPassword, "ed25519-pkcs8.pem", cose.AlgorithmEd25519},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.desc, func(t *testing.T) {
+			signer, err := NewSignerFromPEM(readTestKey(t, tv.key), KeyOptions{Password: tv.password})
+			require.NoError(t, err)
+			assert.Equal(t, tv.alg, signer.Algorithm())
+
+			signAndVerifyWithKeys(t, signer, readTestKey(t, tv.pub))
+		})
+	}
+}
+
+func TestNewSignerFromPEM_algorithm_override(t *testing.T) {
+	signer, err := NewSignerFromPEM(readTestKey(t, "rsa-2048-pkcs1.pem"), KeyOptions{Algorithm: cose.AlgorithmPS384})
+	require.NoError(t, err)
+	assert.Equal(t, cose.AlgorithmPS384, signer.Algorithm())
+
+	signAndVerifyWithKeys(t, signer, readTestKey(t, "rsa-2048-pub-pkcs1.pem"))
+
+	_, err = NewSignerFromPEM(readTestKey(t, "ec-p256-sec1.pem"), KeyOptions{Algorithm: cose.AlgorithmES384})
+	assert.EqualError(t, err, "algorithm ES384 is not compatible with *ecdsa.PublicKey key")
+
+	_, err = NewSignerFromPEM(readTestKey(t, "ed25519-pkcs8.pem"), KeyOptions{Algorithm: cose.AlgorithmPS256})
+	assert.EqualError(t, err, "algorithm PS256 is not compatible with ed25519.PublicKey key")
+
+	_, err = NewSignerFromPEM(readTestKey(t, "ec-p256-sec1.pem"), KeyOptions{Algorithm: AlgorithmRS256})
+	assert.EqualError(t, err, "algorithm RS256 is not compatible with *ecdsa.PublicKey key")
+}
+
+func TestNewSignerFromPEM_RSASSA_PKCS1_v1_5(t *testing.T) {
+	pk, err := NewPublicKeyFromPEM(readTestKey(t, "rsa-2048-pub.pem"))
+	require.NoError(t, err)
+
+	tvs := []struct {
+		alg  cose.Algorithm
+		hash crypto.Hash
+	}{
+		{AlgorithmRS256, crypto.SHA256},
+		{AlgorithmRS384, crypto.SHA384},
+		{AlgorithmRS512, crypto.SHA512},
+	}
+
+	for _, tv := range tvs {
+		t.Run(signatureAlgorithmName(tv.alg), func(t *testing.T) {
+			signer, err := NewSignerFromPEM(readTestKey(t, "rsa-2048-pkcs1.pem"), KeyOptions{Algorithm: tv.alg})
+			require.NoError(t, err)
+			assert.Equal(t, tv.alg, signer.Algorithm())
+
+			// the signature is a plain PKCS #1 v1.5 one
+			content := []byte("content")
+
+			sig, err := signer.Sign(rand.Reader, content)
+			require.NoError(t, err)
+
+			h := tv.hash.New()
+			h.Write(content)
+			assert.NoError(t, rsa.VerifyPKCS1v15(pk.(*rsa.PublicKey), tv.hash, h.Sum(nil), sig))
+
+			signAndVerifyWithKeys(t, signer, readTestKey(t, "rsa-2048-pub.pem"))
+		})
+	}
+
+	signer, err := NewSignerFromPEM(readTestKey(t, "rsa-2048-pkcs1.pem"), KeyOptions{Algorithm: AlgorithmRS256})
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+
+	cbor, err := SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	var SignedCorimOut SignedCorim
+
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+
+	// wrong key type and wrong key
+	ecKey, err := NewPublicKeyFromPEM(readTestKey(t, "ec-p256-pub.pem"))
+	require.NoError(t, err)
+	assert.EqualError(t, SignedCorimOut.Verify(ecKey), "unable to instantiate verifier: RS256: invalid public key")
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	assert.ErrorIs(t, SignedCorimOut.Verify(&otherKey.PublicKey), cose.ErrVerification)
+
+	// the algorithm policy applies
+	SignedCorimOut.AlgorithmPolicy = &AlgorithmPolicy{Algorithms: []cose.Algorithm{cose.AlgorithmPS256}}
+	assert.EqualError(t, SignedCorimOut.Verify(pk), "rejected by algorithm policy: algorithm RS256 is not allowed")
+
+	// countersignatures may use RSASSA-PKCS1-v1_5 too
+	SignedCorimOut.AlgorithmPolicy = nil
+
+	cbor, err = SignedCorimOut.AddCountersignature(signer, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+
+	_, err = SignedCorimOut.VerifyCountersignatures(staticKeyResolver{pk}, SignerPolicyAll, VerifyOptions{})
+	assert.NoError(t, err)
+}
+
+func TestNewSignerFromPEM_fail(t *testing.T) {
+	_, err := NewSignerFromPEM([]byte("not a PEM"), KeyOptions{})
+	assert.EqualError(t, err, "no PEM block found")
+
+	_, err = NewSignerFromPEM(readTestKey(t, "ec-p256-pkcs8-enc.pem"), KeyOptions{})
+	assert.EqualError(t, err, "encrypted private key requires a password")
+
+	_, err = NewSignerFromPEM(readTestKey(t, "ec-p256-pkcs8-enc.pem"), KeyOptions{Password: []byte("wrong")})
+	assert.ErrorContains(t, err, "decrypting private key")
+
+	_, err = NewSignerFromPEM(readTestKey(t, "ec-p256-sec1-legacy-enc.pem"), KeyOptions{Password: testKeyPassword})
+	assert.EqualError(t, err, "legacy PEM encryption is not supported, use encrypted PKCS#8")
+
+	_, err = NewSignerFromPEM(readTestKey(t, "ec-p256-pub.pem"), KeyOptions{})
+	assert.EqualError(t, err, `unsupported PEM block type "PUBLIC KEY"`)
+}
+
+func TestNewSignerFromDER(t *testing.T) {
+	for _, name := range []string{"ec-p256-sec1.pem", "ec-p256-pkcs8.pem", "rsa-2048-pkcs1.pem", "ed25519-pkcs8.pem"} {
+		block, _ := pem.Decode(readTestKey(t, name))
+		require.NotNil(t, block)
+
+		_, err := NewSignerFromDER(block.Bytes, KeyOptions{})
+		assert.NoError(t, err, name)
+	}
+
+	_, err := NewSignerFromDER([]byte{0x30, 0x00}, KeyOptions{})
+	assert.EqualError(t, err, "unable to parse private key as PKCS#8, SEC 1 or PKCS#1")
+}
+
+func TestNewPublicKeyFromPEM(t *testing.T) {
+	pk, err := NewPublicKeyFromPEM(readTestKey(t, "ec-p256-cert.pem"))
+	require.NoError(t, err)
+	assert.IsType(t, &ecdsa.PublicKey{}, pk)
+
+	pk, err = NewPublicKeyFromPEM(readTestKey(t, "rsa-2048-pub-pkcs1.pem"))
+	require.NoError(t, err)
+	assert.IsType(t, &rsa.PublicKey{}, pk)
+
+	pk, err = NewPublicKeyFromPEM(readTestKey(t, "ed25519-pkcs8.pem"))
+	require.NoError(t, err)
+	assert.IsType(t, ed25519.PublicKey{}, pk)
+
+	_, err = NewPublicKeyFromPEM(readTestKey(t, "ec-p256-pkcs8-enc.pem"))
+	assert.EqualError(t, err, "encrypted private key requires a password")
+}
@@ -0,0 +1,156 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// ErrNoKey is returned by VerifyWithResolver when the KeyResolver finds no
+// candidate key for the signed-corim
+var ErrNoKey = errors.New("no verification key found")
+
+// KeyResolver maps the key identifier (kid header) and the signer from the
+// corim-meta-map of a signed-corim to the candidate verification keys.  kid is
+// nil if the signed-corim has no kid header.  An empty slice (and no error)
+// should be returned if no key is known.
+type KeyResolver interface {
+	ResolveKeys(kid []byte, signer Signer) ([]crypto.PublicKey, error)
+}
+
+// VerifyWithResolver verifies the signature of the target SignedCorim object
+// using the keys returned by the supplied KeyResolver, which are tried in
+// order, and then checks the validity periods as VerifyWithOptions does.  The
+// options' AlgorithmPolicy, if set, applies instead of the SignedCorim's.
+func (o *SignedCorim) VerifyWithResolver(r KeyResolver, opts VerifyOptions) error {
+	if r == nil {
+		return errors.New("nil key resolver")
+	}
+
+	if o.message == nil {
+		return errors.New("no Sign1 message found")
+	}
+
+	keys, err := r.ResolveKeys(o.KeyID, o.Meta.Signer)
+	if err != nil {
+		return fmt.Errorf("resolving verification keys: %w", err)
+	}
+
+	if len(keys) == 0 {
+		return ErrNoKey
+	}
+
+	policy := opts.policy(o.AlgorithmPolicy)
+
+	for _, pk := range keys {
+		if err = o.verify(pk, policy); err == nil {
+			return o.CheckValidity(opts.now(), opts.Skew)
+		}
+	}
+
+	// report the error from the last key tried
+	return err
+}
+
+// JWKSetResolver is a KeyResolver backed by a JWK set.  Keys are looked up by
+// their "kid" parameter and, if the signed-corim has no kid header (or its
+// kid is unknown), by the name or URI of the signer, as bound with
+// BindSigner.
+type JWKSetResolver struct {
+	byKID    map[string]crypto.PublicKey
+	bySigner map[string][]string
+}
+
+// NewJWKSetResolver instantiates a JWKSetResolver from the supplied JSON
+// encoded JWK set.  The keys may be either public or private, and are indexed
+// by their "kid" parameter.  Since keys can only be looked up (and bound to a
+// signer with BindSigner) by kid, a key with no (or an empty) kid is an error.
+func NewJWKSetResolver(data []byte) (*JWKSetResolver, error) {
+	set, err := jwk.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing JWK set: %w", err)
+	}
+
+	ret := &JWKSetResolver{
+		byKID:    make(map[string]crypto.PublicKey),
+		bySigner: make(map[string][]string),
+	}
+
+	for i := 0; i < set.Len(); i++ {
+		k, _ := set.Key(i)
+
+		kid := k.KeyID()
+		if kid == "" {
+			return nil, fmt.Errorf("key at index %d: missing kid", i)
+		}
+
+		j, err := json.Marshal(k)
+		if err != nil {
+			return nil, fmt.Errorf("key at index %d: %w", i, err)
+		}
+
+		pk, err := NewPublicKeyFromJWK(j)
+		if err != nil {
+			return nil, fmt.Errorf("key at index %d: %w", i, err)
+		}
+
+		if _, ok := ret.byKID[kid]; ok {
+			return nil, fmt.Errorf("key at index %d: duplicate kid %q", i, kid)
+		}
+
+		ret.byKID[kid] = pk
+	}
+
+	return ret, nil
+}
+
+// BindSigner associates the supplied signer name or URI with the keys
+// identified by the supplied kids, which must be in the set
+func (o *JWKSetResolver) BindSigner(nameOrURI string, kids ...string) error {
+	for _, kid := range kids {
+		if _, ok := o.byKID[kid]; !ok {
+			return fmt.Errorf("unknown kid %q", kid)
+		}
+	}
+
+	o.bySigner[nameOrURI] = append(o.bySigner[nameOrURI], kids...)
+
+	return nil
+}
+
+// ResolveKeys returns the key identified by kid, if any; otherwise, it returns
+// the keys bound to the name or the URI of the signer
+func (o *JWKSetResolver) ResolveKeys(kid []byte, signer Signer) ([]crypto.PublicKey, error) {
+	if kid != nil {
+		if pk, ok := o.byKID[string(kid)]; ok {
+			return []crypto.PublicKey{pk}, nil
+		}
+	}
+
+	names := []string{signer.Name}
+	if signer.URI != nil {
+		names = append(names, string(*signer.URI))
+	}
+
+	var (
+		ret  []crypto.PublicKey
+		seen = make(map[string]bool)
+	)
+
+	for _, n := range names {
+		for _, k := range o.bySigner[n] {
+			if !seen[k] {
+				seen[k] = true
+				ret = append(ret, o.byKID[k])
+			}
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto"
+	"encoding/json"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testJWKSet returns a JWK set with the public parts of the supplied keys,
+// indexed by the supplied kids
+func testJWKSet(t *testing.T, keys map[string][]byte) []byte {
+	set := jwk.NewSet()
+
+	for kid, j := range keys {
+		k, err := jwk.ParseKey(j)
+		require.NoError(t, err)
+
+		pub, err := k.PublicKey()
+		require.NoError(t, err)
+		require.NoError(t, pub.Set(jwk.KeyIDKey, kid))
+		require.NoError(t, set.AddKey(pub))
+	}
+
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	return data
+}
+
+func signWithKeyID(t *testing.T, key []byte, kid []byte, signerName string) *SignedCorim {
+	signer, err := NewSignerFromJWK(key)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{
+		UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR),
+		Meta:          *NewMeta().SetSigner(signerName, nil),
+		KeyID:         kid,
+	}
+
+	cbor, err := SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	var SignedCorimOut SignedCorim
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+
+	return &SignedCorimOut
+}
+
+func TestSignedCorim_VerifyWithResolver(t *testing.T) {
+	r, err := NewJWKSetResolver(testJWKSet(t, map[string][]byte{
+		"es256": testES256Key,
+		"es384": testES384Key,
+		"eddsa": testEdDSAKey,
+	}))
+	require.NoError(t, err)
+
+	require.NoError(t, r.BindSigner("ACME Ltd.", "es384", "eddsa"))
+	require.NoError(t, r.BindSigner("https://acme.example", "eddsa"))
+
+	// by kid
+	signed := signWithKeyID(t, testES256Key, []byte("es256"), "Unknown Ltd.")
+	assert.Equal(t, []byte("es256"), signed.KeyID)
+	assert.NoError(t, signed.VerifyWithResolver(r, VerifyOptions{}))
+
+	// by signer name, trying all the bound keys
+	signed = signWithKeyID(t, testEdDSAKey, nil, "ACME Ltd.")
+	assert.Nil(t, signed.KeyID)
+	assert.NoError(t, signed.VerifyWithResolver(r, VerifyOptions{}))
+
+	// unknown kid falls back to the signer
+	signed = signWithKeyID(t, testES384Key, []byte("unknown"), "ACME Ltd.")
+	assert.NoError(t, signed.VerifyWithResolver(r, VerifyOptions{}))
+
+	// the key bound to the signer is not the signing key
+	signed = signWithKeyID(t, testES256Key, nil, "ACME Ltd.")
+	assert.EqualError(t, signed.VerifyWithResolver(r, VerifyOptions{}),
+		"unable to instantiate verifier: ES256: invalid public key")
+
+	// no key at all
+	signed = signWithKeyID(t, testES256Key, nil, "Unknown Ltd.")
+	assert.ErrorIs(t, signed.VerifyWithResolver(r, VerifyOptions{}), ErrNoKey)
+	assert.EqualError(t, signed.VerifyWithResolver(nil, VerifyOptions{}), "nil key resolver")
+}
+
+func TestJWKSetResolver_ResolveKeys(t *testing.T) {
+	r, err := NewJWKSetResolver(testJWKSet(t, map[string][]byte{
+		"es256": testES256Key,
+		"eddsa": testEdDSAKey,
+	}))
+	require.NoError(t, err)
+
+	es256, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+	eddsa, err := NewPublicKeyFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	require.NoError(t, r.BindSigner("ACME Ltd.", "es256"))
+	require.NoError(t, r.BindSigner("https://acme.example", "eddsa", "es256"))
+	assert.EqualError(t, r.BindSigner("ACME Ltd.", "es512"), `unknown kid "es512"`)
+
+	signer := NewSigner().SetName("ACME Ltd.").SetURI("https://acme.example")
+	require.NotNil(t, signer)
+
+	keys, err := r.ResolveKeys(nil, *signer)
+	require.NoError(t, err)
+	assert.Equal(t, []crypto.PublicKey{es256, eddsa}, keys)
+
+	keys, err = r.ResolveKeys([]byte("eddsa"), Signer{Name: "ACME Ltd."})
+	require.NoError(t, err)
+	assert.Equal(t, []crypto.PublicKey{eddsa}, keys)
+
+	keys, err = r.ResolveKeys(nil, Signer{Name: "Unknown Ltd."})
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestNewJWKSetResolver_fail(t *testing.T) {
+	_, err := NewJWKSetResolver([]byte("{"))
+	assert.ErrorContains(t, err, "parsing JWK set")
+
+	dup := []byte(`{"keys": [
+		{"kty": "EC", "crv": "P-256", "kid": "k",
+		 "x": "MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4",
+		 "y": "4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"},
+		{"kty": "EC", "crv": "P-256", "kid": "k",
+		 "x": "MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4",
+		 "y": "4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"}
+	]}`)
+	_, err = NewJWKSetResolver(dup)
+	assert.EqualError(t, err, `key at index 1: duplicate kid "k"`)
+
+	noKID := []byte(`{"keys": [
+		{"kty": "EC", "crv": "P-256", "kid": "k",
+		 "x": "MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4",
+		 "y": "4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"},
+		{"kty": "EC", "crv": "P-256",
+		 "x": "MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4",
+		 "y": "4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"}
+	]}`)
+	_, err = NewJWKSetResolver(noKID)
+	assert.EqualError(t, err, "key at index 1: missing kid")
+}
+
+func TestNewPublicKeyFromJWK_public(t *testing.T) {
+	k, err := jwk.ParseKey(testES384Key)
+	require.NoError(t, err)
+
+	pub, err := k.PublicKey()
+	require.NoError(t, err)
+
+	j, err := json.Marshal(pub)
+	require.NoError(t, err)
+
+	expected, err := NewPublicKeyFromJWK(testES384Key)
+	require.NoError(t, err)
+
+	actual, err := NewPublicKeyFromJWK(j)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// keyWrapIV is the default initial value of the AES Key Wrap algorithm
+// (RFC 3394, Section 2.2.3.1)
+var keyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// aesKeyWrap wraps the supplied key with the supplied key encryption key,
+// using the AES Key Wrap algorithm (RFC 3394)
+func aesKeyWrap(kek, key []byte) ([]byte, error) {
+	if len(key) < 16 || len(key)%8 != 0 {
+		return nil, fmt.Errorf("invalid key length %d for key wrap", len(key))
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(key) / 8
+
+	out := make([]byte, 8+len(key))
+	copy(out[8:], key)
+
+	a := make([]byte, 8)
+	copy(a, keyWrapIV)
+
+	buf := make([]byte, aes.BlockSize)
+
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			r := out[i*8 : (i+1)*8]
+
+			copy(buf, a)
+			copy(buf[8:], r)
+			block.Encrypt(buf, buf)
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(buf[:8])^t)
+			copy(r, buf[8:])
+		}
+	}
+
+	copy(out, a)
+
+	return out, nil
+}
+
+// aesKeyUnwrap unwraps the supplied wrapped key with the supplied key
+// encryption key, using the AES Key Wrap algorithm (RFC 3394), and checks its
+// integrity
+func aesKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, fmt.Errorf("invalid wrapped key length %d", len(wrapped))
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(wrapped)/8 - 1
+
+	out := make([]byte, len(wrapped)-8)
+	copy(out, wrapped[8:])
+
+	a := make([]byte, 8)
+	copy(a, wrapped)
+
+	buf := make([]byte, aes.BlockSize)
+
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			r := out[(i-1)*8 : i*8]
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf, binary.BigEndian.Uint64(a)^t)
+			copy(buf[8:], r)
+			block.Decrypt(buf, buf)
+
+			copy(a, buf)
+			copy(r, buf[8:])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(a, keyWrapIV) != 1 {
+		return nil, errors.New("key unwrap failed: integrity check")
+	}
+
+	return out, nil
+}
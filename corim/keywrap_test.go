@@ -0,0 +1,84 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestAESKeyWrap_RFC3394_vectors(t *testing.T) {
+	tvs := []struct {
+		name    string
+		kek     string
+		key     string
+		wrapped string
+	}{
+		{
+			// RFC 3394, Section 4.1
+			name:    "128-bit KEK, 128-bit key",
+			kek:     "000102030405060708090a0b0c0d0e0f",
+			key:     "00112233445566778899aabbccddeeff",
+			wrapped: "1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5",
+		},
+		{
+			// RFC 3394, Section 4.3
+			name:    "256-bit KEK, 128-bit key",
+			kek:     "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			key:     "00112233445566778899aabbccddeeff",
+			wrapped: "64e8c3f9ce0f5ba263e9777905818a2a93c8191e7d6e8ae7",
+		},
+		{
+			// RFC 3394, Section 4.6
+			name:    "256-bit KEK, 256-bit key",
+			kek:     "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			key:     "00112233445566778899aabbccddeeff000102030405060708090a0b0c0d0e0f",
+			wrapped: "28c9f404c4b810f4cbccb35cfb87f8263f5786e2d80ed326cbc7f0e71a99f43bfb988b9b7a02dd21",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.name, func(t *testing.T) {
+			kek := mustDecodeHex(t, tv.kek)
+
+			wrapped, err := aesKeyWrap(kek, mustDecodeHex(t, tv.key))
+			require.NoError(t, err)
+			assert.Equal(t, tv.wrapped, hex.EncodeToString(wrapped))
+
+			key, err := aesKeyUnwrap(kek, wrapped)
+			require.NoError(t, err)
+			assert.Equal(t, tv.key, hex.EncodeToString(key))
+		})
+	}
+}
+
+func TestAESKeyWrap_fail(t *testing.T) {
+	kek := mustDecodeHex(t, "000102030405060708090a0b0c0d0e0f")
+
+	_, err := aesKeyWrap(kek, []byte("short"))
+	assert.EqualError(t, err, "invalid key length 5 for key wrap")
+
+	_, err = aesKeyWrap([]byte("bad kek"), make([]byte, 16))
+	assert.EqualError(t, err, "crypto/aes: invalid key size 7")
+
+	wrapped, err := aesKeyWrap(kek, make([]byte, 16))
+	require.NoError(t, err)
+
+	wrapped[len(wrapped)-1] ^= 0xff
+
+	_, err = aesKeyUnwrap(kek, wrapped)
+	assert.EqualError(t, err, "key unwrap failed: integrity check")
+
+	_, err = aesKeyUnwrap(kek, wrapped[:16])
+	assert.EqualError(t, err, "invalid wrapped key length 16")
+}
@@ -0,0 +1,241 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// LocalTSAPolicy is the default TSA policy of LocalTSA, under the example
+// private enterprise number (RFC 5612)
+var LocalTSAPolicy = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 32473, 1}
+
+// LocalTSA is a TimestampAuthority that issues RFC 3161 timestamp tokens
+// signed with a local key.  It stands in for a remote TSA in tests and
+// tooling.
+type LocalTSA struct {
+	// Policy is the TSA policy of the tokens.  If unset, LocalTSAPolicy is
+	// used.
+	Policy asn1.ObjectIdentifier
+	// Clock returns the current time.  If nil, time.Now is used.
+	Clock func() time.Time
+
+	key    crypto.Signer
+	certs  []*x509.Certificate
+	serial atomic.Int64
+}
+
+// NewLocalTSA instantiates a LocalTSA that signs with the supplied key, which
+// must be an ECDSA, RSA or Ed25519 key matching the supplied TSA certificate.
+// The TSA certificate and the intermediate certificates, if any, are included
+// in the tokens.
+func NewLocalTSA(key crypto.Signer, cert *x509.Certificate, intermediates ...*x509.Certificate) (*LocalTSA, error) {
+	if key == nil {
+		return nil, errors.New("nil key")
+	}
+
+	if cert == nil {
+		return nil, errors.New("nil TSA certificate")
+	}
+
+	pub, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(key.Public()) {
+		return nil, errors.New("key does not match the TSA certificate")
+	}
+
+	if _, _, err := localTSAAlgorithms(key); err != nil {
+		return nil, err
+	}
+
+	return &LocalTSA{
+		key:   key,
+		certs: append([]*x509.Certificate{cert}, intermediates...),
+	}, nil
+}
+
+// Timestamp returns a DER encoded TimeStampToken for the supplied message
+// imprint
+func (o *LocalTSA) Timestamp(hash crypto.Hash, digest []byte) ([]byte, error) {
+	hashAlg, err := hashOID(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(digest) != hash.Size() {
+		return nil, fmt.Errorf("expecting %s digest of %d bytes, got %d", hash, hash.Size(), len(digest))
+	}
+
+	now := time.Now
+	if o.Clock != nil {
+		now = o.Clock
+	}
+
+	policy := o.Policy
+	if len(policy) == 0 {
+		policy = LocalTSAPolicy
+	}
+
+	eContent, err := asn1.Marshal(tstInfo{
+		Version: 1,
+		Policy:  policy,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: hashAlg, Parameters: asn1.NullRawValue},
+			HashedMessage: digest,
+		},
+		SerialNumber: big.NewInt(o.serial.Add(1)),
+		GenTime:      now().UTC().Truncate(time.Second),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding TSTInfo: %w", err)
+	}
+
+	si, err := o.sign(eContent)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []byte
+	for _, c := range o.certs {
+		certs = append(certs, c.Raw...)
+	}
+
+	sd, err := asn1.Marshal(signedData{
+		Version:          3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{si.DigestAlgorithm},
+		EncapContentInfo: encapContentInfo{EContentType: oidTSTInfo, EContent: eContent},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certs},
+		SignerInfos:      []signerInfo{*si},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding SignedData: %w", err)
+	}
+
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd},
+	})
+}
+
+// sign returns the SignerInfo for the supplied TSTInfo
+func (o *LocalTSA) sign(eContent []byte) (*signerInfo, error) {
+	hash, sigAlg, err := localTSAAlgorithms(o.key)
+	if err != nil {
+		return nil, err
+	}
+
+	hashAlg, err := hashOID(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := localTSASignedAttrs(hashSum(hash, eContent), o.certs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: attrs})
+	if err != nil {
+		return nil, err
+	}
+
+	// Ed25519 signs the message itself, other algorithms its digest
+	tbs, opts := signed, crypto.SignerOpts(crypto.Hash(0))
+	if !sigAlg.Equal(oidEd25519) {
+		tbs, opts = hashSum(hash, signed), hash
+	}
+
+	sig, err := o.key.Sign(rand.Reader, tbs, opts)
+	if err != nil {
+		return nil, fmt.Errorf("TSA signature failed: %w", err)
+	}
+
+	return &signerInfo{
+		Version: 1,
+		SID: issuerAndSerialNumber{
+			Issuer:       asn1.RawValue{FullBytes: o.certs[0].RawIssuer},
+			SerialNumber: o.certs[0].SerialNumber,
+		},
+		DigestAlgorithm:    pkix.AlgorithmIdentifier{Algorithm: hashAlg, Parameters: asn1.NullRawValue},
+		SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attrs},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: sigAlg},
+		Signature:          sig,
+	}, nil
+}
+
+// localTSASignedAttrs returns the DER encoded content of the SET OF signed
+// attributes: content type, message digest and ESS signing certificate v2
+func localTSASignedAttrs(digest []byte, cert *x509.Certificate) ([]byte, error) {
+	certHash := hashSum(crypto.SHA256, cert.Raw)
+
+	values := []struct {
+		oid asn1.ObjectIdentifier
+		v   any
+	}{
+		{oidAttrContentType, oidTSTInfo},
+		{oidAttrMessageDigest, digest},
+		{oidAttrSigningCertV2, signingCertificateV2{Certs: []essCertIDv2{{CertHash: certHash}}}},
+	}
+
+	attrs := make([][]byte, 0, len(values))
+
+	for _, a := range values {
+		v, err := asn1.Marshal(a.v)
+		if err != nil {
+			return nil, err
+		}
+
+		attr, err := asn1.Marshal(attribute{Type: a.oid, Values: []asn1.RawValue{{FullBytes: v}}})
+		if err != nil {
+			return nil, err
+		}
+
+		attrs = append(attrs, attr)
+	}
+
+	// DER requires the elements of a SET OF to be sorted
+	sort.Slice(attrs, func(i, j int) bool { return bytes.Compare(attrs[i], attrs[j]) < 0 })
+
+	return bytes.Join(attrs, nil), nil
+}
+
+// localTSAAlgorithms returns the digest algorithm and the CMS signature
+// algorithm OID for the supplied key
+func localTSAAlgorithms(key crypto.Signer) (crypto.Hash, asn1.ObjectIdentifier, error) {
+	switch v := key.Public().(type) {
+	case *ecdsa.PublicKey:
+		switch v.Curve {
+		case elliptic.P256():
+			return crypto.SHA256, oidECDSAWithSHA256, nil
+		case elliptic.P384():
+			return crypto.SHA384, oidECDSAWithSHA384, nil
+		case elliptic.P521():
+			return crypto.SHA512, oidECDSAWithSHA512, nil
+		default:
+			return 0, nil, fmt.Errorf("unknown elliptic curve %v", v.Curve.Params().Name)
+		}
+	case *rsa.PublicKey:
+		return crypto.SHA256, oidSHA256WithRSA, nil
+	case ed25519.PublicKey:
+		// RFC 8419
+		return crypto.SHA512, oidEd25519, nil
+	default:
+		return 0, nil, fmt.Errorf("unknown public key type %v", reflect.TypeOf(v))
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/veraison/corim/extensions"
+	cose "github.com/veraison/go-cose"
+)
+
+// COSE MAC algorithms (RFC 9053, Section 3.1)
+const (
+	AlgorithmHMAC256 cose.Algorithm = 5
+	AlgorithmHMAC384 cose.Algorithm = 6
+	AlgorithmHMAC512 cose.Algorithm = 7
+)
+
+// coseMac0Tag is the CBOR tag #6.17 of a COSE_Mac0 message
+var coseMac0Tag = []byte{0xd1}
+
+// coseMac0 is the COSE_Mac0 structure
+type coseMac0 struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   cbor.RawMessage
+	Unprotected cose.UnprotectedHeader
+	Payload     []byte
+	Tag         []byte
+}
+
+// MacedCorim encodes a MAC-protected CoRIM (i.e., a COSE Mac0 wrapped CoRIM)
+// with MAC and verification methods.  It is the counterpart of SignedCorim for
+// the provisioning of CoRIMs with pre-shared keys: the unsigned-corim and the
+// corim-meta-map are carried in the same way as in a COSE Sign1 signed-corim.
+type MacedCorim struct {
+	UnsignedCorim UnsignedCorim
+	Meta          Meta
+	// KeyID, if set, is conveyed in the kid header of the maced-corim
+	KeyID []byte
+	// AlgorithmPolicy, if set, restricts the MAC algorithms accepted on
+	// verification.  It is populated from the profile of a maced-corim
+	// decoded with UnmarshalMacedCorimFromCBOR.
+	AlgorithmPolicy *AlgorithmPolicy
+	message         *coseMac0
+	protected       cose.ProtectedHeader
+}
+
+// NewMacedCorim instantiates an empty MacedCorim
+func NewMacedCorim() *MacedCorim {
+	return &MacedCorim{}
+}
+
+func (o *MacedCorim) RegisterExtensions(exts extensions.Map) error {
+	return registerCorimExtensions(&o.Meta, &o.UnsignedCorim, exts)
+}
+
+// isMacedCorim returns true if the supplied data is a COSE_Mac0 (#6.17)
+// message
+func isMacedCorim(data []byte) bool {
+	return bytes.HasPrefix(data, coseMac0Tag)
+}
+
+// decodeMac0 decodes the supplied COSE_Mac0 message
+func decodeMac0(buf []byte) (*coseMac0, error) {
+	if !isMacedCorim(buf) {
+		return nil, errors.New("expecting a COSE Mac0 message")
+	}
+
+	var m coseMac0
+	if err := dm.Unmarshal(buf[len(coseMac0Tag):], &m); err != nil {
+		return nil, fmt.Errorf("failed CBOR decoding for COSE-Mac0 maced CoRIM: %w", err)
+	}
+
+	return &m, nil
+}
+
+// FromCOSE decodes and effects syntactic validation on the supplied
+// maced-corim message, including the embedded unsigned-corim and corim-meta.
+// On success, the unsigned-corim-map is made available via the UnsignedCorim
+// field while the corim-meta-map is decoded into the Meta field.  The MAC is
+// not verified.
+func (o *MacedCorim) FromCOSE(buf []byte) error {
+	m, err := decodeMac0(buf)
+	if err != nil {
+		return err
+	}
+
+	var protected cose.ProtectedHeader
+	if err := protected.UnmarshalCBOR(m.Protected); err != nil {
+		return fmt.Errorf("processing COSE headers: %w", err)
+	}
+
+	meta, kid, err := processCorimHdrs(cose.Headers{Protected: protected, Unprotected: m.Unprotected})
+	if err != nil {
+		return fmt.Errorf("processing COSE headers: %w", err)
+	}
+
+	if err := o.UnsignedCorim.FromCBOR(m.Payload); err != nil {
+		return fmt.Errorf("failed CBOR decoding of unsigned CoRIM: %w", err)
+	}
+
+	if err := o.UnsignedCorim.Valid(); err != nil {
+		return fmt.Errorf("failed validation of unsigned CoRIM: %w", err)
+	}
+
+	o.Meta, o.KeyID = *meta, kid
+	o.message, o.protected = m, protected
+
+	return nil
+}
+
+// Mac returns the serialized maced-corim, authenticated with the supplied HMAC
+// algorithm and key.  The algorithm must be accepted by the AlgorithmPolicy,
+// if set, and the key must be at least as long as the output of the hash
+// function (RFC 9053, Section 3.1).  The target MacedCorim must have its
+// UnsignedCorim field correctly populated.
+func (o *MacedCorim) Mac(alg cose.Algorithm, key []byte) ([]byte, error) {
+	if err := o.AlgorithmPolicy.checkAlgorithm(alg); err != nil {
+		return nil, err
+	}
+
+	h, err := hmacHash(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkMACKey(alg, h, key); err != nil {
+		return nil, err
+	}
+
+	if err := o.UnsignedCorim.Valid(); err != nil {
+		return nil, fmt.Errorf("failed validation of unsigned CoRIM: %w", err)
+	}
+
+	payload, err := o.UnsignedCorim.ToCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("failed CBOR encoding of unsigned CoRIM: %w", err)
+	}
+
+	metaCBOR, err := o.Meta.ToCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("failed CBOR encoding of CoRIM Meta: %w", err)
+	}
+
+	hdr := cose.ProtectedHeader{}
+	hdr.SetAlgorithm(alg)
+	hdr[cose.HeaderLabelContentType] = ContentType
+	hdr[HeaderLabelCorimMeta] = metaCBOR
+
+	if len(o.KeyID) != 0 {
+		hdr[cose.HeaderLabelKeyID] = o.KeyID
+	}
+
+	protected, err := hdr.MarshalCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("encoding protected header: %w", err)
+	}
+
+	m := &coseMac0{
+		Protected:   protected,
+		Unprotected: cose.UnprotectedHeader{},
+		Payload:     payload,
+	}
+
+	if m.Tag, err = m.computeTag(h, key); err != nil {
+		return nil, err
+	}
+
+	wrap, err := marshalTagged(coseMac0Tag, m)
+	if err != nil {
+		return nil, fmt.Errorf("maced-corim marshaling failed: %w", err)
+	}
+
+	o.message, o.protected = m, hdr
+
+	return wrap, nil
+}
+
+// Verify verifies the MAC of the target MacedCorim object using the supplied
+// key.  The algorithm must be accepted by the AlgorithmPolicy, if set, and the
+// key must be at least as long as the output of the hash function.
+func (o *MacedCorim) Verify(key []byte) error {
+	return o.verify(key, o.AlgorithmPolicy)
+}
+
+func (o *MacedCorim) verify(key []byte, policy *AlgorithmPolicy) error {
+	if o.message == nil {
+		return errors.New("no Mac0 message found")
+	}
+
+	alg, err := o.protected.Algorithm()
+	if err != nil {
+		return fmt.Errorf("unable to get MAC algorithm: %w", err)
+	}
+
+	if err := policy.checkAlgorithm(alg); err != nil {
+		return err
+	}
+
+	h, err := hmacHash(alg)
+	if err != nil {
+		return err
+	}
+
+	if err := checkMACKey(alg, h, key); err != nil {
+		return err
+	}
+
+	tag, err := o.message.computeTag(h, key)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(tag, o.message.Tag) {
+		return cose.ErrVerification
+	}
+
+	return nil
+}
+
+// VerifyWithOptions verifies the MAC of the target MacedCorim object using the
+// supplied key and, if the MAC is good, checks that the current time is
+// within the validity period of both the MAC (from the corim-meta-map) and
+// the CoRIM (from the unsigned-corim-map), if present.  A *ValidityError is
+// returned if either check fails.  The options' AlgorithmPolicy, if set,
+// applies instead of the MacedCorim's.
+func (o *MacedCorim) VerifyWithOptions(key []byte, opts VerifyOptions) error {
+	if err := o.verify(key, opts.policy(o.AlgorithmPolicy)); err != nil {
+		return err
+	}
+
+	return o.CheckValidity(opts.now(), opts.Skew)
+}
+
+// CheckValidity checks that the supplied time is within the validity periods
+// of the MAC and of the CoRIM, with the supplied clock skew tolerance (see
+// SignedCorim.CheckValidity)
+func (o *MacedCorim) CheckValidity(now time.Time, skew time.Duration) error {
+	return checkValidity(o.Meta.Validity, o.UnsignedCorim.RimValidity, now, skew)
+}
+
+// computeTag returns the HMAC, with the supplied hash function and key, of the
+// encoded MAC_structure of the message
+func (o *coseMac0) computeTag(h crypto.Hash, key []byte) ([]byte, error) {
+	tbm, err := em.Marshal([]any{"MAC0", o.Protected, NoExternalData, o.Payload})
+	if err != nil {
+		return nil, fmt.Errorf("encoding MAC_structure: %w", err)
+	}
+
+	mac := hmac.New(h.New, key)
+	mac.Write(tbm)
+
+	return mac.Sum(nil), nil
+}
+
+// checkMACKey checks that the supplied key is at least as long as the output of
+// the hash function of the HMAC algorithm
+func checkMACKey(alg cose.Algorithm, h crypto.Hash, key []byte) error {
+	if len(key) == 0 {
+		return errors.New("empty MAC key")
+	}
+
+	if len(key) < h.Size() {
+		return fmt.Errorf("MAC key too short for %s: got %d bytes, expecting at least %d",
+			algorithmName(alg), len(key), h.Size())
+	}
+
+	return nil
+}
+
+func hmacHash(alg cose.Algorithm) (crypto.Hash, error) {
+	switch alg {
+	case AlgorithmHMAC256:
+		return crypto.SHA256, nil
+	case AlgorithmHMAC384:
+		return crypto.SHA384, nil
+	case AlgorithmHMAC512:
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported MAC algorithm %s", algorithmName(alg))
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/eat"
+	cose "github.com/veraison/go-cose"
+)
+
+// testMACKey is long enough for HMAC512
+var testMACKey = []byte("a pre-shared key for the manufacturing line of ACME Ltd. (HMAC512)")
+
+func TestMacedCorim_Mac_Verify(t *testing.T) {
+	for _, alg := range []cose.Algorithm{AlgorithmHMAC256, AlgorithmHMAC384, AlgorithmHMAC512} {
+		t.Run(algorithmName(alg), func(t *testing.T) {
+			MacedCorimIn := MacedCorim{
+				UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR),
+				Meta:          *metaGood(t),
+				KeyID:         []byte("line-1"),
+			}
+
+			cbor, err := MacedCorimIn.Mac(alg, testMACKey)
+			require.NoError(t, err)
+			assert.NoError(t, MacedCorimIn.Verify(testMACKey))
+
+			MacedCorimOut, err := UnmarshalMacedCorimFromCBOR(cbor)
+			require.NoError(t, err)
+
+			assert.Equal(t, []byte("line-1"), MacedCorimOut.KeyID)
+			assert.Equal(t, "ACME Ltd.", MacedCorimOut.Meta.Signer.Name)
+			assert.Equal(t, MacedCorimIn.UnsignedCorim.GetID(), MacedCorimOut.UnsignedCorim.GetID())
+
+			assert.NoError(t, MacedCorimOut.Verify(testMACKey))
+
+			err = MacedCorimOut.Verify([]byte("another pre-shared key for the other manufacturing line of ACME Ltd."))
+			assert.ErrorIs(t, err, cose.ErrVerification)
+		})
+	}
+}
+
+func TestMacedCorim_VerifyWithOptions(t *testing.T) {
+	MacedCorimIn := MacedCorim{
+		UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR),
+		Meta:          *metaGood(t),
+	}
+
+	cbor, err := MacedCorimIn.Mac(AlgorithmHMAC256, testMACKey)
+	require.NoError(t, err)
+
+	var MacedCorimOut MacedCorim
+	require.NoError(t, MacedCorimOut.FromCOSE(cbor))
+
+	// the validity period of metaGood has expired
+	err = MacedCorimOut.VerifyWithOptions(testMACKey, VerifyOptions{})
+	assert.ErrorIs(t, err, ErrExpired)
+
+	var verr *ValidityError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, ValiditySignature, verr.Scope)
+
+	notAfter := MacedCorimOut.Meta.Validity.NotAfter
+
+	err = MacedCorimOut.VerifyWithOptions(testMACKey, VerifyOptions{
+		Clock: func() time.Time { return notAfter.Add(-time.Hour) },
+	})
+	assert.NoError(t, err)
+}
+
+func TestMacedCorim_Verify_short_key(t *testing.T) {
+	MacedCorimIn := MacedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+
+	cbor, err := MacedCorimIn.Mac(AlgorithmHMAC384, testMACKey)
+	require.NoError(t, err)
+
+	MacedCorimOut, err := UnmarshalMacedCorimFromCBOR(cbor)
+	require.NoError(t, err)
+
+	err = MacedCorimOut.Verify(testMACKey[:32])
+	assert.EqualError(t, err, "MAC key too short for HMAC384: got 32 bytes, expecting at least 48")
+
+	err = MacedCorimOut.Verify(nil)
+	assert.EqualError(t, err, "empty MAC key")
+}
+
+func TestMacedCorim_tampered(t *testing.T) {
+	MacedCorimIn := MacedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+
+	cbor, err := MacedCorimIn.Mac(AlgorithmHMAC256, testMACKey)
+	require.NoError(t, err)
+
+	tampered := append([]byte{}, cbor...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	var MacedCorimOut MacedCorim
+	require.NoError(t, MacedCorimOut.FromCOSE(tampered))
+
+	err = MacedCorimOut.Verify(testMACKey)
+	assert.ErrorIs(t, err, cose.ErrVerification)
+}
+
+func TestUnmarshalMacedCorimFromCBOR_profile(t *testing.T) {
+	profileID, err := eat.NewProfile("http://example.com/provisioning-profile")
+	require.NoError(t, err)
+
+	policy := &AlgorithmPolicy{Algorithms: []cose.Algorithm{AlgorithmHMAC384, AlgorithmHMAC512}}
+
+	require.NoError(t, RegisterProfileWithAlgorithmPolicy(profileID, nil, policy))
+	defer UnregisterProfile(profileID)
+
+	MacedCorimIn := MacedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+	MacedCorimIn.UnsignedCorim.Profile = profileID
+
+	cbor, err := MacedCorimIn.Mac(AlgorithmHMAC256, testMACKey)
+	require.NoError(t, err)
+
+	// the policy of the profile applies to the decoded maced-corim
+	MacedCorimOut, err := UnmarshalMacedCorimFromCBOR(cbor)
+	require.NoError(t, err)
+	assert.Equal(t, profileID, MacedCorimOut.UnsignedCorim.Profile)
+	assert.Equal(t, policy, MacedCorimOut.AlgorithmPolicy)
+
+	err = MacedCorimOut.Verify(testMACKey)
+	assert.EqualError(t, err, "rejected by algorithm policy: algorithm HMAC256 is not allowed")
+
+	// the verifier's policy overrides the profile's
+	require.Nil(t, MacedCorimOut.Meta.Validity)
+
+	err = MacedCorimOut.VerifyWithOptions(testMACKey, VerifyOptions{AlgorithmPolicy: &AlgorithmPolicy{}})
+	assert.NoError(t, err)
+}
+
+func TestMacedCorim_fail(t *testing.T) {
+	var MacedCorimIn MacedCorim
+
+	_, err := MacedCorimIn.Mac(cose.AlgorithmES256, testMACKey)
+	assert.EqualError(t, err, "unsupported MAC algorithm ES256")
+
+	_, err = MacedCorimIn.Mac(AlgorithmHMAC256, nil)
+	assert.EqualError(t, err, "empty MAC key")
+
+	// keys shorter than the hash output are rejected
+	_, err = MacedCorimIn.Mac(AlgorithmHMAC256, []byte{0x01})
+	assert.EqualError(t, err, "MAC key too short for HMAC256: got 1 bytes, expecting at least 32")
+
+	_, err = MacedCorimIn.Mac(AlgorithmHMAC512, testMACKey[:48])
+	assert.EqualError(t, err, "MAC key too short for HMAC512: got 48 bytes, expecting at least 64")
+
+	MacedCorimIn.AlgorithmPolicy = &AlgorithmPolicy{Algorithms: []cose.Algorithm{AlgorithmHMAC384}}
+
+	_, err = MacedCorimIn.Mac(AlgorithmHMAC256, testMACKey)
+	assert.EqualError(t, err, "rejected by algorithm policy: algorithm HMAC256 is not allowed")
+
+	MacedCorimIn.AlgorithmPolicy = nil
+
+	_, err = MacedCorimIn.Mac(AlgorithmHMAC256, testMACKey)
+	assert.ErrorContains(t, err, "failed validation of unsigned CoRIM")
+
+	err = MacedCorimIn.Verify(testMACKey)
+	assert.EqualError(t, err, "no Mac0 message found")
+
+	_, err = UnmarshalMacedCorimFromCBOR(testGoodSignedCorimCBOR)
+	assert.EqualError(t, err, "expecting a COSE Mac0 message")
+
+	err = MacedCorimIn.FromCOSE(coseMac0Tag)
+	assert.ErrorContains(t, err, "failed CBOR decoding for COSE-Mac0 maced CoRIM")
+
+	// a Mac0 message with no content type
+	m := coseMac0{
+		Protected:   []byte{0x40},
+		Unprotected: cose.UnprotectedHeader{},
+		Payload:     testGoodUnsignedCorimCBOR,
+		Tag:         []byte{0x00},
+	}
+
+	cbor, err := marshalTagged(coseMac0Tag, m)
+	require.NoError(t, err)
+
+	err = MacedCorimIn.FromCOSE(cbor)
+	assert.EqualError(t, err, "processing COSE headers: missing mandatory content type")
+}
@@ -3,6 +3,7 @@
 package corim
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -37,6 +38,10 @@ var ComidMapExtensionPoints = []extensions.Point{
 	comid.ExtReferenceValueFlags,
 	comid.ExtEndorsedValue,
 	comid.ExtEndorsedValueFlags,
+	comid.ExtCondEndorseValue,
+	comid.ExtCondEndorseValueFlags,
+	comid.ExtCondEndorseSeriesValue,
+	comid.ExtCondEndorseSeriesValueFlags,
 }
 
 // AllExtensionPoints is a list of all valid extension.Point's
@@ -45,23 +50,132 @@ var AllExtensionPoints = make(map[extensions.Point]bool) // populated inside ini
 // UnmarshalSignedCorimFromCBOR unmarshals a SignedCorim from provided
 // CBOR data. If there are extensions associated with the profile specified by
 // the data, they will be registered with the UnsignedCorim before it is
-// unmarshaled.
+// unmarshaled. Encrypted CoRIMs cannot be decrypted without keys: an error
+// wrapping ErrNoDecryptionKey is returned for them (see
+// UnmarshalSignedCorimFromCBORWithKeys).
 func UnmarshalSignedCorimFromCBOR(buf []byte) (*SignedCorim, error) {
+	if isEncryptedCorim(buf) {
+		return nil, fmt.Errorf("%w: encrypted CoRIM, use UnmarshalSignedCorimFromCBORWithKeys", ErrNoDecryptionKey)
+	}
+
+	return UnmarshalSignedCorimFromCBORWithKeys(buf, nil)
+}
+
+// UnmarshalSignedCorimFromCBORWithKeys is like UnmarshalSignedCorimFromCBOR,
+// but the CBOR data may also be a sign-then-encrypt CoRIM, i.e., a
+// COSE_Encrypt0 or COSE_Encrypt message wrapping a signed-corim, which is
+// decrypted with the keys looked up by the supplied DecryptionKeyResolver.
+func UnmarshalSignedCorimFromCBORWithKeys(buf []byte, keys DecryptionKeyResolver) (*SignedCorim, error) {
+	if isEncryptedCorim(buf) {
+		plaintext, contentType, err := Decrypt(buf, keys)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting CoRIM: %w", err)
+		}
+
+		if contentType != SignedContentType {
+			return nil, fmt.Errorf("expecting encrypted content type %q, got %q instead",
+				SignedContentType, contentType)
+		}
+
+		buf = plaintext
+	}
+
+	payload, err := signedCorimPayload(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	// the profile of a detached payload is only applied by AttachPayload
+	if payload == nil {
+		ret := NewSignedCorim()
+		if err := ret.FromCOSE(buf); err != nil {
+			return nil, err
+		}
+
+		return ret, nil
+	}
+
+	return unmarshalProfiledSignedCorim(buf, payload)
+}
+
+// UnmarshalDetachedSignedCorimFromCBOR unmarshals a SignedCorim from provided
+// CBOR data, which must be a COSE Sign1 signed-corim with a detached payload,
+// and from the provided payload. If there are extensions associated with the
+// profile specified by the payload, they will be registered with the
+// UnsignedCorim before it is unmarshaled.
+func UnmarshalDetachedSignedCorimFromCBOR(buf, payload []byte) (*SignedCorim, error) {
+	ret := NewSignedCorim()
+	if err := ret.FromCOSE(buf); err != nil {
+		return nil, err
+	}
+
+	if err := ret.AttachPayload(payload); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func unmarshalProfiledSignedCorim(buf, payload []byte) (*SignedCorim, error) {
+	profiled := struct {
+		Profile *eat.Profile `cbor:"3,keyasint,omitempty"`
+	}{}
+
+	if err := dm.Unmarshal(payload, &profiled); err != nil {
+		return nil, err
+	}
+
+	ret := GetSignedCorim(profiled.Profile)
+	if err := ret.FromCOSE(buf); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// signedCorimPayload returns the payload of the supplied signed-corim, which
+// may be either a COSE Sign1 or a COSE Sign message
+func signedCorimPayload(buf []byte) ([]byte, error) {
+	buf, _ = bytes.CutPrefix(buf, corimTypeChoiceSigned)
+
+	if isCOSESign(buf) {
+		message := cose.NewSignMessage()
+
+		if err := message.UnmarshalCBOR(buf); err != nil {
+			return nil, fmt.Errorf("failed CBOR decoding for COSE-Sign signed CoRIM: %w", err)
+		}
+
+		return message.Payload, nil
+	}
+
 	message := cose.NewSign1Message()
 
 	if err := message.UnmarshalCBOR(buf); err != nil {
 		return nil, fmt.Errorf("failed CBOR decoding for COSE-Sign1 signed CoRIM: %w", err)
 	}
 
+	return message.Payload, nil
+}
+
+// UnmarshalMacedCorimFromCBOR unmarshals a MacedCorim from provided CBOR
+// data. If there are extensions associated with the profile specified by the
+// data, they will be registered with the UnsignedCorim before it is
+// unmarshaled.
+func UnmarshalMacedCorimFromCBOR(buf []byte) (*MacedCorim, error) {
+	m, err := decodeMac0(buf)
+	if err != nil {
+		return nil, err
+	}
+
 	profiled := struct {
 		Profile *eat.Profile `cbor:"3,keyasint,omitempty"`
 	}{}
 
-	if err := dm.Unmarshal(message.Payload, &profiled); err != nil {
+	if err := dm.Unmarshal(m.Payload, &profiled); err != nil {
 		return nil, err
 	}
 
-	ret := GetSignedCorim(profiled.Profile)
+	ret := GetMacedCorim(profiled.Profile)
 	if err := ret.FromCOSE(buf); err != nil {
 		return nil, err
 	}
@@ -72,8 +186,14 @@ func UnmarshalSignedCorimFromCBOR(buf []byte) (*SignedCorim, error) {
 // UnmarshalUnsignedCorimFromCBOR unmarshals an UnsignedCorim from provided
 // CBOR data. If there are extensions associated with the profile specified by
 // the data, they will be registered with the UnsignedCorim before it is
-// unmarshaled.
+// unmarshaled. Encrypted CoRIMs cannot be decrypted without keys: an error
+// wrapping ErrNoDecryptionKey is returned for them (see
+// UnmarshalUnsignedCorimFromCBORWithKeys).
 func UnmarshalUnsignedCorimFromCBOR(buf []byte) (*UnsignedCorim, error) {
+	if isEncryptedCorim(buf) {
+		return nil, fmt.Errorf("%w: encrypted CoRIM, use UnmarshalUnsignedCorimFromCBORWithKeys", ErrNoDecryptionKey)
+	}
+
 	profiled := struct {
 		Profile *eat.Profile `cbor:"3,keyasint,omitempty"`
 	}{}
@@ -90,6 +210,29 @@ func UnmarshalUnsignedCorimFromCBOR(buf []byte) (*UnsignedCorim, error) {
 	return ret, nil
 }
 
+// UnmarshalUnsignedCorimFromCBORWithKeys is like
+// UnmarshalUnsignedCorimFromCBOR, but the CBOR data may also be an encrypted
+// CoRIM, i.e., a COSE_Encrypt0 or COSE_Encrypt message wrapping an
+// unsigned-corim, which is decrypted with the keys looked up by the supplied
+// DecryptionKeyResolver.
+func UnmarshalUnsignedCorimFromCBORWithKeys(buf []byte, keys DecryptionKeyResolver) (*UnsignedCorim, error) {
+	if isEncryptedCorim(buf) {
+		plaintext, contentType, err := Decrypt(buf, keys)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting CoRIM: %w", err)
+		}
+
+		if contentType != ContentType {
+			return nil, fmt.Errorf("expecting encrypted content type %q, got %q instead",
+				ContentType, contentType)
+		}
+
+		buf = plaintext
+	}
+
+	return UnmarshalUnsignedCorimFromCBOR(buf)
+}
+
 // UnmarshalUnsignedCorimFromJSON unmarshals an UnsignedCorim from provided
 // JSON data. If there are extensions associated with the profile specified by
 // the data, they will be registered with the UnsignedCorim before it is
@@ -160,6 +303,20 @@ func GetSignedCorim(profileID *eat.Profile) *SignedCorim {
 	return ret
 }
 
+// GetMacedCorim returns a pointer to a new MacedCorim instance. If there are
+// extensions associated with the provided profileID, they will be registered
+// with the instance.
+func GetMacedCorim(profileID *eat.Profile) *MacedCorim {
+	profile, ok := GetProfile(profileID)
+	if !ok {
+		// no or unknown profile -- treat like an unprofiled CoRIM (see
+		// GetSignedCorim)
+		return NewMacedCorim()
+	}
+
+	return profile.GetMacedCorim()
+}
+
 // GetUnsignedCorim returns a pointer to a new UnsignedCorim instance. If there
 // are extensions associated with the provided profileID, they will be
 // registered with the instance.
@@ -189,12 +346,14 @@ func GetUnsignedCorim(profileID *eat.Profile) *UnsignedCorim {
 	return ret
 }
 
-// Profile associates an EAT profile ID with a set of extensions. It allows
-// obtaining new CoRIM and CoMID structures that had associated extensions
-// registered.
+// Profile associates an EAT profile ID with a set of extensions and,
+// optionally, with the algorithm policy for the verification of its signed
+// CoRIMs. It allows obtaining new CoRIM and CoMID structures that had
+// associated extensions registered.
 type Profile struct {
-	ID            *eat.Profile
-	MapExtensions extensions.Map
+	ID              *eat.Profile
+	MapExtensions   extensions.Map
+	AlgorithmPolicy *AlgorithmPolicy
 }
 
 // GetComid returns a pointer to a new comid.Comid that had the Profile's
@@ -215,10 +374,23 @@ func (o *Profile) GetUnsignedCorim() *UnsignedCorim {
 }
 
 // GetSignedCorim returns a pointer to a new SignedCorim that had the
-// Profile's extensions (if any) registered.
+// Profile's extensions (if any) registered, and the Profile's algorithm policy
+// (if any) set.
 func (o *Profile) GetSignedCorim() *SignedCorim {
 	ret := NewSignedCorim()
 	ret.UnsignedCorim.Profile = o.ID
+	ret.AlgorithmPolicy = o.AlgorithmPolicy
+	o.registerExtensions(ret, SignedCorimMapExtensionPoints)
+	return ret
+}
+
+// GetMacedCorim returns a pointer to a new MacedCorim that had the Profile's
+// extensions (if any) registered, and the Profile's algorithm policy (if any)
+// set.  The extension points are those of a SignedCorim.
+func (o *Profile) GetMacedCorim() *MacedCorim {
+	ret := NewMacedCorim()
+	ret.UnsignedCorim.Profile = o.ID
+	ret.AlgorithmPolicy = o.AlgorithmPolicy
 	o.registerExtensions(ret, SignedCorimMapExtensionPoints)
 	return ret
 }
@@ -243,6 +415,17 @@ func (o *Profile) registerExtensions(e iextensible, points []extensions.Point) {
 // the profile has already been registered, or if the extensions are invalid,
 // an error is returned.
 func RegisterProfile(id *eat.Profile, exts extensions.Map) error {
+	return RegisterProfileWithAlgorithmPolicy(id, exts, nil)
+}
+
+// RegisterProfileWithAlgorithmPolicy registers a set of extensions, and the
+// algorithm policy that applies to the verification of signed CoRIMs, with the
+// specified profile. A nil policy accepts any algorithm and key. If the
+// profile has already been registered, or if the extensions are invalid, an
+// error is returned.
+func RegisterProfileWithAlgorithmPolicy(
+	id *eat.Profile, exts extensions.Map, policy *AlgorithmPolicy,
+) error {
 	strID, err := id.Get()
 	if err != nil {
 		return err
@@ -262,7 +445,7 @@ func RegisterProfile(id *eat.Profile, exts extensions.Map) error {
 		}
 	}
 
-	profilesRegister[strID] = Profile{ID: id, MapExtensions: exts}
+	profilesRegister[strID] = Profile{ID: id, MapExtensions: exts, AlgorithmPolicy: policy}
 
 	return nil
 }
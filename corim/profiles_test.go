@@ -185,3 +185,72 @@ func TestProfile_marshaling(t *testing.T) {
 
 	UnregisterProfile(profID)
 }
+
+func TestProfile_CondEndorse_extensions(t *testing.T) {
+	type mvalExtensions struct {
+		Timestamp *int `cbor:"-1,keyasint,omitempty" json:"timestamp,omitempty"`
+	}
+
+	type flagsExtensions struct {
+		IsAudited *bool `cbor:"-1,keyasint,omitempty" json:"is-audited,omitempty"`
+	}
+
+	profID, err := eat.NewProfile("http://example.com/test-profile")
+	require.NoError(t, err)
+
+	extMap := extensions.NewMap().
+		Add(comid.ExtCondEndorseValue, &mvalExtensions{}).
+		Add(comid.ExtCondEndorseValueFlags, &flagsExtensions{}).
+		Add(comid.ExtCondEndorseSeriesValue, &mvalExtensions{}).
+		Add(comid.ExtCondEndorseSeriesValueFlags, &flagsExtensions{})
+	require.NoError(t, RegisterProfile(profID, extMap))
+	defer UnregisterProfile(profID)
+
+	env := comid.Environment{Class: comid.NewClassUUID(comid.TestUUID)}
+	ts := 1720782190
+
+	withTimestamp := func() comid.Measurements {
+		m := comid.MustNewUintMeasurement(uint64(1)).SetSVN(2)
+		require.NoError(t, m.Val.RegisterExtensions(
+			extensions.NewMap().Add(comid.ExtMval, &mvalExtensions{Timestamp: &ts}),
+		))
+		return *comid.NewMeasurements().Add(m)
+	}
+
+	condition := comid.ValueTriple{
+		Environment:  env,
+		Measurements: *comid.NewMeasurements().Add(comid.MustNewUintMeasurement(uint64(1)).SetMinSVN(1)),
+	}
+
+	c := comid.NewComid().
+		SetTagIdentity(comid.TestTagID, 0).
+		AddCondEndorsement(*comid.NewCondEndorseTriple().
+			AddCondition(condition).
+			AddEndorsement(comid.ValueTriple{Environment: env, Measurements: withTimestamp()})).
+		AddCondEndorsementSeries(*comid.NewCondEndorseSeriesTriple(condition).
+			AddSeries(condition.Measurements, withTimestamp()))
+	require.NotNil(t, c)
+
+	u := NewUnsignedCorim().SetID("test corim id").AddComid(c).SetProfile("http://example.com/test-profile")
+	require.NotNil(t, u)
+
+	data, err := u.ToCBOR()
+	require.NoError(t, err)
+
+	out, err := UnmarshalUnsignedCorimFromCBOR(data)
+	require.NoError(t, err)
+
+	comids, err := out.GetComids()
+	require.NoError(t, err)
+	require.Len(t, comids, 1)
+
+	triples := comids[0].Triples
+
+	require.NotNil(t, triples.CondEndorse)
+	endorsement := triples.CondEndorse.Values[0].Endorsements.Values[0].Measurements.Values[0]
+	assert.Equal(t, ts, endorsement.Val.Extensions.MustGetInt("timestamp"))
+
+	require.NotNil(t, triples.CondEndorseSeries)
+	addition := triples.CondEndorseSeries.Values[0].Series.Values[0].Addition.Values[0]
+	assert.Equal(t, ts, addition.Val.Extensions.MustGetInt("timestamp"))
+}
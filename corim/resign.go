@@ -0,0 +1,89 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	cose "github.com/veraison/go-cose"
+)
+
+// Resign verifies the signature of the target SignedCorim, which must hold a
+// signed COSE Sign1 message, with the supplied (old) public key, and signs its
+// payload again with the supplied (new) cose Signer, e.g., for key rotation.
+// The payload bytes are preserved exactly, so that thumbprints of the CoRIM
+// (e.g., in Locators) remain valid.  If meta is not nil, it replaces the
+// corim-meta-map (e.g., to update the signer or the validity period).  The
+// KeyID and certificate fields are used as they are, and should be updated for
+// the new signer beforehand.  Countersignatures, timestamps and any other
+// unprotected headers of the old signature are dropped.  On success, the
+// serialized signed-corim is returned; on failure, the SignedCorim is left
+// unchanged.
+func (o *SignedCorim) Resign(oldKey crypto.PublicKey, signer cose.Signer, meta *Meta) ([]byte, error) {
+	if signer == nil {
+		return nil, errors.New("nil signer")
+	}
+
+	if err := o.checkSigned(); err != nil {
+		return nil, err
+	}
+
+	if err := o.Verify(oldKey); err != nil {
+		return nil, fmt.Errorf("verifying with the old key: %w", err)
+	}
+
+	alg, err := signerAlgorithm(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	saved := *o
+
+	wrap, err := o.resign(alg, signer, meta)
+	if err != nil {
+		*o = saved
+		return nil, err
+	}
+
+	return wrap, nil
+}
+
+func (o *SignedCorim) resign(alg cose.Algorithm, signer cose.Signer, meta *Meta) ([]byte, error) {
+	if meta != nil {
+		o.Meta = *meta
+	}
+
+	tbs, err := o.prepareSign1(alg, o.message.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := signer.Sign(rand.Reader, tbs)
+	if err != nil {
+		return nil, fmt.Errorf("COSE Sign1 signature failed: %w", err)
+	}
+
+	return o.AttachSignature(sig)
+}
+
+// ResignSignedCorim decodes the supplied serialized signed-corim (see
+// UnmarshalSignedCorimFromCBOR) and re-signs it (see SignedCorim.Resign).  The
+// supplied kid (which may be nil) replaces the original one, and the x5chain
+// of the original signature, which does not match the new signer, is dropped.
+func ResignSignedCorim(
+	buf []byte, oldKey crypto.PublicKey, signer cose.Signer, meta *Meta, kid []byte,
+) ([]byte, error) {
+	s, err := UnmarshalSignedCorimFromCBOR(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	s.KeyID = kid
+	s.SigningCert, s.IntermediateCerts = nil, nil
+
+	return s.Resign(oldKey, signer, meta)
+}
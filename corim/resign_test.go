@@ -0,0 +1,163 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cose "github.com/veraison/go-cose"
+)
+
+type failingSigner struct{}
+
+func (failingSigner) Algorithm() cose.Algorithm {
+	return cose.AlgorithmES256
+}
+
+func (failingSigner) Sign(io.Reader, []byte) ([]byte, error) {
+	return nil, errors.New("HSM unavailable")
+}
+
+// testNonCanonicalPayload returns the good unsigned-corim with the keys of its
+// map out of order, which ToCBOR would not reproduce
+func testNonCanonicalPayload(t *testing.T) []byte {
+	good := testGoodUnsignedCorimCBOR
+
+	// a2 00 6d <13 bytes id> 01 ... => a2 01 ... 00 6d <13 bytes id>
+	require.Equal(t, []byte{0xa2, 0x00, 0x6d}, good[:3])
+
+	payload := append([]byte{0xa2}, good[16:]...)
+	payload = append(payload, good[1:16]...)
+
+	u := unsignedCorimFromCBOR(t, payload)
+
+	reencoded, err := u.ToCBOR()
+	require.NoError(t, err)
+	require.NotEqual(t, payload, reencoded)
+
+	return payload
+}
+
+// signPayload returns a COSE Sign1 signed-corim over the supplied payload
+func signPayload(t *testing.T, payload []byte, signer cose.Signer, kid []byte) []byte {
+	SignedCorimIn := SignedCorim{Meta: *metaGood(t), KeyID: kid}
+
+	tbs, err := SignedCorimIn.prepareSign1(signer.Algorithm(), payload)
+	require.NoError(t, err)
+
+	sig, err := signer.Sign(rand.Reader, tbs)
+	require.NoError(t, err)
+
+	cbor, err := SignedCorimIn.AttachSignature(sig)
+	require.NoError(t, err)
+
+	return cbor
+}
+
+func TestSignedCorim_Resign(t *testing.T) {
+	oldSigner, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	oldKey, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	newSigner, err := NewSignerFromJWK(testES384Key)
+	require.NoError(t, err)
+
+	newKey, err := NewPublicKeyFromJWK(testES384Key)
+	require.NoError(t, err)
+
+	payload := testNonCanonicalPayload(t)
+	cbor := signPayload(t, payload, oldSigner, []byte("old"))
+
+	var SignedCorimIn SignedCorim
+	require.NoError(t, SignedCorimIn.FromCOSE(cbor))
+
+	notBefore := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := notBefore.AddDate(2, 0, 0)
+
+	meta := NewMeta().SetSigner("ACME Ltd. (2025)", nil).SetValidity(notAfter, &notBefore)
+	require.NotNil(t, meta)
+
+	SignedCorimIn.KeyID = []byte("new")
+
+	cbor, err = SignedCorimIn.Resign(oldKey, newSigner, meta)
+	require.NoError(t, err)
+
+	SignedCorimOut, err := UnmarshalSignedCorimFromCBOR(cbor)
+	require.NoError(t, err)
+
+	// the payload is byte-identical
+	m := cose.NewSign1Message()
+	require.NoError(t, m.UnmarshalCBOR(cbor))
+	assert.Equal(t, payload, m.Payload)
+
+	assert.NoError(t, SignedCorimOut.Verify(newKey))
+	assert.Error(t, SignedCorimOut.Verify(oldKey))
+
+	assert.Equal(t, []byte("new"), SignedCorimOut.KeyID)
+	assert.Equal(t, "ACME Ltd. (2025)", SignedCorimOut.Meta.Signer.Name)
+	assert.True(t, notAfter.Equal(SignedCorimOut.Meta.Validity.NotAfter))
+
+	// without a new Meta, the original one is kept
+	cbor, err = ResignSignedCorim(cbor, newKey, oldSigner, nil, nil)
+	require.NoError(t, err)
+
+	SignedCorimOut, err = UnmarshalSignedCorimFromCBOR(cbor)
+	require.NoError(t, err)
+	assert.NoError(t, SignedCorimOut.Verify(oldKey))
+	assert.Nil(t, SignedCorimOut.KeyID)
+	assert.Equal(t, "ACME Ltd. (2025)", SignedCorimOut.Meta.Signer.Name)
+
+	require.NoError(t, m.UnmarshalCBOR(cbor))
+	assert.Equal(t, payload, m.Payload)
+}
+
+func TestSignedCorim_Resign_fail(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	otherKey, err := NewPublicKeyFromJWK(testES384Key)
+	require.NoError(t, err)
+
+	var SignedCorimIn SignedCorim
+
+	_, err = SignedCorimIn.Resign(otherKey, signer, nil)
+	assert.EqualError(t, err, "no Sign1 message found")
+
+	cbor := signPayload(t, testGoodUnsignedCorimCBOR, signer, nil)
+	require.NoError(t, SignedCorimIn.FromCOSE(cbor))
+
+	_, err = SignedCorimIn.Resign(otherKey, nil, nil)
+	assert.EqualError(t, err, "nil signer")
+
+	// the old signature does not verify
+	_, err = SignedCorimIn.Resign(otherKey, signer, NewMeta().SetSigner("Mallory", nil))
+	assert.EqualError(t, err, "verifying with the old key: verification error")
+	assert.Equal(t, "ACME Ltd.", SignedCorimIn.Meta.Signer.Name)
+
+	// the SignedCorim is unchanged on failure
+	pk, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	_, err = SignedCorimIn.Resign(pk, failingSigner{}, NewMeta().SetSigner("ACME Ltd. (2025)", nil))
+	assert.EqualError(t, err, "COSE Sign1 signature failed: HSM unavailable")
+	assert.Equal(t, "ACME Ltd.", SignedCorimIn.Meta.Signer.Name)
+
+	out, err := SignedCorimIn.ToCOSE()
+	require.NoError(t, err)
+	assert.Equal(t, cbor, out)
+
+	_, err = ResignSignedCorim([]byte("bad"), pk, signer, nil, nil)
+	assert.Error(t, err)
+
+	_, err = (&SignedCorim{}).Resign(pk, signer, nil)
+	assert.EqualError(t, err, "no Sign1 message found")
+}
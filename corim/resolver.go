@@ -0,0 +1,412 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/veraison/swid"
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	// ErrThumbprintMismatch is returned when the digest of a fetched RIM does
+	// not match the thumbprint in its locator
+	ErrThumbprintMismatch = errors.New("thumbprint mismatch")
+	// ErrDependencyCycle is returned when a dependent RIM (directly or
+	// indirectly) depends on itself
+	ErrDependencyCycle = errors.New("dependency cycle")
+	// ErrMaxDepthExceeded is returned when dependent RIMs are nested deeper
+	// than the configured limit
+	ErrMaxDepthExceeded = errors.New("maximum dependency depth exceeded")
+	// ErrUnsupportedScheme is returned by a LocatorResolver that cannot handle
+	// the scheme of the supplied href
+	ErrUnsupportedScheme = errors.New("unsupported URI scheme")
+)
+
+// DefaultMaxDependencyDepth is the maximum nesting of dependent RIMs followed
+// by a DependencyResolver that does not set MaxDepth
+const DefaultMaxDependencyDepth = 8
+
+// DefaultMaxRimSize is the maximum size of a RIM fetched by HTTPResolver or
+// FileResolver if MaxSize is not set
+const DefaultMaxRimSize = 16 << 20
+
+// LocatorResolver fetches the RIM identified by the href of a corim-locator-map
+type LocatorResolver interface {
+	Resolve(ctx context.Context, href string) ([]byte, error)
+}
+
+// FileResolver resolves "file" URIs (as well as plain paths) by reading from
+// the local filesystem, below BaseDir.  Since hrefs come from (possibly
+// untrusted) CoRIMs, BaseDir must be set: all paths, including absolute ones,
+// are interpreted relative to it, and neither ".." elements nor symbolic
+// links can escape it.
+type FileResolver struct {
+	BaseDir string
+	MaxSize int64
+}
+
+// Resolve reads the file identified by the supplied href
+func (o FileResolver) Resolve(ctx context.Context, href string) ([]byte, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil, fmt.Errorf("parsing href %q: %w", href, err)
+	}
+
+	if u.Scheme != "" && u.Scheme != "file" {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedScheme, u.Scheme)
+	}
+
+	path := u.Path
+	if u.Scheme == "" {
+		path = href
+	}
+
+	path, err = o.resolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readLimited(f, o.MaxSize)
+}
+
+// resolvePath returns the supplied path, interpreted relative to BaseDir, with
+// any symbolic links resolved.  An error is returned if the result is not
+// within BaseDir.
+func (o FileResolver) resolvePath(path string) (string, error) {
+	if o.BaseDir == "" {
+		return "", errors.New("no base directory")
+	}
+
+	base, err := filepath.EvalSymlinks(o.BaseDir)
+	if err != nil {
+		return "", fmt.Errorf("resolving base directory: %w", err)
+	}
+
+	base, err = filepath.Abs(base)
+	if err != nil {
+		return "", fmt.Errorf("resolving base directory: %w", err)
+	}
+
+	// cleaning an absolute path removes any leading "..", so the result is
+	// lexically within BaseDir
+	path, err = filepath.EvalSymlinks(filepath.Join(base, filepath.Clean("/"+path)))
+	if err != nil {
+		return "", err
+	}
+
+	// symbolic links may still point outside of it
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%q is outside of the base directory", path)
+	}
+
+	return path, nil
+}
+
+// HTTPResolver resolves "http" and "https" URIs using the supplied Client (or
+// http.DefaultClient, if Client is nil)
+type HTTPResolver struct {
+	Client  *http.Client
+	MaxSize int64
+}
+
+// Resolve issues a GET request for the supplied href and returns the response
+// body
+func (o HTTPResolver) Resolve(ctx context.Context, href string) ([]byte, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil, fmt.Errorf("parsing href %q: %w", href, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedScheme, u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, href, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ContentType)
+
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: unexpected status %q", href, res.Status)
+	}
+
+	return readLimited(res.Body, o.MaxSize)
+}
+
+func readLimited(r io.Reader, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxRimSize
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("RIM exceeds maximum size of %d bytes", maxSize)
+	}
+
+	return data, nil
+}
+
+// ResolvedRim is a dependent RIM that has been fetched and decoded by a
+// DependencyResolver
+type ResolvedRim struct {
+	// Locator is the corim-locator-map the RIM was fetched from
+	Locator Locator
+	// Parent is the corim-id of the RIM that references this one
+	Parent string
+	// Depth is the nesting level, starting at 1 for the RIMs referenced by
+	// the root
+	Depth int
+	// Data is the RIM as fetched
+	Data []byte
+	// Signed is set if the fetched RIM is a signed CoRIM.  Note that the
+	// signature is not verified by the resolver.
+	Signed *SignedCorim
+	// Unsigned is the unsigned-corim-map of the fetched RIM (if the RIM is
+	// signed, it points to Signed.UnsignedCorim)
+	Unsigned *UnsignedCorim
+}
+
+// DependencyResolver follows the dependent-rims of a CoRIM, recursively
+type DependencyResolver struct {
+	Resolver LocatorResolver
+	// MaxDepth is the maximum nesting of dependent RIMs.  If not set,
+	// DefaultMaxDependencyDepth is used.
+	MaxDepth int
+}
+
+// NewDependencyResolver instantiates a DependencyResolver that uses the
+// supplied LocatorResolver to fetch RIMs
+func NewDependencyResolver(r LocatorResolver) *DependencyResolver {
+	return &DependencyResolver{Resolver: r}
+}
+
+// Resolve fetches the dependent RIMs of the supplied CoRIM and, recursively,
+// their own dependent RIMs.  Each RIM is checked against the thumbprint (if
+// any) in its locator and decoded (using UnmarshalSignedCorimFromCBOR or
+// UnmarshalUnsignedCorimFromCBOR, as appropriate).  RIMs are returned in
+// depth-first order; a RIM referenced more than once is only fetched and
+// returned the first time.  An error is returned if a RIM depends on one of
+// its ancestors, or if the nesting exceeds the maximum depth.
+func (o DependencyResolver) Resolve(ctx context.Context, root *UnsignedCorim) ([]ResolvedRim, error) {
+	if o.Resolver == nil {
+		return nil, errors.New("no locator resolver")
+	}
+
+	if root == nil {
+		return nil, errors.New("nil CoRIM")
+	}
+
+	w := walker{
+		DependencyResolver: o,
+		seen:               make(map[string]bool),
+		pathHrefs:          make(map[string]bool),
+		pathIDs:            map[string]bool{root.GetID(): true},
+	}
+
+	if w.MaxDepth <= 0 {
+		w.MaxDepth = DefaultMaxDependencyDepth
+	}
+
+	if err := w.walk(ctx, root, 1); err != nil {
+		return nil, err
+	}
+
+	return w.resolved, nil
+}
+
+type walker struct {
+	DependencyResolver
+
+	// hrefs that have been resolved so far
+	seen map[string]bool
+	// hrefs and corim-ids of the RIMs on the current path from the root
+	pathHrefs map[string]bool
+	pathIDs   map[string]bool
+
+	resolved []ResolvedRim
+}
+
+func (o *walker) walk(ctx context.Context, parent *UnsignedCorim, depth int) error {
+	if parent.DependentRims == nil {
+		return nil
+	}
+
+	for _, l := range *parent.DependentRims {
+		href := string(l.Href)
+
+		if o.pathHrefs[href] {
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, href)
+		}
+
+		if o.seen[href] {
+			continue
+		}
+
+		if depth > o.MaxDepth {
+			return fmt.Errorf("%w (%d) at %s", ErrMaxDepthExceeded, o.MaxDepth, href)
+		}
+
+		rim, err := o.fetch(ctx, l)
+		if err != nil {
+			return fmt.Errorf("dependent RIM %s: %w", href, err)
+		}
+
+		rim.Parent = parent.GetID()
+		rim.Depth = depth
+
+		id := rim.Unsigned.GetID()
+		if o.pathIDs[id] {
+			return fmt.Errorf("%w: %s (corim-id %q)", ErrDependencyCycle, href, id)
+		}
+
+		o.seen[href] = true
+		o.resolved = append(o.resolved, *rim)
+
+		o.pathHrefs[href], o.pathIDs[id] = true, true
+
+		if err := o.walk(ctx, rim.Unsigned, depth+1); err != nil {
+			return err
+		}
+
+		delete(o.pathHrefs, href)
+		delete(o.pathIDs, id)
+	}
+
+	return nil
+}
+
+func (o *walker) fetch(ctx context.Context, l Locator) (*ResolvedRim, error) {
+	data, err := o.Resolver.Resolve(ctx, string(l.Href))
+	if err != nil {
+		return nil, fmt.Errorf("fetching: %w", err)
+	}
+
+	if l.Thumbprint != nil {
+		if err := VerifyThumbprint(*l.Thumbprint, data); err != nil {
+			return nil, err
+		}
+	}
+
+	ret := ResolvedRim{Locator: l, Data: data}
+
+	if isSignedCorim(data) {
+		ret.Signed, err = UnmarshalSignedCorimFromCBOR(data)
+		if err != nil {
+			return nil, fmt.Errorf("decoding signed CoRIM: %w", err)
+		}
+		ret.Unsigned = &ret.Signed.UnsignedCorim
+	} else {
+		ret.Unsigned, err = UnmarshalUnsignedCorimFromCBOR(bytes.TrimPrefix(data, UnsignedCorimTag))
+		if err != nil {
+			return nil, fmt.Errorf("decoding unsigned CoRIM: %w", err)
+		}
+
+		if err := ret.Unsigned.Valid(); err != nil {
+			return nil, fmt.Errorf("failed validation of unsigned CoRIM: %w", err)
+		}
+	}
+
+	return &ret, nil
+}
+
+// isSignedCorim returns true if the supplied data is a COSE_Sign1 (#6.18) or
+// COSE_Sign (#6.98) message, optionally wrapped in a tagged-corim-type-choice
+func isSignedCorim(data []byte) bool {
+	data = bytes.TrimPrefix(data, corimTypeChoiceSigned)
+	return (len(data) > 0 && data[0] == 0xd2) || isCOSESign(data)
+}
+
+// VerifyThumbprint checks that the digest of the supplied data, computed with
+// the thumbprint's hash algorithm, is equal to the thumbprint's value
+func VerifyThumbprint(thumbprint swid.HashEntry, data []byte) error {
+	h, size, err := thumbprintHash(thumbprint.HashAlgID)
+	if err != nil {
+		return err
+	}
+
+	h.Write(data)
+	digest := h.Sum(nil)[:size]
+
+	if subtle.ConstantTimeCompare(digest, thumbprint.HashValue) != 1 {
+		return fmt.Errorf("%w: expected %x, got %x", ErrThumbprintMismatch, thumbprint.HashValue, digest)
+	}
+
+	return nil
+}
+
+// thumbprintHash returns the hash function for the supplied named information
+// hash algorithm, together with the (possibly truncated) size of its output
+func thumbprintHash(algID uint64) (hash.Hash, int, error) {
+	switch algID {
+	case swid.Sha256:
+		return sha256.New(), crypto.SHA256.Size(), nil
+	case swid.Sha256_128:
+		return sha256.New(), 16, nil
+	case swid.Sha256_120:
+		return sha256.New(), 15, nil
+	case swid.Sha256_96:
+		return sha256.New(), 12, nil
+	case swid.Sha256_64:
+		return sha256.New(), 8, nil
+	case swid.Sha256_32:
+		return sha256.New(), 4, nil
+	case swid.Sha384:
+		return sha512.New384(), crypto.SHA384.Size(), nil
+	case swid.Sha512:
+		return sha512.New(), crypto.SHA512.Size(), nil
+	case swid.Sha3_224:
+		return sha3.New224(), crypto.SHA3_224.Size(), nil
+	case swid.Sha3_256:
+		return sha3.New256(), crypto.SHA3_256.Size(), nil
+	case swid.Sha3_384:
+		return sha3.New384(), crypto.SHA3_384.Size(), nil
+	case swid.Sha3_512:
+		return sha3.New512(), crypto.SHA3_512.Size(), nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported thumbprint hash algorithm %d", algID)
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/swid"
+)
+
+// testRim returns the CBOR encoding of an unsigned CoRIM with the supplied ID
+// that depends on the supplied locators
+func testRim(t *testing.T, id string, deps ...Locator) []byte {
+	rim := unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+	require.NotNil(t, rim.SetID(id))
+
+	for _, d := range deps {
+		require.NotNil(t, rim.AddDependentRim(string(d.Href), d.Thumbprint))
+	}
+
+	data, err := rim.ToCBOR()
+	require.NoError(t, err)
+
+	return data
+}
+
+func testSignedRim(t *testing.T, id string, deps ...Locator) []byte {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	var signed SignedCorim
+	require.NoError(t, signed.UnsignedCorim.FromCBOR(testRim(t, id, deps...)))
+	signed.Meta = *metaGood(t)
+
+	data, err := signed.Sign(signer)
+	require.NoError(t, err)
+
+	return data
+}
+
+func sha256Thumbprint(data []byte) *swid.HashEntry {
+	d := sha256.Sum256(data)
+	return &swid.HashEntry{HashAlgID: swid.Sha256, HashValue: d[:]}
+}
+
+type testRimServer struct {
+	*httptest.Server
+	rims map[string][]byte
+}
+
+func newTestRimServer(t *testing.T) *testRimServer {
+	ret := &testRimServer{rims: make(map[string][]byte)}
+
+	ret.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := ret.rims[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(ret.Close)
+
+	return ret
+}
+
+func (o *testRimServer) locator(path string, thumbprint *swid.HashEntry) Locator {
+	return Locator{Href: comid.TaggedURI(o.URL + path), Thumbprint: thumbprint}
+}
+
+func TestDependencyResolver_Resolve_http(t *testing.T) {
+	srv := newTestRimServer(t)
+
+	leaf := testRim(t, "leaf")
+	srv.rims["/leaf.cbor"] = leaf
+
+	// signed, depends on leaf
+	srv.rims["/mid.cbor"] = testSignedRim(t, "mid", srv.locator("/leaf.cbor", sha256Thumbprint(leaf)))
+
+	// root depends on mid and, again, on leaf
+	root := unsignedCorimFromCBOR(t, testRim(t, "root",
+		srv.locator("/mid.cbor", nil),
+		srv.locator("/leaf.cbor", nil),
+	))
+
+	resolver := NewDependencyResolver(HTTPResolver{Client: srv.Client()})
+
+	res, err := resolver.Resolve(context.Background(), root)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+
+	assert.Equal(t, "mid", res[0].Unsigned.GetID())
+	assert.Equal(t, "root", res[0].Parent)
+	assert.Equal(t, 1, res[0].Depth)
+	require.NotNil(t, res[0].Signed)
+	assert.Equal(t, res[0].Unsigned, &res[0].Signed.UnsignedCorim)
+
+	assert.Equal(t, "leaf", res[1].Unsigned.GetID())
+	assert.Equal(t, "mid", res[1].Parent)
+	assert.Equal(t, 2, res[1].Depth)
+	assert.Nil(t, res[1].Signed)
+	assert.Equal(t, leaf, res[1].Data)
+}
+
+func TestDependencyResolver_Resolve_thumbprint_mismatch(t *testing.T) {
+	srv := newTestRimServer(t)
+	srv.rims["/leaf.cbor"] = testRim(t, "leaf")
+
+	root := unsignedCorimFromCBOR(t, testRim(t, "root",
+		srv.locator("/leaf.cbor", sha256Thumbprint([]byte("something else"))),
+	))
+
+	_, err := NewDependencyResolver(HTTPResolver{}).Resolve(context.Background(), root)
+	assert.ErrorIs(t, err, ErrThumbprintMismatch)
+}
+
+func TestDependencyResolver_Resolve_cycle(t *testing.T) {
+	srv := newTestRimServer(t)
+	srv.rims["/a.cbor"] = testRim(t, "a", srv.locator("/b.cbor", nil))
+	srv.rims["/b.cbor"] = testRim(t, "b", srv.locator("/a.cbor", nil))
+
+	root := unsignedCorimFromCBOR(t, testRim(t, "root", srv.locator("/a.cbor", nil)))
+
+	_, err := NewDependencyResolver(HTTPResolver{}).Resolve(context.Background(), root)
+	assert.ErrorIs(t, err, ErrDependencyCycle)
+
+	// a dependent RIM that refers back to the root by corim-id
+	srv.rims["/c.cbor"] = testRim(t, "root")
+	root = unsignedCorimFromCBOR(t, testRim(t, "root", srv.locator("/c.cbor", nil)))
+
+	_, err = NewDependencyResolver(HTTPResolver{}).Resolve(context.Background(), root)
+	assert.ErrorIs(t, err, ErrDependencyCycle)
+}
+
+func TestDependencyResolver_Resolve_max_depth(t *testing.T) {
+	srv := newTestRimServer(t)
+	srv.rims["/3.cbor"] = testRim(t, "3")
+	srv.rims["/2.cbor"] = testRim(t, "2", srv.locator("/3.cbor", nil))
+	srv.rims["/1.cbor"] = testRim(t, "1", srv.locator("/2.cbor", nil))
+
+	root := unsignedCorimFromCBOR(t, testRim(t, "root", srv.locator("/1.cbor", nil)))
+
+	resolver := DependencyResolver{Resolver: HTTPResolver{}, MaxDepth: 2}
+
+	_, err := resolver.Resolve(context.Background(), root)
+	assert.ErrorIs(t, err, ErrMaxDepthExceeded)
+
+	resolver.MaxDepth = 3
+
+	res, err := resolver.Resolve(context.Background(), root)
+	require.NoError(t, err)
+	assert.Len(t, res, 3)
+}
+
+func TestDependencyResolver_Resolve_fetch_errors(t *testing.T) {
+	srv := newTestRimServer(t)
+	// corim-id only, no tags
+	srv.rims["/bad.cbor"] = []byte{0xa1, 0x00, 0x61, 0x78}
+
+	root := unsignedCorimFromCBOR(t, testRim(t, "root", srv.locator("/missing.cbor", nil)))
+	_, err := NewDependencyResolver(HTTPResolver{}).Resolve(context.Background(), root)
+	assert.ErrorContains(t, err, `unexpected status "404 Not Found"`)
+
+	root = unsignedCorimFromCBOR(t, testRim(t, "root", srv.locator("/bad.cbor", nil)))
+	_, err = NewDependencyResolver(HTTPResolver{}).Resolve(context.Background(), root)
+	assert.ErrorContains(t, err, `decoding unsigned CoRIM: missing mandatory field "Tags"`)
+
+	root = unsignedCorimFromCBOR(t, testRim(t, "root", Locator{Href: "ftp://example.com/a.cbor"}))
+	_, err = NewDependencyResolver(HTTPResolver{}).Resolve(context.Background(), root)
+	assert.ErrorIs(t, err, ErrUnsupportedScheme)
+
+	_, err = NewDependencyResolver(nil).Resolve(context.Background(), root)
+	assert.EqualError(t, err, "no locator resolver")
+}
+
+func TestFileResolver_Resolve(t *testing.T) {
+	dir := t.TempDir()
+
+	leaf := testRim(t, "leaf")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "leaf.cbor"), leaf, 0600))
+
+	root := unsignedCorimFromCBOR(t, testRim(t, "root", Locator{
+		Href:       "file:///../../leaf.cbor",
+		Thumbprint: sha256Thumbprint(leaf),
+	}))
+
+	res, err := NewDependencyResolver(FileResolver{BaseDir: dir}).Resolve(context.Background(), root)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	assert.Equal(t, "leaf", res[0].Unsigned.GetID())
+
+	data, err := FileResolver{BaseDir: dir}.Resolve(context.Background(), "leaf.cbor")
+	require.NoError(t, err)
+	assert.Equal(t, leaf, data)
+
+	_, err = FileResolver{BaseDir: dir, MaxSize: 4}.Resolve(context.Background(), "leaf.cbor")
+	assert.EqualError(t, err, "RIM exceeds maximum size of 4 bytes")
+
+	_, err = FileResolver{BaseDir: dir}.Resolve(context.Background(), "https://example.com/leaf.cbor")
+	assert.ErrorIs(t, err, ErrUnsupportedScheme)
+}
+
+func TestFileResolver_Resolve_escape(t *testing.T) {
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret")
+	require.NoError(t, os.WriteFile(secret, []byte("secret"), 0600))
+
+	dir := t.TempDir()
+	require.NoError(t, os.Symlink(secret, filepath.Join(dir, "link.cbor")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "linkdir")))
+
+	// BaseDir is required
+	_, err := FileResolver{}.Resolve(context.Background(), secret)
+	assert.EqualError(t, err, "no base directory")
+
+	_, err = FileResolver{}.Resolve(context.Background(), "file://"+secret)
+	assert.EqualError(t, err, "no base directory")
+
+	r := FileResolver{BaseDir: dir}
+
+	// absolute paths and ".." are confined to BaseDir
+	for _, href := range []string{
+		secret,
+		"file://" + secret,
+		"../" + filepath.Base(outside) + "/secret",
+		"file:///../" + filepath.Base(outside) + "/secret",
+	} {
+		_, err = r.Resolve(context.Background(), href)
+		assert.ErrorIs(t, err, os.ErrNotExist, href)
+	}
+
+	// symbolic links cannot point outside of BaseDir
+	for _, href := range []string{"link.cbor", "file:///link.cbor", "linkdir/secret"} {
+		_, err = r.Resolve(context.Background(), href)
+		assert.ErrorContains(t, err, "is outside of the base directory", href)
+	}
+
+	// but can point within it
+	leaf := testRim(t, "leaf")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "leaf.cbor"), leaf, 0600))
+	require.NoError(t, os.Symlink("leaf.cbor", filepath.Join(dir, "alias.cbor")))
+
+	data, err := r.Resolve(context.Background(), "alias.cbor")
+	require.NoError(t, err)
+	assert.Equal(t, leaf, data)
+
+	// BaseDir may itself be a symbolic link
+	baseLink := filepath.Join(t.TempDir(), "base")
+	require.NoError(t, os.Symlink(dir, baseLink))
+
+	data, err = FileResolver{BaseDir: baseLink}.Resolve(context.Background(), "leaf.cbor")
+	require.NoError(t, err)
+	assert.Equal(t, leaf, data)
+}
+
+func TestVerifyThumbprint(t *testing.T) {
+	data := []byte("corim")
+	d := sha256.Sum256(data)
+
+	assert.NoError(t, VerifyThumbprint(swid.HashEntry{HashAlgID: swid.Sha256_32, HashValue: d[:4]}, data))
+	assert.ErrorIs(t, VerifyThumbprint(swid.HashEntry{HashAlgID: swid.Sha256_32, HashValue: d[1:5]}, data),
+		ErrThumbprintMismatch)
+	assert.EqualError(t, VerifyThumbprint(swid.HashEntry{HashAlgID: 99}, data),
+		"unsupported thumbprint hash algorithm 99")
+}
@@ -7,11 +7,15 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/rand"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
+	"time"
 
 	"github.com/veraison/corim/extensions"
+	"github.com/veraison/eat"
 	cose "github.com/veraison/go-cose"
 )
 
@@ -21,12 +25,44 @@ var (
 	HeaderLabelCorimMeta = int64(8)
 )
 
-// SignedCorim encodes a signed-corim message (i.e., a COSE Sign1 wrapped CoRIM)
-// with signature and verification methods
+// corimTypeChoiceSigned is the tagged-corim-type-choice #6.500 of
+// tagged-signed-corim #6.502 prefix.  This is a remnant of an older draft of
+// the specification before
+// https://github.com/ietf-rats-wg/draft-ietf-rats-corim/pull/337
+var corimTypeChoiceSigned = []byte("\xd9\x01\xf4\xd9\x01\xf6")
+
+// SignedCorim encodes a signed-corim message (i.e., a COSE Sign1 wrapped CoRIM,
+// or a COSE Sign wrapped CoRIM for multiple signers) with signature and
+// verification methods
 type SignedCorim struct {
 	UnsignedCorim UnsignedCorim
 	Meta          Meta
-	message       *cose.Sign1Message
+	// KeyID, if set, is conveyed in the kid header of the signed-corim
+	KeyID []byte
+	// Detached, if set, makes Sign and AttachSignature produce a COSE Sign1
+	// signed-corim with a detached (nil) payload: the unsigned-corim travels
+	// separately, and must be supplied with AttachPayload for verification.
+	// It is set by FromCOSE when decoding such a signed-corim.
+	Detached bool
+	// SigningCert and IntermediateCerts, if set, are conveyed in the x5chain
+	// header of the signed-corim
+	SigningCert       *x509.Certificate
+	IntermediateCerts []*x509.Certificate
+	// Signatures describes the signers of a COSE Sign signed-corim (see
+	// AddSigner).  Meta, KeyID and the certificates above are only used with
+	// COSE Sign1.
+	Signatures []CorimSignature
+	// AlgorithmPolicy, if set, restricts the algorithms and keys accepted on
+	// verification.  It is populated from the profile of a signed-corim
+	// decoded with UnmarshalSignedCorimFromCBOR.
+	AlgorithmPolicy *AlgorithmPolicy
+	// Countersignatures and TimestampToken are carried in the unprotected
+	// header of a COSE Sign1 signed-corim (see AddCountersignature and
+	// AddTimestamp)
+	Countersignatures []Countersignature
+	TimestampToken    []byte
+	message           *cose.Sign1Message
+	signMessage       *cose.SignMessage
 }
 
 // NewSignedCorim instantiates an empty SignedCorim
@@ -35,13 +71,19 @@ func NewSignedCorim() *SignedCorim {
 }
 
 func (o *SignedCorim) RegisterExtensions(exts extensions.Map) error {
+	return registerCorimExtensions(&o.Meta, &o.UnsignedCorim, exts)
+}
+
+// registerCorimExtensions registers the signer extensions with the supplied
+// Meta, and the others with the supplied UnsignedCorim
+func registerCorimExtensions(meta *Meta, unsigned *UnsignedCorim, exts extensions.Map) error {
 	unsignedExts := extensions.NewMap()
 
 	for p, v := range exts {
 		switch p {
 		case ExtSigner:
 			signerExts := extensions.NewMap().Add(ExtSigner, v)
-			if err := o.Meta.RegisterExtensions(signerExts); err != nil {
+			if err := meta.RegisterExtensions(signerExts); err != nil {
 				return err
 			}
 		default:
@@ -49,12 +91,59 @@ func (o *SignedCorim) RegisterExtensions(exts extensions.Map) error {
 		}
 	}
 
-	return o.UnsignedCorim.RegisterExtensions(unsignedExts)
+	return unsigned.RegisterExtensions(unsignedExts)
 }
 
 func (o *SignedCorim) processHdrs() error {
-	var hdr = o.message.Headers
+	meta, kid, err := processCorimHdrs(o.message.Headers)
+	if err != nil {
+		return err
+	}
+
+	o.Meta, o.KeyID = *meta, kid
+
+	if err := o.processX5Chain(); err != nil {
+		return fmt.Errorf("processing x5chain: %w", err)
+	}
+
+	if err := o.processCountersignatures(); err != nil {
+		return fmt.Errorf("processing countersignatures: %w", err)
+	}
+
+	if err := o.processTimestamp(); err != nil {
+		return fmt.Errorf("processing timestamp token: %w", err)
+	}
+
+	return nil
+}
+
+// processCorimHdrs checks the content type, and decodes the corim-meta and
+// kid headers, of a signed or MAC-protected CoRIM
+func processCorimHdrs(hdr cose.Headers) (*Meta, []byte, error) {
+	if err := checkContentType(hdr); err != nil {
+		return nil, nil, err
+	}
+
+	// TODO(tho) key id is apparently mandatory, which doesn't look right.
+	// TODO(tho) Check with the CoRIM design team.
+	// See https://github.com/veraison/corim/issues/14
+
+	meta, err := decodeMetaHdr(hdr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kid, err := decodeKeyIDHdr(hdr)
+	if err != nil {
+		return nil, nil, err
+	}
 
+	return meta, kid, nil
+}
+
+// checkContentType checks that the protected header carries the CoRIM
+// content type
+func checkContentType(hdr cose.Headers) error {
 	if hdr.Protected == nil {
 		return errors.New("missing mandatory protected header")
 	}
@@ -68,28 +157,79 @@ func (o *SignedCorim) processHdrs() error {
 		return fmt.Errorf("expecting content type %q, got %q instead", ContentType, v)
 	}
 
-	// TODO(tho) key id is apparently mandatory, which doesn't look right.
-	// TODO(tho) Check with the CoRIM design team.
-	// See https://github.com/veraison/corim/issues/14
+	return nil
+}
 
-	v, ok = hdr.Protected[HeaderLabelCorimMeta]
+// decodeMetaHdr decodes the corim-meta-map from the protected header
+func decodeMetaHdr(hdr cose.Headers) (*Meta, error) {
+	v, ok := hdr.Protected[HeaderLabelCorimMeta]
 	if !ok {
-		return errors.New("missing mandatory corim.meta")
+		return nil, errors.New("missing mandatory corim.meta")
 	}
 
 	metaCBOR, ok := v.([]byte)
 	if !ok {
-		return fmt.Errorf("expecting CBOR-encoded CoRIM Meta, got %T instead", v)
+		return nil, fmt.Errorf("expecting CBOR-encoded CoRIM Meta, got %T instead", v)
 	}
 
 	var meta Meta
 
 	err := meta.FromCBOR(metaCBOR)
 	if err != nil {
-		return fmt.Errorf("unable to decode CoRIM Meta: %w", err)
+		return nil, fmt.Errorf("unable to decode CoRIM Meta: %w", err)
+	}
+
+	return &meta, nil
+}
+
+// decodeKeyIDHdr returns the kid header, which may be carried in either the
+// protected or the unprotected header bucket, or nil if there is none
+func decodeKeyIDHdr(hdr cose.Headers) ([]byte, error) {
+	v, ok := hdr.Protected[cose.HeaderLabelKeyID]
+	if !ok {
+		v, ok = hdr.Unprotected[cose.HeaderLabelKeyID]
+		if !ok {
+			return nil, nil
+		}
+	}
+
+	kid, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("expecting bstr key id, got %T instead", v)
+	}
+
+	return kid, nil
+}
+
+// AddSigningCert sets the supplied DER-encoded X.509 certificate as the
+// signing certificate, i.e., the first element of the x5chain header.  The
+// certificate must contain the public key matching the signer.
+func (o *SignedCorim) AddSigningCert(der []byte) error {
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return fmt.Errorf("parsing signing certificate: %w", err)
+	}
+
+	o.SigningCert = cert
+
+	return nil
+}
+
+// AddIntermediateCerts appends the supplied DER-encoded X.509 certificates
+// (which may be a concatenation of multiple certificates) to the intermediate
+// certificates in the x5chain header.  The certificates should be ordered from
+// the issuer of the signing certificate towards the root.
+func (o *SignedCorim) AddIntermediateCerts(der []byte) error {
+	certs, err := x509.ParseCertificates(der)
+	if err != nil {
+		return fmt.Errorf("parsing intermediate certificates: %w", err)
+	}
+
+	if len(certs) == 0 {
+		return errors.New("no intermediate certificates found")
 	}
 
-	o.Meta = meta
+	o.IntermediateCerts = append(o.IntermediateCerts, certs...)
 
 	return nil
 }
@@ -97,15 +237,19 @@ func (o *SignedCorim) processHdrs() error {
 // FromCOSE decodes and effects syntactic validation on the supplied
 // signed-corim message, including the embedded unsigned-corim and corim-meta.
 // On success, the unsigned-corim-map is made available via the UnsignedCorim
-// field while the corim-meta-map is decoded into the Meta field.
+// field while the corim-meta-map is decoded into the Meta field.  If the
+// payload of a COSE Sign1 signed-corim is detached, Detached is set and the
+// UnsignedCorim field is only populated by AttachPayload.
 func (o *SignedCorim) FromCOSE(buf []byte) error {
-	o.message = cose.NewSign1Message()
-
 	// If a tagged-corim-type-choice #6.500 of tagged-signed-corim #6.502, strip the prefix.
-	// This is a remnant of an older draft of the specification before
-	// https://github.com/ietf-rats-wg/draft-ietf-rats-corim/pull/337
-	corimTypeChoice := []byte("\xd9\x01\xf4\xd9\x01\xf6")
-	buf, _ = bytes.CutPrefix(buf, corimTypeChoice)
+	buf, _ = bytes.CutPrefix(buf, corimTypeChoiceSigned)
+
+	if isCOSESign(buf) {
+		return o.fromCOSESign(buf)
+	}
+
+	o.message = cose.NewSign1Message()
+	o.signMessage, o.Signatures = nil, nil
 
 	if err := o.message.UnmarshalCBOR(buf); err != nil {
 		return fmt.Errorf("failed CBOR decoding for COSE-Sign1 signed CoRIM: %w", err)
@@ -115,7 +259,74 @@ func (o *SignedCorim) FromCOSE(buf []byte) error {
 		return fmt.Errorf("processing COSE headers: %w", err)
 	}
 
-	if err := o.UnsignedCorim.FromCBOR(o.message.Payload); err != nil {
+	o.Detached = o.message.Payload == nil
+	if o.Detached {
+		return nil
+	}
+
+	return o.decodePayload(o.message.Payload)
+}
+
+// AttachPayload supplies the detached payload, i.e., the encoded
+// unsigned-corim, of the COSE Sign1 signed-corim decoded by FromCOSE.  The
+// payload is decoded into the UnsignedCorim field and used, as is, for the
+// verification of the signature.  If the profile specified by the payload is
+// registered, its extensions are registered before decoding, and its
+// algorithm policy applies unless AlgorithmPolicy is already set.
+func (o *SignedCorim) AttachPayload(payload []byte) error {
+	if o.message == nil {
+		return errors.New("no Sign1 message found")
+	}
+
+	if !o.Detached {
+		return errors.New("payload is not detached")
+	}
+
+	if len(payload) == 0 {
+		return errors.New("empty payload")
+	}
+
+	if err := o.applyPayloadProfile(payload); err != nil {
+		return err
+	}
+
+	if err := o.decodePayload(payload); err != nil {
+		return err
+	}
+
+	o.message.Payload = payload
+
+	return nil
+}
+
+// applyPayloadProfile looks up the profile specified by the supplied payload
+// and, if it is registered, registers its extensions and sets its algorithm
+// policy
+func (o *SignedCorim) applyPayloadProfile(payload []byte) error {
+	profiled := struct {
+		Profile *eat.Profile `cbor:"3,keyasint,omitempty"`
+	}{}
+
+	if err := dm.Unmarshal(payload, &profiled); err != nil {
+		return fmt.Errorf("failed CBOR decoding of unsigned CoRIM: %w", err)
+	}
+
+	profile, ok := GetProfile(profiled.Profile)
+	if !ok {
+		return nil
+	}
+
+	profile.registerExtensions(o, SignedCorimMapExtensionPoints)
+
+	if o.AlgorithmPolicy == nil {
+		o.AlgorithmPolicy = profile.AlgorithmPolicy
+	}
+
+	return nil
+}
+
+func (o *SignedCorim) decodePayload(payload []byte) error {
+	if err := o.UnsignedCorim.FromCBOR(payload); err != nil {
 		return fmt.Errorf("failed CBOR decoding of unsigned CoRIM: %w", err)
 	}
 
@@ -128,59 +339,260 @@ func (o *SignedCorim) FromCOSE(buf []byte) error {
 
 // Sign returns the serialized signed-corim, signed by the supplied cose Signer.
 // The target SignedCorim must have its UnsignedCorim field correctly
-// populated.
+// populated.  Sign is equivalent to PrepareSign followed by AttachSignature
+// with the signature computed by the supplied cose Signer.
 func (o *SignedCorim) Sign(signer cose.Signer) ([]byte, error) {
 	if signer == nil {
 		return nil, errors.New("nil signer")
 	}
 
+	tbs, err := o.PrepareSign(signer.Algorithm())
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := signer.Sign(rand.Reader, tbs)
+	if err != nil {
+		return nil, fmt.Errorf("COSE Sign1 signature failed: %w", err)
+	}
+
+	return o.AttachSignature(sig)
+}
+
+// PrepareSign is the first phase of two-phase signing.  It builds the COSE
+// Sign1 message for the target SignedCorim, which must have its UnsignedCorim
+// field correctly populated, and returns its to-be-signed bytes (i.e., the
+// encoded Sig_structure).  These must be signed with the supplied algorithm,
+// in the format of a cose.Signer (e.g., for ECDSA, the hash of the
+// to-be-signed bytes is signed and the signature is the concatenation of r
+// and s), and the signature passed to AttachSignature.
+func (o *SignedCorim) PrepareSign(alg cose.Algorithm) ([]byte, error) {
 	if err := o.UnsignedCorim.Valid(); err != nil {
 		return nil, fmt.Errorf("failed validation of unsigned CoRIM: %w", err)
 	}
 
-	o.message = cose.NewSign1Message()
-
-	var err error
-	o.message.Payload, err = o.UnsignedCorim.ToCBOR()
+	payload, err := o.UnsignedCorim.ToCBOR()
 	if err != nil {
 		return nil, fmt.Errorf("failed CBOR encoding of unsigned CoRIM: %w", err)
 	}
 
+	return o.prepareSign1(alg, payload)
+}
+
+// prepareSign1 builds the COSE Sign1 message over the supplied (encoded)
+// payload and returns its to-be-signed bytes
+func (o *SignedCorim) prepareSign1(alg cose.Algorithm, payload []byte) ([]byte, error) {
+	o.message = cose.NewSign1Message()
+	o.signMessage, o.Signatures = nil, nil
+	o.Countersignatures, o.TimestampToken = nil, nil
+
+	o.message.Payload = payload
+
 	metaCBOR, err := o.Meta.ToCBOR()
 	if err != nil {
 		return nil, fmt.Errorf("failed CBOR encoding of CoRIM Meta: %w", err)
 	}
 
-	alg := signer.Algorithm()
-
-	if strings.Contains(alg.String(), "unknown algorithm value") {
-		return nil, errors.New("signer has no algorithm")
+	if err := checkAlgorithm(alg); err != nil {
+		return nil, err
 	}
 
 	o.message.Headers.Protected.SetAlgorithm(alg)
 	o.message.Headers.Protected[cose.HeaderLabelContentType] = ContentType
 	o.message.Headers.Protected[HeaderLabelCorimMeta] = metaCBOR
 
-	err = o.message.Sign(rand.Reader, NoExternalData, signer)
+	if len(o.KeyID) != 0 {
+		o.message.Headers.Protected[cose.HeaderLabelKeyID] = o.KeyID
+	}
+
+	if x5chain := o.x5chain(); x5chain != nil {
+		o.message.Headers.Protected[cose.HeaderLabelX5Chain] = x5chain
+	} else if len(o.IntermediateCerts) != 0 {
+		return nil, errors.New("intermediate certificates set without a signing certificate")
+	}
+
+	// go-cose does not expose the to-be-signed bytes, so they are captured
+	// by a signer that does not sign
+	capture := tbsCapture{alg: alg}
+
+	if err := o.message.Sign(rand.Reader, NoExternalData, &capture); err != nil {
+		return nil, fmt.Errorf("computing to-be-signed bytes: %w", err)
+	}
+
+	o.message.Signature = nil
+
+	return capture.tbs, nil
+}
+
+// AttachSignature is the second phase of two-phase signing.  It sets the
+// supplied signature, computed over the to-be-signed bytes returned by
+// PrepareSign, and returns the serialized signed-corim.  The signature is not
+// verified.
+func (o *SignedCorim) AttachSignature(sig []byte) ([]byte, error) {
+	if o.message == nil || len(o.message.Signature) != 0 {
+		return nil, errors.New("no prepared Sign1 message found")
+	}
+
+	if len(sig) == 0 {
+		return nil, errors.New("empty signature")
+	}
+
+	o.message.Signature = sig
+
+	wrap, err := o.marshalSign1()
 	if err != nil {
-		return nil, fmt.Errorf("COSE Sign1 signature failed: %w", err)
+		o.message.Signature = nil
+		return nil, fmt.Errorf("signed-corim marshaling failed: %w", err)
 	}
 
-	wrap, err := o.message.MarshalCBOR()
+	return wrap, nil
+}
+
+// ToCOSE returns the serialized signed-corim held by the target SignedCorim,
+// which must have been signed or decoded, including any unprotected header
+// added since
+func (o *SignedCorim) ToCOSE() ([]byte, error) {
+	if o.signMessage != nil {
+		return o.signMessage.MarshalCBOR()
+	}
+
+	if err := o.checkSigned(); err != nil {
+		return nil, err
+	}
+
+	return o.marshalSign1()
+}
+
+// marshalSign1 returns the serialized COSE Sign1 message, with a nil payload
+// if the target SignedCorim is detached
+func (o *SignedCorim) marshalSign1() ([]byte, error) {
+	if !o.Detached {
+		return o.message.MarshalCBOR()
+	}
+
+	payload := o.message.Payload
+	o.message.Payload = nil
+
+	defer func() { o.message.Payload = payload }()
+
+	return o.message.MarshalCBOR()
+}
+
+// UnprotectedHeader returns the value, as decoded by go-cose, of the supplied
+// unprotected header of the signed COSE Sign1 message, if present
+func (o *SignedCorim) UnprotectedHeader(label int64) (any, bool) {
+	if o.message == nil {
+		return nil, false
+	}
+
+	v, ok := o.message.Headers.Unprotected[label]
+
+	return v, ok
+}
+
+// SetUnprotectedHeader sets the supplied unprotected header of the signed COSE
+// Sign1 message held by the target SignedCorim, and returns the serialized
+// signed-corim.  This allows adding headers, such as transparency receipts,
+// that are not covered by the signature.  The headers decoded into the
+// SignedCorim fields (kid, x5chain, countersignature and timestamp token)
+// cannot be set.
+func (o *SignedCorim) SetUnprotectedHeader(label int64, v any) ([]byte, error) {
+	if err := o.checkSigned(); err != nil {
+		return nil, err
+	}
+
+	switch label {
+	case cose.HeaderLabelKeyID, cose.HeaderLabelX5Chain, HeaderLabelCountersignature, HeaderLabelTimestampToken:
+		return nil, fmt.Errorf("header %d cannot be set directly", label)
+	}
+
+	return o.setUnprotectedHdr(label, v)
+}
+
+// setUnprotectedHdr sets the supplied unprotected header of the signed Sign1
+// message and returns the serialized signed-corim
+func (o *SignedCorim) setUnprotectedHdr(label int64, v any) ([]byte, error) {
+	hdr := o.message.Headers.Unprotected
+	if hdr == nil {
+		hdr = cose.UnprotectedHeader{}
+	}
+
+	prev, hadPrev := hdr[label]
+	hdr[label] = v
+
+	// the raw unprotected header of a decoded message takes precedence on
+	// encoding
+	raw := o.message.Headers.RawUnprotected
+	o.message.Headers.Unprotected, o.message.Headers.RawUnprotected = hdr, nil
+
+	wrap, err := o.marshalSign1()
 	if err != nil {
+		if hadPrev {
+			hdr[label] = prev
+		} else {
+			delete(hdr, label)
+		}
+		o.message.Headers.RawUnprotected = raw
+
 		return nil, fmt.Errorf("signed-corim marshaling failed: %w", err)
 	}
 
 	return wrap, nil
 }
 
+// tbsCapture is a cose.Signer that records the content it is asked to sign
+type tbsCapture struct {
+	alg cose.Algorithm
+	tbs []byte
+}
+
+func (o *tbsCapture) Algorithm() cose.Algorithm {
+	return o.alg
+}
+
+func (o *tbsCapture) Sign(_ io.Reader, content []byte) ([]byte, error) {
+	o.tbs = content
+	return []byte{0}, nil
+}
+
+func signerAlgorithm(signer cose.Signer) (cose.Algorithm, error) {
+	alg := signer.Algorithm()
+	return alg, checkAlgorithm(alg)
+}
+
+func checkAlgorithm(alg cose.Algorithm) error {
+	if _, ok := rsaPKCS1v15Hash(alg); ok {
+		return nil
+	}
+
+	if strings.Contains(alg.String(), "unknown algorithm value") {
+		return errors.New("signer has no algorithm")
+	}
+
+	return nil
+}
+
 // Verify verifies the signature of the target SignedCorim object using the
-// supplied public key
+// supplied public key.  The algorithm and the key must be accepted by the
+// AlgorithmPolicy, if set.
 func (o *SignedCorim) Verify(pk crypto.PublicKey) error {
+	return o.verify(pk, o.AlgorithmPolicy)
+}
+
+// verify verifies the signature of the target SignedCorim object using the
+// supplied public key, which must be accepted by the supplied policy, if set
+func (o *SignedCorim) verify(pk crypto.PublicKey, policy *AlgorithmPolicy) error {
 	if o.message == nil {
+		if o.signMessage != nil {
+			return errors.New("COSE Sign signed CoRIM: use VerifySigners")
+		}
 		return errors.New("no Sign1 message found")
 	}
 
+	if o.message.Payload == nil {
+		return errors.New("detached payload not attached")
+	}
+
 	protected := o.message.Headers.Protected
 
 	alg, err := protected.Algorithm()
@@ -188,7 +600,11 @@ func (o *SignedCorim) Verify(pk crypto.PublicKey) error {
 		return fmt.Errorf("unable to get verification algorithm: %w", err)
 	}
 
-	verifier, err := cose.NewVerifier(alg, pk)
+	if err := policy.Check(alg, pk); err != nil {
+		return err
+	}
+
+	verifier, err := newVerifier(alg, pk)
 	if err != nil {
 		return fmt.Errorf("unable to instantiate verifier: %w", err)
 	}
@@ -200,3 +616,87 @@ func (o *SignedCorim) Verify(pk crypto.PublicKey) error {
 
 	return nil
 }
+
+// VerifyOptions controls the checks made by VerifyWithOptions in addition to
+// signature verification
+type VerifyOptions struct {
+	// Clock returns the current time.  If nil, time.Now is used.
+	Clock func() time.Time
+	// Skew is the tolerated clock skew, applied to both ends of the
+	// validity periods
+	Skew time.Duration
+	// ExtKeyUsages are the extended key usages acceptable for the signing
+	// certificate in VerifyChain.  If unset, any usage is accepted.
+	ExtKeyUsages []x509.ExtKeyUsage
+	// AlgorithmPolicy, if set, is the verifier's own policy, which overrides
+	// the AlgorithmPolicy of the SignedCorim (e.g., from its profile), so
+	// that CoRIMs with no (or an unregistered) profile are also restricted
+	AlgorithmPolicy *AlgorithmPolicy
+}
+
+func (o VerifyOptions) now() time.Time {
+	if o.Clock == nil {
+		return time.Now()
+	}
+	return o.Clock()
+}
+
+// policy returns the options' AlgorithmPolicy, if set, or else the supplied
+// one
+func (o VerifyOptions) policy(def *AlgorithmPolicy) *AlgorithmPolicy {
+	if o.AlgorithmPolicy != nil {
+		return o.AlgorithmPolicy
+	}
+	return def
+}
+
+// VerifyWithOptions verifies the signature of the target SignedCorim object
+// using the supplied public key and, if the signature is good, checks that the
+// current time is within the validity period of both the signature (from the
+// corim-meta-map) and the CoRIM (from the unsigned-corim-map), if present.  A
+// *ValidityError is returned if either check fails.  The options'
+// AlgorithmPolicy, if set, applies instead of the SignedCorim's.
+func (o *SignedCorim) VerifyWithOptions(pk crypto.PublicKey, opts VerifyOptions) error {
+	if err := o.verify(pk, opts.policy(o.AlgorithmPolicy)); err != nil {
+		return err
+	}
+
+	return o.CheckValidity(opts.now(), opts.Skew)
+}
+
+// CheckValidity checks that the supplied time is within the validity periods
+// of the signature and of the CoRIM, with the supplied clock skew tolerance.
+// Periods that are not set are not checked.  A *ValidityError is returned if
+// either check fails, the signature validity being checked first.
+func (o *SignedCorim) CheckValidity(now time.Time, skew time.Duration) error {
+	return checkValidity(o.Meta.Validity, o.UnsignedCorim.RimValidity, now, skew)
+}
+
+// checkValidity checks the supplied signature (or MAC) and CoRIM validity
+// periods, in that order
+func checkValidity(sigValidity, rimValidity *Validity, now time.Time, skew time.Duration) error {
+	checks := []struct {
+		scope    ValidityScope
+		validity *Validity
+	}{
+		{ValiditySignature, sigValidity},
+		{ValidityRim, rimValidity},
+	}
+
+	for _, c := range checks {
+		if c.validity == nil {
+			continue
+		}
+
+		if err := c.validity.Check(now, skew); err != nil {
+			return &ValidityError{
+				Scope:    c.scope,
+				Validity: *c.validity,
+				Now:      now,
+				Err:      err,
+			}
+		}
+	}
+
+	return nil
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/veraison/corim/extensions"
+	"github.com/veraison/eat"
+	cose "github.com/veraison/go-cose"
 )
 
 var (
@@ -144,7 +146,7 @@ func TestSignedCorim_FromCOSE_ok(t *testing.T) {
 		0x6d, 0x65, 0x6e, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2d, 0x69, 0x64,
 		0x2d, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x31, 0x01, 0x64,
 		0x41, 0x43, 0x4d, 0x45, 0x02, 0x6a, 0x52, 0x6f, 0x61, 0x64, 0x52, 0x75,
-		0x6e, 0x6e, 0x65, 0x72, 0x83, 0xa2, 0x00, 0xd9, 0x02, 0x58, 0xa3, 0x01,
+		0x6e, 0x6e, 0x65, 0x72, 0x83, 0xa2, 0x00, 0xd9, 0x02, 0x59, 0xa3, 0x01,
 		0x62, 0x42, 0x4c, 0x04, 0x65, 0x32, 0x2e, 0x31, 0x2e, 0x30, 0x05, 0x58,
 		0x20, 0xac, 0xbb, 0x11, 0xc7, 0xe4, 0xda, 0x21, 0x72, 0x05, 0x52, 0x3c,
 		0xe4, 0xce, 0x1a, 0x24, 0x5a, 0xe1, 0xa2, 0x39, 0xae, 0x3c, 0x6b, 0xfd,
@@ -152,7 +154,7 @@ func TestSignedCorim_FromCOSE_ok(t *testing.T) {
 		0x81, 0x82, 0x01, 0x58, 0x20, 0x87, 0x42, 0x8f, 0xc5, 0x22, 0x80, 0x3d,
 		0x31, 0x06, 0x5e, 0x7b, 0xce, 0x3c, 0xf0, 0x3f, 0xe4, 0x75, 0x09, 0x66,
 		0x31, 0xe5, 0xe0, 0x7b, 0xbd, 0x7a, 0x0f, 0xde, 0x60, 0xc4, 0xcf, 0x25,
-		0xc7, 0xa2, 0x00, 0xd9, 0x02, 0x58, 0xa3, 0x01, 0x64, 0x50, 0x52, 0x6f,
+		0xc7, 0xa2, 0x00, 0xd9, 0x02, 0x59, 0xa3, 0x01, 0x64, 0x50, 0x52, 0x6f,
 		0x54, 0x04, 0x65, 0x31, 0x2e, 0x33, 0x2e, 0x35, 0x05, 0x58, 0x20, 0xac,
 		0xbb, 0x11, 0xc7, 0xe4, 0xda, 0x21, 0x72, 0x05, 0x52, 0x3c, 0xe4, 0xce,
 		0x1a, 0x24, 0x5a, 0xe1, 0xa2, 0x39, 0xae, 0x3c, 0x6b, 0xfd, 0x9e, 0x78,
@@ -160,7 +162,7 @@ func TestSignedCorim_FromCOSE_ok(t *testing.T) {
 		0x01, 0x58, 0x20, 0x02, 0x63, 0x82, 0x99, 0x89, 0xb6, 0xfd, 0x95, 0x4f,
 		0x72, 0xba, 0xaf, 0x2f, 0xc6, 0x4b, 0xc2, 0xe2, 0xf0, 0x1d, 0x69, 0x2d,
 		0x4d, 0xe7, 0x29, 0x86, 0xea, 0x80, 0x8f, 0x6e, 0x99, 0x81, 0x3f, 0xa2,
-		0x00, 0xd9, 0x02, 0x58, 0xa3, 0x01, 0x64, 0x41, 0x52, 0x6f, 0x54, 0x04,
+		0x00, 0xd9, 0x02, 0x59, 0xa3, 0x01, 0x64, 0x41, 0x52, 0x6f, 0x54, 0x04,
 		0x65, 0x30, 0x2e, 0x31, 0x2e, 0x34, 0x05, 0x58, 0x20, 0xac, 0xbb, 0x11,
 		0xc7, 0xe4, 0xda, 0x21, 0x72, 0x05, 0x52, 0x3c, 0xe4, 0xce, 0x1a, 0x24,
 		0x5a, 0xe1, 0xa2, 0x39, 0xae, 0x3c, 0x6b, 0xfd, 0x9e, 0x78, 0x71, 0xf7,
@@ -230,7 +232,7 @@ func TestSignedCorim_TaggedFromCOSE_ok(t *testing.T) {
 		0x30, 0x30, 0x30, 0x30, 0x31, 0x01, 0x64, 0x41, 0x43,
 		0x4d, 0x45, 0x02, 0x6a, 0x52, 0x6f, 0x61, 0x64, 0x52,
 		0x75, 0x6e, 0x6e, 0x65, 0x72, 0x83, 0xa2, 0x00, 0xd9,
-		0x02, 0x58, 0xa3, 0x01, 0x62, 0x42, 0x4c, 0x04, 0x65,
+		0x02, 0x59, 0xa3, 0x01, 0x62, 0x42, 0x4c, 0x04, 0x65,
 		0x32, 0x2e, 0x31, 0x2e, 0x30, 0x05, 0x58, 0x20, 0xac,
 		0xbb, 0x11, 0xc7, 0xe4, 0xda, 0x21, 0x72, 0x05, 0x52,
 		0x3c, 0xe4, 0xce, 0x1a, 0x24, 0x5a, 0xe1, 0xa2, 0x39,
@@ -240,7 +242,7 @@ func TestSignedCorim_TaggedFromCOSE_ok(t *testing.T) {
 		0x3d, 0x31, 0x06, 0x5e, 0x7b, 0xce, 0x3c, 0xf0, 0x3f,
 		0xe4, 0x75, 0x09, 0x66, 0x31, 0xe5, 0xe0, 0x7b, 0xbd,
 		0x7a, 0x0f, 0xde, 0x60, 0xc4, 0xcf, 0x25, 0xc7, 0xa2,
-		0x00, 0xd9, 0x02, 0x58, 0xa3, 0x01, 0x64, 0x50, 0x52,
+		0x00, 0xd9, 0x02, 0x59, 0xa3, 0x01, 0x64, 0x50, 0x52,
 		0x6f, 0x54, 0x04, 0x65, 0x31, 0x2e, 0x33, 0x2e, 0x35,
 		0x05, 0x58, 0x20, 0xac, 0xbb, 0x11, 0xc7, 0xe4, 0xda,
 		0x21, 0x72, 0x05, 0x52, 0x3c, 0xe4, 0xce, 0x1a, 0x24,
@@ -250,7 +252,7 @@ func TestSignedCorim_TaggedFromCOSE_ok(t *testing.T) {
 		0x82, 0x99, 0x89, 0xb6, 0xfd, 0x95, 0x4f, 0x72, 0xba,
 		0xaf, 0x2f, 0xc6, 0x4b, 0xc2, 0xe2, 0xf0, 0x1d, 0x69,
 		0x2d, 0x4d, 0xe7, 0x29, 0x86, 0xea, 0x80, 0x8f, 0x6e,
-		0x99, 0x81, 0x3f, 0xa2, 0x00, 0xd9, 0x02, 0x58, 0xa3,
+		0x99, 0x81, 0x3f, 0xa2, 0x00, 0xd9, 0x02, 0x59, 0xa3,
 		0x01, 0x64, 0x41, 0x52, 0x6f, 0x54, 0x04, 0x65, 0x30,
 		0x2e, 0x31, 0x2e, 0x34, 0x05, 0x58, 0x20, 0xac, 0xbb,
 		0x11, 0xc7, 0xe4, 0xda, 0x21, 0x72, 0x05, 0x52, 0x3c,
@@ -490,6 +492,81 @@ func TestSignedCorim_SignVerify_fail_tampered(t *testing.T) {
 	assert.EqualError(t, err, "verification error")
 }
 
+func TestSignedCorim_VerifyWithOptions(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	pk, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	var (
+		metaNotBefore = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+		metaNotAfter  = time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+		rimNotAfter   = time.Date(2021, time.July, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	var SignedCorimIn SignedCorim
+
+	SignedCorimIn.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+	require.NotNil(t, SignedCorimIn.UnsignedCorim.SetRimValidity(rimNotAfter, nil))
+	SignedCorimIn.Meta = *metaGood(t)
+	require.NotNil(t, SignedCorimIn.Meta.SetValidity(metaNotAfter, &metaNotBefore))
+
+	cbor, err := SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	var SignedCorimOut SignedCorim
+
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+
+	tvs := []struct {
+		now   time.Time
+		skew  time.Duration
+		err   error
+		scope ValidityScope
+	}{
+		{now: time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{now: metaNotBefore.Add(-time.Minute), err: ErrNotYetValid, scope: ValiditySignature},
+		{now: metaNotBefore.Add(-time.Minute), skew: 5 * time.Minute},
+		{now: rimNotAfter.Add(time.Hour), err: ErrExpired, scope: ValidityRim},
+		{now: rimNotAfter.Add(time.Hour), skew: 2 * time.Hour},
+		{now: metaNotAfter.Add(time.Second), err: ErrExpired, scope: ValiditySignature},
+	}
+
+	for _, tv := range tvs {
+		now := tv.now
+		err := SignedCorimOut.VerifyWithOptions(pk, VerifyOptions{
+			Clock: func() time.Time { return now },
+			Skew:  tv.skew,
+		})
+
+		if tv.err == nil {
+			assert.NoError(t, err, "now: %s", now)
+			continue
+		}
+
+		assert.ErrorIs(t, err, tv.err, "now: %s", now)
+
+		var verr *ValidityError
+		require.ErrorAs(t, err, &verr)
+		assert.Equal(t, tv.scope, verr.Scope)
+		assert.Equal(t, now, verr.Now)
+	}
+
+	err = SignedCorimOut.VerifyWithOptions(pk, VerifyOptions{
+		Clock: func() time.Time { return metaNotAfter.Add(time.Second) },
+	})
+	assert.EqualError(t, err, "signature validity: expired at 2022-01-01T00:00:01Z")
+
+	// signature verification takes precedence over validity checks
+	other, err := NewPublicKeyFromJWK(testES384Key)
+	require.NoError(t, err)
+
+	err = SignedCorimOut.VerifyWithOptions(other, VerifyOptions{})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrExpired)
+}
+
 func TestSignedCorim_Sign_fail_bad_corim(t *testing.T) {
 	signer, err := NewSignerFromJWK(testES256Key)
 	require.NoError(t, err)
@@ -530,3 +607,192 @@ func TestSignedCorim_extensions(t *testing.T) {
 	err = s.RegisterExtensions(badMap)
 	assert.EqualError(t, err, `unexpected extension point: "test"`)
 }
+
+func TestSignedCorim_SetUnprotectedHeader(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	pk, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	var SignedCorimIn SignedCorim
+
+	_, err = SignedCorimIn.ToCOSE()
+	assert.EqualError(t, err, "no Sign1 message found")
+
+	_, err = SignedCorimIn.SetUnprotectedHeader(-70000, "x")
+	assert.EqualError(t, err, "no Sign1 message found")
+
+	SignedCorimIn.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	signed, err := SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	cbor, err := SignedCorimIn.ToCOSE()
+	require.NoError(t, err)
+	assert.Equal(t, signed, cbor)
+
+	cbor, err = SignedCorimIn.SetUnprotectedHeader(-70000, []byte("x"))
+	require.NoError(t, err)
+
+	var SignedCorimOut SignedCorim
+
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+	assert.NoError(t, SignedCorimOut.Verify(pk))
+
+	v, ok := SignedCorimOut.UnprotectedHeader(-70000)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("x"), v)
+
+	_, ok = SignedCorimOut.UnprotectedHeader(-70001)
+	assert.False(t, ok)
+
+	_, err = SignedCorimOut.SetUnprotectedHeader(HeaderLabelTimestampToken, []byte("x"))
+	assert.EqualError(t, err, "header 270 cannot be set directly")
+}
+
+func TestSignedCorim_Sign_Verify_detached(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	pk, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{
+		UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR),
+		Meta:          *metaGood(t),
+		Detached:      true,
+	}
+
+	cbor, err := SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	// the signature can be verified straight away
+	assert.NoError(t, SignedCorimIn.Verify(pk))
+
+	payload, err := SignedCorimIn.UnsignedCorim.ToCBOR()
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(cbor), string(payload))
+
+	SignedCorimOut, err := UnmarshalSignedCorimFromCBOR(cbor)
+	require.NoError(t, err)
+	assert.True(t, SignedCorimOut.Detached)
+	assert.Equal(t, "ACME Ltd.", SignedCorimOut.Meta.Signer.Name)
+
+	err = SignedCorimOut.Verify(pk)
+	assert.EqualError(t, err, "detached payload not attached")
+
+	require.NoError(t, SignedCorimOut.AttachPayload(payload))
+	assert.Equal(t, SignedCorimIn.UnsignedCorim.GetID(), SignedCorimOut.UnsignedCorim.GetID())
+	assert.NoError(t, SignedCorimOut.Verify(pk))
+
+	// the signed-corim is still serialized without its payload
+	out, err := SignedCorimOut.ToCOSE()
+	require.NoError(t, err)
+	assert.Equal(t, cbor, out)
+
+	SignedCorimOut, err = UnmarshalDetachedSignedCorimFromCBOR(cbor, payload)
+	require.NoError(t, err)
+	assert.NoError(t, SignedCorimOut.Verify(pk))
+
+	// another payload does not verify
+	other := SignedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+	other.UnsignedCorim.SetID("other")
+
+	otherPayload, err := other.UnsignedCorim.ToCBOR()
+	require.NoError(t, err)
+
+	SignedCorimOut, err = UnmarshalDetachedSignedCorimFromCBOR(cbor, otherPayload)
+	require.NoError(t, err)
+	assert.EqualError(t, SignedCorimOut.Verify(pk), "verification error")
+}
+
+func TestSignedCorim_detached_fail(t *testing.T) {
+	var SignedCorimIn SignedCorim
+
+	err := SignedCorimIn.AttachPayload(testGoodUnsignedCorimCBOR)
+	assert.EqualError(t, err, "no Sign1 message found")
+
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	cbor, err := SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	err = SignedCorimIn.AttachPayload(testGoodUnsignedCorimCBOR)
+	assert.EqualError(t, err, "payload is not detached")
+
+	_, err = UnmarshalDetachedSignedCorimFromCBOR(cbor, testGoodUnsignedCorimCBOR)
+	assert.EqualError(t, err, "payload is not detached")
+
+	SignedCorimIn.Detached = true
+
+	cbor, err = SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	var SignedCorimOut SignedCorim
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+
+	err = SignedCorimOut.AttachPayload(nil)
+	assert.EqualError(t, err, "empty payload")
+
+	err = SignedCorimOut.AttachPayload([]byte{0xa0})
+	assert.ErrorContains(t, err, "failed CBOR decoding of unsigned CoRIM")
+
+	// countersignatures cover the payload
+	_, err = SignedCorimOut.AddCountersignature(signer, nil)
+	assert.EqualError(t, err, "detached payload not attached")
+
+	_, err = (&SignedCorim{Detached: true}).AddSigner(signer, *NewMeta(), nil)
+	assert.EqualError(t, err, "detached payload is only supported with COSE Sign1")
+}
+
+func TestSignedCorim_AttachPayload_profile(t *testing.T) {
+	profileID, err := eat.NewProfile("http://example.com/detached-profile")
+	require.NoError(t, err)
+
+	policy := &AlgorithmPolicy{Algorithms: []cose.Algorithm{cose.AlgorithmES384}}
+
+	require.NoError(t, RegisterProfileWithAlgorithmPolicy(profileID, nil, policy))
+	defer UnregisterProfile(profileID)
+
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	pk, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{
+		UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR),
+		Detached:      true,
+	}
+	SignedCorimIn.UnsignedCorim.Profile = profileID
+
+	cbor, err := SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	payload, err := SignedCorimIn.UnsignedCorim.ToCBOR()
+	require.NoError(t, err)
+
+	// the profile is only known once the payload is attached
+	SignedCorimOut, err := UnmarshalSignedCorimFromCBOR(cbor)
+	require.NoError(t, err)
+	assert.Nil(t, SignedCorimOut.AlgorithmPolicy)
+
+	require.NoError(t, SignedCorimOut.AttachPayload(payload))
+	assert.Equal(t, profileID, SignedCorimOut.UnsignedCorim.Profile)
+	assert.Equal(t, policy, SignedCorimOut.AlgorithmPolicy)
+
+	err = SignedCorimOut.Verify(pk)
+	assert.EqualError(t, err, "rejected by algorithm policy: algorithm ES256 is not allowed")
+
+	SignedCorimOut, err = UnmarshalDetachedSignedCorimFromCBOR(cbor, payload)
+	require.NoError(t, err)
+	assert.Equal(t, policy, SignedCorimOut.AlgorithmPolicy)
+
+	err = SignedCorimOut.Verify(pk)
+	assert.EqualError(t, err, "rejected by algorithm policy: algorithm ES256 is not allowed")
+}
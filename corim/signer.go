@@ -189,11 +189,34 @@ func NewSignerFromJWK(j []byte) (cose.Signer, error) {
 	return cose.NewSigner(alg, key)
 }
 
+// NewPublicKeyFromJWK returns the public key from the supplied JWK, which may
+// contain either a private key or a public key
 func NewPublicKeyFromJWK(j []byte) (crypto.PublicKey, error) {
-	_, key, err := getAlgAndKeyFromJWK(j)
+	k, err := jwk.ParseKey(j)
 	if err != nil {
 		return nil, err
 	}
 
-	return key.Public(), nil
+	pub, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var key crypto.PublicKey
+
+	if err := pub.Raw(&key); err != nil {
+		return nil, err
+	}
+
+	switch v := key.(type) {
+	case *ecdsa.PublicKey:
+		if ellipticCurveToAlg(v.Curve) == noAlg {
+			return nil, fmt.Errorf("unknown elliptic curve %v", v.Curve.Params().Name)
+		}
+	case ed25519.PublicKey, *rsa.PublicKey:
+	default:
+		return nil, fmt.Errorf("unknown public key type %v", reflect.TypeOf(key))
+	}
+
+	return key, nil
 }
@@ -0,0 +1,529 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/cots"
+	"github.com/veraison/eat"
+	"github.com/veraison/swid"
+)
+
+// CBOR major types
+const (
+	cborUnsignedInt = 0
+	cborByteString  = 2
+	cborTextString  = 3
+	cborArray       = 4
+	cborMap         = 5
+	cborTag         = 6
+)
+
+// maxNestedLevels is the maximum nesting depth of the CBOR data items read by
+// a TagReader (the same as the default of the CBOR decoder)
+const maxNestedLevels = 32
+
+// keys of the unsigned-corim-map
+const (
+	corimIDKey   = 0
+	corimTagsKey = 1
+)
+
+var (
+	emptyCBORArray   = []byte{0x80}
+	errNotFullyRead  = errors.New("unsigned CoRIM not fully read")
+	errTagWriterDone = errors.New("tag writer already closed")
+)
+
+// TagReader reads the tags of an unsigned-corim from an io.Reader one at a
+// time, so that large CoRIMs can be processed without holding the whole
+// payload in memory.  Tags are neither decoded nor validated: see Tag.Decode
+// and Tag.Valid.  The other fields of the unsigned-corim-map are decoded
+// into an UnsignedCorim, which is made available once all the tags have been
+// read.  Note that in a CoRIM with the usual (i.e., deterministic) encoding,
+// the profile and other fields following the tags are only read after them.
+type TagReader struct {
+	r *bufio.Reader
+	// pairs is the number of entries of the unsigned-corim-map yet to be
+	// read
+	pairs uint64
+	// tags is the number of tags yet to be read from the tags array
+	tags uint64
+	// nTags is the number of tags read so far
+	nTags    uint64
+	seenTags bool
+	header   bytes.Buffer
+	nHeader  uint64
+	corim    *UnsignedCorim
+	err      error
+}
+
+// NewTagReader returns a TagReader for the unsigned-corim, optionally tagged
+// with #6.501, read from r
+func NewTagReader(r io.Reader) (*TagReader, error) {
+	o := &TagReader{r: bufio.NewReader(r)}
+
+	prefix, err := o.r.Peek(len(UnsignedCorimTag))
+	if err == nil && bytes.Equal(prefix, UnsignedCorimTag) {
+		if _, err := o.r.Discard(len(UnsignedCorimTag)); err != nil {
+			return nil, err
+		}
+	}
+
+	major, n, err := readHead(o.r, nil)
+	if err != nil {
+		return nil, fmt.Errorf("reading unsigned-corim-map: %w", noEOF(err))
+	}
+
+	if major != cborMap {
+		return nil, fmt.Errorf("expecting unsigned-corim-map, got CBOR major type %d", major)
+	}
+
+	o.pairs = n
+
+	return o, nil
+}
+
+// Next returns the next tag of the unsigned-corim.  io.EOF is returned once
+// all the tags have been read, unless the unsigned-corim has no tags (i.e.,
+// no tags entry or an empty tags array), which is an error.
+func (o *TagReader) Next() (Tag, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	t, err := o.next()
+	if err != nil {
+		o.err = err
+	}
+
+	return t, err
+}
+
+func (o *TagReader) next() (Tag, error) {
+	for o.tags == 0 {
+		if o.pairs == 0 {
+			if o.nTags == 0 {
+				return nil, errors.New("tags validation failed: no tags")
+			}
+
+			if err := o.decodeHeader(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+
+		if err := o.readEntry(); err != nil {
+			return nil, err
+		}
+	}
+
+	major, n, err := readHead(o.r, nil)
+	if err != nil {
+		return nil, fmt.Errorf("reading tag: %w", noEOF(err))
+	}
+
+	if major != cborByteString {
+		return nil, fmt.Errorf("expecting tag as CBOR byte string, got CBOR major type %d", major)
+	}
+
+	if n > math.MaxInt64 {
+		return nil, fmt.Errorf("tag length %d too large", n)
+	}
+
+	t, err := io.ReadAll(io.LimitReader(o.r, int64(n)))
+	if err != nil {
+		return nil, fmt.Errorf("reading tag: %w", err)
+	}
+
+	if uint64(len(t)) != n {
+		return nil, fmt.Errorf("reading tag: %w", io.ErrUnexpectedEOF)
+	}
+
+	o.tags--
+	o.nTags++
+
+	return t, nil
+}
+
+// readEntry reads the next entry of the unsigned-corim-map.  The tags entry is
+// positioned on its first tag, while any other entry is buffered into the
+// header.
+func (o *TagReader) readEntry() error {
+	var key bytes.Buffer
+
+	if err := readItem(o.r, &key, 0); err != nil {
+		return fmt.Errorf("reading unsigned-corim-map key: %w", noEOF(err))
+	}
+
+	o.pairs--
+
+	if k, ok := cborUint(key.Bytes()); !ok || k != corimTagsKey {
+		o.header.Write(key.Bytes())
+
+		if err := readItem(o.r, &o.header, 0); err != nil {
+			return fmt.Errorf("reading unsigned-corim-map value: %w", noEOF(err))
+		}
+
+		o.nHeader++
+
+		return nil
+	}
+
+	if o.seenTags {
+		return errors.New("duplicate tags in unsigned-corim-map")
+	}
+
+	major, n, err := readHead(o.r, nil)
+	if err != nil {
+		return fmt.Errorf("reading tags: %w", noEOF(err))
+	}
+
+	if major != cborArray {
+		return fmt.Errorf("expecting tags as CBOR array, got CBOR major type %d", major)
+	}
+
+	o.tags, o.seenTags = n, true
+
+	return nil
+}
+
+// decodeHeader decodes the buffered entries of the unsigned-corim-map (i.e.,
+// all but the tags) into an UnsignedCorim, honouring its profile
+func (o *TagReader) decodeHeader() error {
+	// the tags entry is mandatory for decoding: an empty array stands for it
+	buf := appendHead(nil, cborMap, o.nHeader+1)
+	buf = appendHead(buf, cborUnsignedInt, corimTagsKey)
+	buf = append(buf, emptyCBORArray...)
+	buf = append(buf, o.header.Bytes()...)
+
+	c, err := UnmarshalUnsignedCorimFromCBOR(buf)
+	if err != nil {
+		return fmt.Errorf("failed CBOR decoding of unsigned CoRIM: %w", err)
+	}
+
+	c.Tags = nil
+	o.corim = c
+
+	return nil
+}
+
+// UnsignedCorim returns the unsigned-corim read so far, with all its fields but
+// the tags.  It must only be called after Next has returned io.EOF.
+func (o *TagReader) UnsignedCorim() (*UnsignedCorim, error) {
+	if o.corim == nil {
+		return nil, errNotFullyRead
+	}
+
+	return o.corim, nil
+}
+
+// TagWriter writes an unsigned-corim to an io.Writer, appending its tags one
+// at a time as they are produced.  Since the tags array is encoded with a
+// definite length, the number of tags must be known in advance.
+type TagWriter struct {
+	w       io.Writer
+	profile *eat.Profile
+	count   int
+	written int
+	trailer []byte
+	closed  bool
+}
+
+// NewTagWriter validates the supplied unsigned CoRIM, ignoring its tags, and
+// writes the beginning of its encoding, up to the tags array that will hold
+// the supplied number of tags, to w.  The fields of the unsigned CoRIM that are
+// encoded after the tags are written by Close.
+// nolint:gocritic
+func NewTagWriter(w io.Writer, header UnsignedCorim, count int) (*TagWriter, error) {
+	if header.ID == (swid.TagID{}) {
+		return nil, errors.New("empty id")
+	}
+
+	if count <= 0 {
+		return nil, errors.New("tags validation failed: no tags")
+	}
+
+	if err := header.validHeader(); err != nil {
+		return nil, err
+	}
+
+	header.Tags = nil
+
+	data, err := header.ToCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("failed CBOR encoding of unsigned CoRIM: %w", err)
+	}
+
+	var (
+		r        = bufio.NewReader(bytes.NewReader(data))
+		leading  []byte
+		trailing []byte
+		pairs    uint64
+	)
+
+	// the encoded header is a map: re-arrange its entries around the tags
+	_, n, err := readHead(r, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint64(0); i < n; i++ {
+		var entry bytes.Buffer
+
+		if err := readItem(r, &entry, 0); err != nil {
+			return nil, err
+		}
+
+		key := entry.Len()
+
+		if err := readItem(r, &entry, 0); err != nil {
+			return nil, err
+		}
+
+		k, ok := cborUint(entry.Bytes()[:key])
+
+		switch {
+		case ok && k == corimTagsKey:
+			continue
+		case ok && k == corimIDKey:
+			leading = append(leading, entry.Bytes()...)
+		default:
+			trailing = append(trailing, entry.Bytes()...)
+		}
+
+		pairs++
+	}
+
+	buf := appendHead(nil, cborMap, pairs+1)
+	buf = append(buf, leading...)
+	buf = appendHead(buf, cborUnsignedInt, corimTagsKey)
+	buf = appendHead(buf, cborArray, uint64(count))
+
+	if _, err := w.Write(buf); err != nil {
+		return nil, err
+	}
+
+	return &TagWriter{
+		w:       w,
+		profile: header.Profile,
+		count:   count,
+		trailer: trailing,
+	}, nil
+}
+
+// WriteTag validates the supplied tag (see Tag.Valid), honouring the profile of
+// the unsigned CoRIM, and writes it
+func (o *TagWriter) WriteTag(t Tag) error {
+	if o.closed {
+		return errTagWriterDone
+	}
+
+	if o.written == o.count {
+		return fmt.Errorf("expecting %d tags, got more", o.count)
+	}
+
+	if err := t.valid(o.profile); err != nil {
+		return fmt.Errorf("tag validation failed at pos %d: %w", o.written, err)
+	}
+
+	buf := appendHead(nil, cborByteString, uint64(len(t)))
+
+	if _, err := o.w.Write(buf); err != nil {
+		return err
+	}
+
+	if _, err := o.w.Write(t); err != nil {
+		return err
+	}
+
+	o.written++
+
+	return nil
+}
+
+// WriteComid writes the CBOR encoded (and appropriately tagged) CoMID
+func (o *TagWriter) WriteComid(c *comid.Comid) error {
+	t, err := newComidTag(c)
+	if err != nil {
+		return err
+	}
+
+	return o.WriteTag(t)
+}
+
+// WriteCots writes the CBOR encoded (and appropriately tagged) CoTS
+func (o *TagWriter) WriteCots(c *cots.ConciseTaStore) error {
+	t, err := newCotsTag(c)
+	if err != nil {
+		return err
+	}
+
+	return o.WriteTag(t)
+}
+
+// WriteCoswid writes the CBOR encoded (and appropriately tagged) CoSWID
+func (o *TagWriter) WriteCoswid(c *swid.SoftwareIdentity) error {
+	t, err := newCoswidTag(c)
+	if err != nil {
+		return err
+	}
+
+	return o.WriteTag(t)
+}
+
+// Close checks that all the tags have been written and writes the remaining
+// fields of the unsigned CoRIM.  The underlying io.Writer is not closed.
+func (o *TagWriter) Close() error {
+	if o.closed {
+		return errTagWriterDone
+	}
+
+	if o.written != o.count {
+		return fmt.Errorf("expecting %d tags, %d written", o.count, o.written)
+	}
+
+	if _, err := o.w.Write(o.trailer); err != nil {
+		return err
+	}
+
+	o.closed = true
+
+	return nil
+}
+
+// readHead reads the head of a CBOR data item from r, returning its major type
+// and argument.  If buf is not nil, the raw head is appended to it.
+func readHead(r *bufio.Reader, buf *bytes.Buffer) (byte, uint64, error) {
+	ib, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	major, info := ib>>5, ib&0x1f
+
+	if buf != nil {
+		buf.WriteByte(ib)
+	}
+
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info <= 27:
+		var b [8]byte
+
+		arg := b[:1<<(info-24)]
+		if _, err := io.ReadFull(r, arg); err != nil {
+			return 0, 0, noEOF(err)
+		}
+
+		if buf != nil {
+			buf.Write(arg)
+		}
+
+		var n uint64
+		for _, c := range arg {
+			n = n<<8 | uint64(c)
+		}
+
+		return major, n, nil
+	case info == 31:
+		return 0, 0, errors.New("indefinite-length CBOR data items are not supported")
+	default:
+		return 0, 0, fmt.Errorf("malformed CBOR data item: reserved additional information %d", info)
+	}
+}
+
+// readItem reads a complete CBOR data item from r and appends its raw encoding
+// to buf
+func readItem(r *bufio.Reader, buf *bytes.Buffer, depth int) error {
+	if depth > maxNestedLevels {
+		return fmt.Errorf("exceeded max nested level %d", maxNestedLevels)
+	}
+
+	major, n, err := readHead(r, buf)
+	if err != nil {
+		return err
+	}
+
+	switch major {
+	case cborByteString, cborTextString:
+		if n > math.MaxInt64 {
+			return fmt.Errorf("string length %d too large", n)
+		}
+
+		if _, err := io.CopyN(buf, r, int64(n)); err != nil {
+			return noEOF(err)
+		}
+	case cborArray, cborMap:
+		if major == cborMap {
+			if n > math.MaxUint64/2 {
+				return fmt.Errorf("map length %d too large", n)
+			}
+			n *= 2
+		}
+
+		for i := uint64(0); i < n; i++ {
+			if err := readItem(r, buf, depth+1); err != nil {
+				return noEOF(err)
+			}
+		}
+	case cborTag:
+		if err := readItem(r, buf, depth+1); err != nil {
+			return noEOF(err)
+		}
+	}
+
+	return nil
+}
+
+// cborUint returns the value of the supplied raw CBOR data item if it is an
+// unsigned integer, in any (including non-shortest) encoding
+func cborUint(raw []byte) (uint64, bool) {
+	r := bufio.NewReader(bytes.NewReader(raw))
+
+	major, n, err := readHead(r, nil)
+	if err != nil || major != cborUnsignedInt || r.Buffered() != 0 {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// appendHead appends the (shortest) head of a CBOR data item with the supplied
+// major type and argument to buf
+func appendHead(buf []byte, major byte, n uint64) []byte {
+	major <<= 5
+
+	switch {
+	case n < 24:
+		return append(buf, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return append(buf, major|25, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		return append(buf, major|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	default:
+		return append(buf, major|27,
+			byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32),
+			byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+}
+
+// noEOF turns an io.EOF in the middle of a CBOR data item into an
+// io.ErrUnexpectedEOF
+func noEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,295 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/eat"
+)
+
+// readAllTags reads all the tags from the supplied TagReader
+func readAllTags(t *testing.T, r *TagReader) []Tag {
+	var tags []Tag
+
+	for {
+		tag, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return tags
+		}
+		require.NoError(t, err)
+
+		tags = append(tags, tag)
+	}
+}
+
+func TestTagReader(t *testing.T) {
+	expected := unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	for _, data := range [][]byte{
+		testGoodUnsignedCorimCBOR,
+		append(append([]byte{}, UnsignedCorimTag...), testGoodUnsignedCorimCBOR...),
+	} {
+		r, err := NewTagReader(bytes.NewReader(data))
+		require.NoError(t, err)
+
+		_, err = r.UnsignedCorim()
+		assert.EqualError(t, err, "unsigned CoRIM not fully read")
+
+		tags := readAllTags(t, r)
+		assert.Equal(t, expected.Tags, tags)
+
+		// the reader stays at the end
+		_, err = r.Next()
+		assert.ErrorIs(t, err, io.EOF)
+
+		u, err := r.UnsignedCorim()
+		require.NoError(t, err)
+		assert.Equal(t, expected.GetID(), u.GetID())
+		assert.Nil(t, u.Tags)
+	}
+}
+
+func TestTagReader_non_shortest_key(t *testing.T) {
+	expected := unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	// a2 00 6d <13 bytes id> 01 ... => a2 00 6d <13 bytes id> 18 01 ...
+	require.Equal(t, byte(0x01), testGoodUnsignedCorimCBOR[16])
+
+	data := append([]byte{}, testGoodUnsignedCorimCBOR[:16]...)
+	data = append(data, 0x18)
+	data = append(data, testGoodUnsignedCorimCBOR[16:]...)
+
+	// the tags are the same as seen by FromCBOR
+	u := unsignedCorimFromCBOR(t, data)
+	require.Equal(t, expected.Tags, u.Tags)
+
+	r, err := NewTagReader(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	assert.Equal(t, expected.Tags, readAllTags(t, r))
+}
+
+func TestTagReader_profile(t *testing.T) {
+	type corimExtensions struct {
+		Extension1 *string `cbor:"-1,keyasint,omitempty" json:"ext1,omitempty"`
+	}
+
+	profID, err := eat.NewProfile("http://example.com/test-profile")
+	require.NoError(t, err)
+
+	require.NoError(t, RegisterProfile(profID, extensions.NewMap().Add(ExtUnsignedCorim, &corimExtensions{})))
+	defer UnregisterProfile(profID)
+
+	r, err := NewTagReader(bytes.NewReader(testUnsignedCorimWithExtensionsCBOR))
+	require.NoError(t, err)
+
+	tags := readAllTags(t, r)
+	require.Len(t, tags, 1)
+
+	u, err := r.UnsignedCorim()
+	require.NoError(t, err)
+	assert.Equal(t, profID, u.Profile)
+	assert.Equal(t, "foo", u.Extensions.MustGetString("Extension1"))
+
+	// the tags are decoded with the profile of the CoRIM
+	tt, err := tags[0].Decode(u.Profile)
+	require.NoError(t, err)
+	assert.Equal(t, TagTypeComid, tt.Type)
+}
+
+func TestTagReader_fail(t *testing.T) {
+	tvs := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{
+			name: "empty",
+			data: []byte{},
+			err:  "reading unsigned-corim-map: unexpected EOF",
+		},
+		{
+			name: "not a map",
+			data: []byte{0x80},
+			err:  "expecting unsigned-corim-map, got CBOR major type 4",
+		},
+		{
+			name: "indefinite-length map",
+			data: []byte{0xbf},
+			err:  "reading unsigned-corim-map: indefinite-length CBOR data items are not supported",
+		},
+		{
+			name: "truncated map",
+			data: []byte{0xa2, 0x00, 0x61},
+			err:  "reading unsigned-corim-map value: unexpected EOF",
+		},
+		{
+			name: "tags not an array",
+			data: []byte{0xa1, 0x01, 0xa0},
+			err:  "expecting tags as CBOR array, got CBOR major type 5",
+		},
+		{
+			name: "tag not a byte string",
+			data: []byte{0xa1, 0x01, 0x81, 0x61, 0x61},
+			err:  "expecting tag as CBOR byte string, got CBOR major type 3",
+		},
+		{
+			name: "truncated tag",
+			data: []byte{0xa1, 0x01, 0x81, 0x44, 0xd9, 0x01},
+			err:  "reading tag: unexpected EOF",
+		},
+		{
+			name: "duplicate tags",
+			data: []byte{0xa2, 0x01, 0x80, 0x01, 0x80},
+			err:  "duplicate tags in unsigned-corim-map",
+		},
+		{
+			name: "bad header",
+			data: []byte{0xa2, 0x00, 0x01, 0x01, 0x81, 0x41, 0x00},
+			err:  "failed CBOR decoding of unsigned CoRIM: ",
+		},
+		{
+			name: "no tags entry",
+			data: []byte{0xa1, 0x00, 0x61, 0x78},
+			err:  "tags validation failed: no tags",
+		},
+		{
+			name: "empty tags",
+			data: []byte{0xa2, 0x00, 0x61, 0x78, 0x01, 0x80},
+			err:  "tags validation failed: no tags",
+		},
+		{
+			name: "non-shortest tags key, empty tags",
+			data: []byte{0xa2, 0x00, 0x61, 0x78, 0x18, 0x01, 0x80},
+			err:  "tags validation failed: no tags",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.name, func(t *testing.T) {
+			r, err := NewTagReader(bytes.NewReader(tv.data))
+			if err == nil {
+				for err == nil {
+					_, err = r.Next()
+				}
+
+				// errors are sticky
+				_, again := r.Next()
+				assert.Equal(t, err, again)
+			}
+
+			assert.ErrorContains(t, err, tv.err)
+		})
+	}
+}
+
+func TestTagWriter(t *testing.T) {
+	notBefore := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	expected := testTypedTagsCorim(t).
+		SetProfile("http://example.com/test-profile").
+		SetRimValidity(notBefore.AddDate(1, 0, 0), &notBefore).
+		AddEntity("ACME Ltd.", nil, RoleManifestCreator)
+	require.NotNil(t, expected)
+
+	header := *expected
+	header.Tags = nil
+
+	var buf bytes.Buffer
+
+	w, err := NewTagWriter(&buf, header, len(expected.Tags))
+	require.NoError(t, err)
+
+	for _, tag := range expected.Tags {
+		require.NoError(t, w.WriteTag(tag))
+	}
+
+	require.NoError(t, w.Close())
+
+	out, err := expected.ToCBOR()
+	require.NoError(t, err)
+	assert.Equal(t, out, buf.Bytes())
+
+	// round-trip through the reader
+	r, err := NewTagReader(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, expected.Tags, readAllTags(t, r))
+
+	u, err := r.UnsignedCorim()
+	require.NoError(t, err)
+	assert.Equal(t, expected.Profile, u.Profile)
+	assert.Equal(t, "ACME Ltd.", u.Entities.Values[0].Name.String())
+}
+
+func TestTagWriter_WriteComid(t *testing.T) {
+	c := &comid.Comid{}
+	require.NoError(t, c.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+
+	var buf bytes.Buffer
+
+	w, err := NewTagWriter(&buf, *NewUnsignedCorim().SetID("test corim id"), 1)
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteComid(c))
+	require.NoError(t, w.Close())
+
+	assertCoRIMEq(t, testGoodUnsignedCorimCBOR, buf.Bytes())
+}
+
+func TestTagWriter_fail(t *testing.T) {
+	var buf bytes.Buffer
+
+	_, err := NewTagWriter(&buf, *NewUnsignedCorim(), 1)
+	assert.EqualError(t, err, "empty id")
+
+	header := *NewUnsignedCorim().SetID("test corim id")
+
+	_, err = NewTagWriter(&buf, header, 0)
+	assert.EqualError(t, err, "tags validation failed: no tags")
+
+	notAfter := time.Now()
+	notBefore := notAfter.Add(time.Hour)
+
+	invalid := header
+	invalid.RimValidity = &Validity{NotAfter: notAfter, NotBefore: &notBefore}
+
+	_, err = NewTagWriter(&buf, invalid, 1)
+	assert.ErrorContains(t, err, "RIM validity validation failed")
+
+	assert.Zero(t, buf.Len())
+
+	tags := testTypedTagsCorim(t).Tags
+
+	w, err := NewTagWriter(&buf, header, 2)
+	require.NoError(t, err)
+
+	err = w.WriteTag(Tag{0xd9, 0x01, 0xf5, 0xa0})
+	assert.EqualError(t, err, "tag validation failed at pos 0: unknown tag type (CBOR prefix d901f5)")
+
+	err = w.WriteComid(&comid.Comid{})
+	assert.ErrorContains(t, err, "CoMID validation failed")
+
+	require.NoError(t, w.WriteTag(tags[0]))
+
+	assert.EqualError(t, w.Close(), "expecting 2 tags, 1 written")
+
+	require.NoError(t, w.WriteTag(tags[1]))
+
+	assert.EqualError(t, w.WriteTag(tags[2]), "expecting 2 tags, got more")
+
+	require.NoError(t, w.Close())
+
+	assert.EqualError(t, w.Close(), "tag writer already closed")
+	assert.EqualError(t, w.WriteTag(tags[2]), "tag writer already closed")
+}
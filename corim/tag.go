@@ -0,0 +1,213 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/cots"
+	"github.com/veraison/eat"
+	"github.com/veraison/swid"
+)
+
+// TagType identifies the kind of a Tag carried in an unsigned CoRIM, based on
+// its CBOR tag number
+type TagType int
+
+const (
+	TagTypeUnknown TagType = iota
+	// TagTypeComid is a CoMID (#6.506)
+	TagTypeComid
+	// TagTypeCoswid is a CoSWID (#6.505)
+	TagTypeCoswid
+	// TagTypeCots is a CoTS (#6.507)
+	TagTypeCots
+)
+
+func (o TagType) String() string {
+	switch o {
+	case TagTypeComid:
+		return "CoMID"
+	case TagTypeCoswid:
+		return "CoSWID"
+	case TagTypeCots:
+		return "CoTS"
+	default:
+		return "unknown"
+	}
+}
+
+// TypedTag is a decoded Tag.  Depending on Type, exactly one of Comid, Coswid
+// or Cots is set.
+type TypedTag struct {
+	Type   TagType
+	Comid  *comid.Comid
+	Coswid *swid.SoftwareIdentity
+	Cots   *cots.ConciseTaStore
+}
+
+// newComidTag validates and encodes the supplied CoMID into an appropriately
+// tagged Tag
+func newComidTag(c *comid.Comid) (Tag, error) {
+	if err := c.Valid(); err != nil {
+		return nil, fmt.Errorf("CoMID validation failed: %w", err)
+	}
+
+	comidCBOR, err := c.ToCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("failed CBOR encoding of CoMID: %w", err)
+	}
+
+	return append(append(Tag{}, ComidTag...), comidCBOR...), nil
+}
+
+// newCotsTag validates and encodes the supplied CoTS into an appropriately
+// tagged Tag
+func newCotsTag(c *cots.ConciseTaStore) (Tag, error) {
+	if err := c.Valid(); err != nil {
+		return nil, fmt.Errorf("CoTS validation failed: %w", err)
+	}
+
+	cotsCBOR, err := c.ToCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("failed CBOR encoding of CoTS: %w", err)
+	}
+
+	return append(append(Tag{}, cots.CotsTag...), cotsCBOR...), nil
+}
+
+// newCoswidTag encodes the supplied CoSWID into an appropriately tagged Tag
+func newCoswidTag(c *swid.SoftwareIdentity) (Tag, error) {
+	// Currently the swid package doesn't offer an interface
+	// for validating the supplied CoSWID, so -- for now --
+	// we take any input for granted and pass it to the encoder.
+	// See also https://github.com/veraison/swid/issues/23.
+
+	coswidCBOR, err := c.ToCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("failed CBOR encoding of CoSWID: %w", err)
+	}
+
+	return append(append(Tag{}, CoswidTag...), coswidCBOR...), nil
+}
+
+// Type returns the kind of the target Tag, or TagTypeUnknown if its CBOR tag
+// number is not one of CoMID, CoSWID or CoTS
+func (o Tag) Type() TagType {
+	switch {
+	case bytes.HasPrefix(o, ComidTag):
+		return TagTypeComid
+	case bytes.HasPrefix(o, CoswidTag):
+		return TagTypeCoswid
+	case bytes.HasPrefix(o, cots.CotsTag):
+		return TagTypeCots
+	default:
+		return TagTypeUnknown
+	}
+}
+
+// Decode strips the CBOR tag number from the target Tag and decodes its
+// content according to its Type.  CoMIDs are decoded with the extensions
+// associated with the supplied profileID (if any) registered.
+func (o Tag) Decode(profileID *eat.Profile) (*TypedTag, error) {
+	ret := TypedTag{Type: o.Type()}
+
+	switch ret.Type {
+	case TagTypeComid:
+		c, err := UnmarshalComidFromCBOR(o[len(ComidTag):], profileID)
+		if err != nil {
+			return nil, fmt.Errorf("decoding CoMID: %w", err)
+		}
+		ret.Comid = c
+	case TagTypeCoswid:
+		var s swid.SoftwareIdentity
+		if err := s.FromCBOR(o[len(CoswidTag):]); err != nil {
+			return nil, fmt.Errorf("decoding CoSWID: %w", err)
+		}
+		ret.Coswid = &s
+	case TagTypeCots:
+		var c cots.ConciseTaStore
+		if err := c.FromCBOR(o[len(cots.CotsTag):]); err != nil {
+			return nil, fmt.Errorf("decoding CoTS: %w", err)
+		}
+		ret.Cots = &c
+	default:
+		return nil, fmt.Errorf("unknown tag type (CBOR prefix %x)", o[:min(len(o), 3)])
+	}
+
+	return &ret, nil
+}
+
+// GetTags decodes all the tags of the target unsigned CoRIM (see Tag.Decode),
+// honouring its Profile
+// nolint:gocritic
+func (o UnsignedCorim) GetTags() ([]TypedTag, error) {
+	ret := make([]TypedTag, 0, len(o.Tags))
+
+	for i, t := range o.Tags {
+		tt, err := t.Decode(o.Profile)
+		if err != nil {
+			return nil, fmt.Errorf("tag at pos %d: %w", i, err)
+		}
+		ret = append(ret, *tt)
+	}
+
+	return ret, nil
+}
+
+// GetComids returns the decoded CoMIDs carried in the target unsigned CoRIM
+// nolint:gocritic
+func (o UnsignedCorim) GetComids() ([]*comid.Comid, error) {
+	tags, err := o.GetTags()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*comid.Comid
+	for _, t := range tags {
+		if t.Comid != nil {
+			ret = append(ret, t.Comid)
+		}
+	}
+
+	return ret, nil
+}
+
+// GetCoswids returns the decoded CoSWIDs carried in the target unsigned CoRIM
+// nolint:gocritic
+func (o UnsignedCorim) GetCoswids() ([]*swid.SoftwareIdentity, error) {
+	tags, err := o.GetTags()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*swid.SoftwareIdentity
+	for _, t := range tags {
+		if t.Coswid != nil {
+			ret = append(ret, t.Coswid)
+		}
+	}
+
+	return ret, nil
+}
+
+// GetCots returns the decoded CoTSes carried in the target unsigned CoRIM
+// nolint:gocritic
+func (o UnsignedCorim) GetCots() ([]*cots.ConciseTaStore, error) {
+	tags, err := o.GetTags()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*cots.ConciseTaStore
+	for _, t := range tags {
+		if t.Cots != nil {
+			ret = append(ret, t.Cots)
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/cots"
+	"github.com/veraison/corim/extensions"
+	"github.com/veraison/eat"
+	"github.com/veraison/swid"
+)
+
+func testTypedTagsCorim(t *testing.T) *UnsignedCorim {
+	c := &comid.Comid{}
+	require.NoError(t, c.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+
+	s := &swid.SoftwareIdentity{}
+	require.NoError(t, s.FromXML([]byte(
+		`<SoftwareIdentity xmlns="http://standards.iso.org/iso/19770/-2/2015/schema.xsd" tagId="com.acme.fw-1.0" name="ACME Firmware" version="1.0"><Entity name="ACME Ltd." regid="acme.example" role="tagCreator softwareCreator"></Entity></SoftwareIdentity>`,
+	)))
+
+	ta := &cots.ConciseTaStore{}
+	require.NoError(t, ta.FromJSON([]byte(cots.ConciseTaStoreTemplateSingleOrg)))
+
+	u := NewUnsignedCorim().SetID("test corim id").AddComid(c).AddCoswid(s).AddCots(ta)
+	require.NotNil(t, u)
+
+	return u
+}
+
+func TestTag_Type(t *testing.T) {
+	u := testTypedTagsCorim(t)
+
+	assert.Equal(t, TagTypeComid, u.Tags[0].Type())
+	assert.Equal(t, TagTypeCoswid, u.Tags[1].Type())
+	assert.Equal(t, TagTypeCots, u.Tags[2].Type())
+	assert.Equal(t, TagTypeUnknown, Tag{0xd9, 0x01, 0xf5, 0xa0}.Type())
+	assert.Equal(t, TagTypeUnknown, Tag{}.Type())
+
+	assert.Equal(t, "CoMID", TagTypeComid.String())
+	assert.Equal(t, "CoSWID", TagTypeCoswid.String())
+	assert.Equal(t, "CoTS", TagTypeCots.String())
+	assert.Equal(t, "unknown", TagTypeUnknown.String())
+}
+
+func TestUnsignedCorim_GetTags(t *testing.T) {
+	u := testTypedTagsCorim(t)
+	require.NoError(t, u.Valid())
+
+	tags, err := u.GetTags()
+	require.NoError(t, err)
+	require.Len(t, tags, 3)
+
+	assert.Equal(t, TagTypeComid, tags[0].Type)
+	require.NotNil(t, tags[0].Comid)
+	assert.Nil(t, tags[0].Coswid)
+	assert.Nil(t, tags[0].Cots)
+	assert.Equal(t, "43bbe37f-2e61-4b33-aed3-53cff1428b16", tags[0].Comid.TagIdentity.TagID.String())
+
+	assert.Equal(t, TagTypeCoswid, tags[1].Type)
+	require.NotNil(t, tags[1].Coswid)
+	assert.Equal(t, "com.acme.fw-1.0", tags[1].Coswid.TagID.String())
+
+	assert.Equal(t, TagTypeCots, tags[2].Type)
+	require.NotNil(t, tags[2].Cots)
+
+	comids, err := u.GetComids()
+	require.NoError(t, err)
+	assert.Equal(t, []*comid.Comid{tags[0].Comid}, comids)
+
+	coswids, err := u.GetCoswids()
+	require.NoError(t, err)
+	assert.Equal(t, []*swid.SoftwareIdentity{tags[1].Coswid}, coswids)
+
+	cotses, err := u.GetCots()
+	require.NoError(t, err)
+	assert.Equal(t, []*cots.ConciseTaStore{tags[2].Cots}, cotses)
+}
+
+func TestUnsignedCorim_GetComids_profile(t *testing.T) {
+	type entityExtensions struct {
+		Address *string `cbor:"-1,keyasint,omitempty" json:"address,omitempty"`
+	}
+
+	profID, err := eat.NewProfile("http://example.com/test-profile")
+	require.NoError(t, err)
+
+	require.NoError(t, RegisterProfile(profID, extensions.NewMap().Add(comid.ExtEntity, &entityExtensions{})))
+	defer UnregisterProfile(profID)
+
+	u, err := UnmarshalUnsignedCorimFromCBOR(testUnsignedCorimWithExtensionsCBOR)
+	require.NoError(t, err)
+	require.NoError(t, u.Valid())
+
+	comids, err := u.GetComids()
+	require.NoError(t, err)
+	require.Len(t, comids, 1)
+
+	address := comids[0].Entities.Values[0].Extensions.MustGetString("Address")
+	assert.Equal(t, "123 Fake Street", address)
+}
+
+func TestTag_Decode_fail(t *testing.T) {
+	tvs := []struct {
+		name string
+		tag  Tag
+		err  string
+	}{
+		{
+			name: "unknown tag",
+			tag:  Tag{0xd9, 0x01, 0xf5, 0xa0},
+			err:  "unknown tag type (CBOR prefix d901f5)",
+		},
+		{
+			name: "untagged",
+			tag:  Tag{0xa0},
+			err:  "unknown tag type (CBOR prefix a0)",
+		},
+		{
+			name: "bad CoMID",
+			tag:  append(append(Tag{}, ComidTag...), 0x01),
+			err:  "decoding CoMID: ",
+		},
+		{
+			name: "bad CoSWID",
+			tag:  append(append(Tag{}, CoswidTag...), 0x01),
+			err:  "decoding CoSWID: ",
+		},
+		{
+			name: "bad CoTS",
+			tag:  append(append(Tag{}, cots.CotsTag...), 0x01),
+			err:  "decoding CoTS: ",
+		},
+	}
+
+	for _, tv := range tvs {
+		t.Run(tv.name, func(t *testing.T) {
+			_, err := tv.tag.Decode(nil)
+			assert.ErrorContains(t, err, tv.err)
+
+			assert.ErrorContains(t, tv.tag.Valid(), tv.err)
+		})
+	}
+
+	u := testTypedTagsCorim(t)
+	u.Tags = append(u.Tags, tvs[0].tag)
+
+	err := u.Valid()
+	assert.EqualError(t, err, "tag validation failed at pos 3: unknown tag type (CBOR prefix d901f5)")
+
+	_, err = u.GetTags()
+	assert.EqualError(t, err, "tag at pos 3: unknown tag type (CBOR prefix d901f5)")
+
+	_, err = u.GetComids()
+	assert.Error(t, err)
+
+	assert.EqualError(t, u.ValidCoswidTriples(), "tag at pos 3: unknown tag type (CBOR prefix d901f5)")
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	cose "github.com/veraison/go-cose"
+)
+
+// TBSSigner is a signer that holds its key outside of the library, e.g., in a
+// remote signing service or an HSM.  SignTBS is passed the to-be-signed bytes
+// of a COSE message and must return the signature in the format expected by
+// COSE for the signer's Algorithm.
+type TBSSigner interface {
+	Algorithm() cose.Algorithm
+	SignTBS(tbs []byte) ([]byte, error)
+}
+
+// SignWithTBSSigner returns the serialized signed-corim, signed by the supplied
+// TBSSigner using two-phase signing (see PrepareSign and AttachSignature)
+func (o *SignedCorim) SignWithTBSSigner(signer TBSSigner) ([]byte, error) {
+	if signer == nil {
+		return nil, errors.New("nil signer")
+	}
+
+	tbs, err := o.PrepareSign(signer.Algorithm())
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := signer.SignTBS(tbs)
+	if err != nil {
+		return nil, fmt.Errorf("external signature failed: %w", err)
+	}
+
+	return o.AttachSignature(sig)
+}
+
+// LocalTBSSigner is a TBSSigner backed by a local cose.Signer.  It stands in
+// for an external signer in tests and tooling.
+type LocalTBSSigner struct {
+	signer cose.Signer
+}
+
+// NewLocalTBSSigner instantiates a LocalTBSSigner that signs with the supplied
+// cose.Signer
+func NewLocalTBSSigner(signer cose.Signer) (*LocalTBSSigner, error) {
+	if signer == nil {
+		return nil, errors.New("nil signer")
+	}
+
+	return &LocalTBSSigner{signer: signer}, nil
+}
+
+// Algorithm returns the algorithm of the underlying cose.Signer
+func (o LocalTBSSigner) Algorithm() cose.Algorithm {
+	return o.signer.Algorithm()
+}
+
+// SignTBS signs the supplied to-be-signed bytes with the underlying
+// cose.Signer
+func (o LocalTBSSigner) SignTBS(tbs []byte) ([]byte, error) {
+	return o.signer.Sign(rand.Reader, tbs)
+}
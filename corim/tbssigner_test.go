@@ -0,0 +1,109 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cose "github.com/veraison/go-cose"
+)
+
+type failingTBSSigner struct{}
+
+func (failingTBSSigner) Algorithm() cose.Algorithm {
+	return cose.AlgorithmES256
+}
+
+func (failingTBSSigner) SignTBS([]byte) ([]byte, error) {
+	return nil, errors.New("signing service unavailable")
+}
+
+func TestSignedCorim_PrepareSign_AttachSignature(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	pk, err := NewPublicKeyFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{
+		UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR),
+		Meta:          *metaGood(t),
+		KeyID:         []byte("1"),
+	}
+
+	tbs, err := SignedCorimIn.PrepareSign(cose.AlgorithmES256)
+	require.NoError(t, err)
+	require.NotEmpty(t, tbs)
+
+	// the to-be-signed bytes are signed elsewhere...
+	sig, err := signer.Sign(rand.Reader, tbs)
+	require.NoError(t, err)
+
+	// ... and attached
+	cbor, err := SignedCorimIn.AttachSignature(sig)
+	require.NoError(t, err)
+
+	var SignedCorimOut SignedCorim
+
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+	assert.Equal(t, []byte("1"), SignedCorimOut.KeyID)
+	assert.NoError(t, SignedCorimOut.Verify(pk))
+
+	// the signature can only be attached once
+	_, err = SignedCorimIn.AttachSignature(sig)
+	assert.EqualError(t, err, "no prepared Sign1 message found")
+}
+
+func TestSignedCorim_AttachSignature_fail(t *testing.T) {
+	var SignedCorimIn SignedCorim
+
+	_, err := SignedCorimIn.AttachSignature([]byte{1})
+	assert.EqualError(t, err, "no prepared Sign1 message found")
+
+	SignedCorimIn.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+
+	_, err = SignedCorimIn.PrepareSign(cose.AlgorithmES256)
+	require.NoError(t, err)
+
+	_, err = SignedCorimIn.AttachSignature(nil)
+	assert.EqualError(t, err, "empty signature")
+
+	_, err = SignedCorimIn.PrepareSign(cose.Algorithm(-1000))
+	assert.EqualError(t, err, "signer has no algorithm")
+}
+
+func TestSignedCorim_SignWithTBSSigner(t *testing.T) {
+	signer, err := NewSignerFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	pk, err := NewPublicKeyFromJWK(testEdDSAKey)
+	require.NoError(t, err)
+
+	local, err := NewLocalTBSSigner(signer)
+	require.NoError(t, err)
+	assert.Equal(t, cose.AlgorithmEd25519, local.Algorithm())
+
+	SignedCorimIn := SignedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+
+	cbor, err := SignedCorimIn.SignWithTBSSigner(local)
+	require.NoError(t, err)
+
+	var SignedCorimOut SignedCorim
+
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+	assert.NoError(t, SignedCorimOut.Verify(pk))
+
+	_, err = SignedCorimIn.SignWithTBSSigner(failingTBSSigner{})
+	assert.EqualError(t, err, "external signature failed: signing service unavailable")
+
+	_, err = SignedCorimIn.SignWithTBSSigner(nil)
+	assert.EqualError(t, err, "nil signer")
+
+	_, err = NewLocalTBSSigner(nil)
+	assert.EqualError(t, err, "nil signer")
+}
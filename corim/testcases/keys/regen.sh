@@ -0,0 +1,25 @@
+#!/usr/bin/bash
+# Copyright 2024 Contributors to the Veraison project.
+# SPDX-License-Identifier: Apache-2.0
+set -e
+
+# the password of the encrypted keys is "corim"
+
+openssl ecparam -name prime256v1 -genkey -noout -out ec-p256-sec1.pem
+openssl pkcs8 -topk8 -nocrypt -in ec-p256-sec1.pem -out ec-p256-pkcs8.pem
+openssl pkcs8 -topk8 -v2 aes-256-cbc -v2prf hmacWithSHA256 -passout pass:corim \
+	-in ec-p256-sec1.pem -out ec-p256-pkcs8-enc.pem
+openssl ec -in ec-p256-sec1.pem -aes256 -passout pass:corim -out ec-p256-sec1-legacy-enc.pem
+openssl ec -in ec-p256-sec1.pem -pubout -out ec-p256-pub.pem
+openssl req -new -x509 -key ec-p256-sec1.pem -subj "/CN=ACME Ltd. CoRIM signer" -days 3650 \
+	-out ec-p256-cert.pem
+
+openssl genrsa -traditional -out rsa-2048-pkcs1.pem 2048
+openssl rsa -in rsa-2048-pkcs1.pem -pubout -out rsa-2048-pub.pem
+openssl rsa -in rsa-2048-pkcs1.pem -RSAPublicKey_out -out rsa-2048-pub-pkcs1.pem
+
+openssl genpkey -algorithm ed25519 -out ed25519-pkcs8.pem
+openssl pkcs8 -topk8 -v2 aes-128-cbc -v2prf hmacWithSHA1 -passout pass:corim \
+	-in ed25519-pkcs8.pem -out ed25519-pkcs8-enc.pem
+
+echo "done."
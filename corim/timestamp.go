@@ -0,0 +1,560 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+)
+
+// HeaderLabelTimestampToken is the label of the 3161-ctt header parameter
+// (draft-ietf-cose-tsa-tst-header-parameter), which carries an RFC 3161
+// TimeStampToken over the signature of a COSE message
+var HeaderLabelTimestampToken = int64(270)
+
+// ErrNoTimestamp is returned by VerifyTimestamp when the signed-corim carries
+// no timestamp token
+var ErrNoTimestamp = errors.New("no timestamp token found")
+
+// TimestampAuthority is an RFC 3161 Time Stamping Authority (TSA)
+type TimestampAuthority interface {
+	// Timestamp returns a DER encoded TimeStampToken for the supplied
+	// message imprint, i.e., the digest of the timestamped data computed with
+	// the supplied hash function
+	Timestamp(hash crypto.Hash, digest []byte) ([]byte, error)
+}
+
+// Timestamp describes a verified RFC 3161 timestamp token
+type Timestamp struct {
+	// Time is the time at which the timestamp token was generated
+	Time         time.Time
+	Policy       asn1.ObjectIdentifier
+	SerialNumber *big.Int
+	// TSACert is the certificate of the TSA that signed the token
+	TSACert *x509.Certificate
+}
+
+// AddTimestamp obtains from the supplied TSA a timestamp token over the
+// signature of the target SignedCorim, which must hold a signed COSE Sign1
+// message, using the supplied hash function for the message imprint.  It
+// returns the serialized signed-corim with the token in its (unprotected)
+// 3161-ctt header, replacing any token already present.
+func (o *SignedCorim) AddTimestamp(tsa TimestampAuthority, hash crypto.Hash) ([]byte, error) {
+	if tsa == nil {
+		return nil, errors.New("nil TSA")
+	}
+
+	if err := o.checkSigned(); err != nil {
+		return nil, err
+	}
+
+	if _, err := hashOID(hash); err != nil {
+		return nil, err
+	}
+
+	token, err := tsa.Timestamp(hash, hashSum(hash, o.message.Signature))
+	if err != nil {
+		return nil, fmt.Errorf("timestamping failed: %w", err)
+	}
+
+	tst, err := parseTimestampToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("parsing timestamp token: %w", err)
+	}
+
+	if err := tst.checkImprint(o.message.Signature); err != nil {
+		return nil, err
+	}
+
+	wrap, err := o.setUnprotectedHdr(HeaderLabelTimestampToken, token)
+	if err != nil {
+		return nil, err
+	}
+
+	o.TimestampToken = token
+
+	return wrap, nil
+}
+
+// processTimestamp populates TimestampToken from the 3161-ctt header, if
+// present
+func (o *SignedCorim) processTimestamp() error {
+	o.TimestampToken = nil
+
+	v, ok := o.message.Headers.Unprotected[HeaderLabelTimestampToken]
+	if !ok {
+		return nil
+	}
+
+	token, ok := v.([]byte)
+	if !ok {
+		return fmt.Errorf("expecting bstr, got %T instead", v)
+	}
+
+	o.TimestampToken = token
+
+	return nil
+}
+
+// VerifyTimestamp verifies the timestamp token of the target COSE Sign1
+// SignedCorim object: the token must be signed by a TSA whose certificate
+// chains up to one of the supplied roots and is valid for time stamping at the
+// time of the token, and its message imprint must match the signature of the
+// signed-corim.  It then checks that the signature was made while the signing
+// key was valid, i.e., that the time of the token is within the signature
+// validity period (from the corim-meta-map) and, if the signed-corim carries
+// an x5chain header, within the validity period of the signing certificate.
+// The signature of the signed-corim itself is not verified.
+func (o *SignedCorim) VerifyTimestamp(roots *x509.CertPool) (*Timestamp, error) {
+	if roots == nil {
+		return nil, errors.New("nil TSA roots")
+	}
+
+	if err := o.checkSigned(); err != nil {
+		return nil, err
+	}
+
+	if len(o.TimestampToken) == 0 {
+		return nil, ErrNoTimestamp
+	}
+
+	tst, err := parseTimestampToken(o.TimestampToken)
+	if err != nil {
+		return nil, fmt.Errorf("parsing timestamp token: %w", err)
+	}
+
+	cert, err := tst.verify(roots)
+	if err != nil {
+		return nil, fmt.Errorf("verifying timestamp token: %w", err)
+	}
+
+	if err := tst.checkImprint(o.message.Signature); err != nil {
+		return nil, err
+	}
+
+	ts := Timestamp{
+		Time:         tst.info.GenTime,
+		Policy:       tst.info.Policy,
+		SerialNumber: tst.info.SerialNumber,
+		TSACert:      cert,
+	}
+
+	if v := o.Meta.Validity; v != nil {
+		if err := v.Check(ts.Time, 0); err != nil {
+			return nil, &ValidityError{Scope: ValiditySignature, Validity: *v, Now: ts.Time, Err: err}
+		}
+	}
+
+	if c := o.SigningCert; c != nil {
+		if ts.Time.Before(c.NotBefore) {
+			return nil, fmt.Errorf("signing certificate %w at %s", ErrNotYetValid, ts.Time.Format(time.RFC3339))
+		}
+
+		if ts.Time.After(c.NotAfter) {
+			return nil, fmt.Errorf("signing certificate %w at %s", ErrExpired, ts.Time.Format(time.RFC3339))
+		}
+	}
+
+	return &ts, nil
+}
+
+var (
+	oidSignedData        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidAttrContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttrMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttrSigningCertV2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+	oidSHA256            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidRSAEncryption     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA256WithRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSHA384WithRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSHA512WithRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidECPublicKey       = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidECDSAWithSHA256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSAWithSHA384   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidEd25519           = asn1.ObjectIdentifier{1, 3, 101, 112}
+)
+
+// contentInfo is the CMS ContentInfo (RFC 5652)
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+// signedData is the CMS SignedData (RFC 5652)
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+// signerInfo is the CMS SignerInfo (RFC 5652), identifying the signer by
+// issuer and serial number
+type signerInfo struct {
+	Version            int
+	SID                issuerAndSerialNumber
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+// signingCertificateV2 is the ESS SigningCertificateV2 attribute (RFC 5035)
+type signingCertificateV2 struct {
+	Certs []essCertIDv2
+}
+
+type essCertIDv2 struct {
+	HashAlgorithm pkix.AlgorithmIdentifier `asn1:"optional"`
+	CertHash      []byte
+	IssuerSerial  asn1.RawValue `asn1:"optional"`
+}
+
+// tstInfo is the RFC 3161 TSTInfo
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time     `asn1:"generalized"`
+	Accuracy       accuracy      `asn1:"optional"`
+	Ordering       bool          `asn1:"optional"`
+	Nonce          *big.Int      `asn1:"optional"`
+	TSA            asn1.RawValue `asn1:"optional,tag:0"`
+	Extensions     asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+// timestampToken is a decoded RFC 3161 TimeStampToken
+type timestampToken struct {
+	info     tstInfo
+	eContent []byte
+	certs    []*x509.Certificate
+	signer   signerInfo
+}
+
+func parseTimestampToken(der []byte) (*timestampToken, error) {
+	var ci contentInfo
+	if err := unmarshalASN1(der, &ci); err != nil {
+		return nil, fmt.Errorf("parsing ContentInfo: %w", err)
+	}
+
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("expecting SignedData content type, got %s instead", ci.ContentType)
+	}
+
+	var sd signedData
+	if err := unmarshalASN1(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("parsing SignedData: %w", err)
+	}
+
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, fmt.Errorf("expecting TSTInfo content type, got %s instead", sd.EncapContentInfo.EContentType)
+	}
+
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("expecting exactly one SignerInfo, got %d", len(sd.SignerInfos))
+	}
+
+	tst := timestampToken{
+		eContent: sd.EncapContentInfo.EContent,
+		signer:   sd.SignerInfos[0],
+	}
+
+	if err := unmarshalASN1(tst.eContent, &tst.info); err != nil {
+		return nil, fmt.Errorf("parsing TSTInfo: %w", err)
+	}
+
+	if tst.info.Version != 1 {
+		return nil, fmt.Errorf("unsupported TSTInfo version %d", tst.info.Version)
+	}
+
+	if len(sd.Certificates.Bytes) != 0 {
+		certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificates: %w", err)
+		}
+		tst.certs = certs
+	}
+
+	return &tst, nil
+}
+
+// checkImprint checks that the message imprint of the token matches the
+// supplied data
+func (o *timestampToken) checkImprint(data []byte) error {
+	hash, err := hashFromOID(o.info.MessageImprint.HashAlgorithm.Algorithm)
+	if err != nil {
+		return fmt.Errorf("message imprint: %w", err)
+	}
+
+	if !bytes.Equal(o.info.MessageImprint.HashedMessage, hashSum(hash, data)) {
+		return errors.New("message imprint does not match the signature")
+	}
+
+	return nil
+}
+
+// verify verifies the signature of the token and the certificate of the TSA,
+// which is returned
+func (o *timestampToken) verify(roots *x509.CertPool) (*x509.Certificate, error) {
+	cert, intermediates, err := o.signerCert()
+	if err != nil {
+		return nil, err
+	}
+
+	// RFC 3161 requires the extended key usage to be present, whereas a
+	// certificate without extended key usages is valid for any usage in
+	// x509.Verify
+	if !slices.Contains(cert.ExtKeyUsage, x509.ExtKeyUsageTimeStamping) {
+		return nil, errors.New("TSA certificate is not valid for time stamping")
+	}
+
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   o.info.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("verifying TSA certificate: %w", err)
+	}
+
+	if err := o.verifySignature(cert); err != nil {
+		return nil, err
+	}
+
+	return cert, nil
+}
+
+func (o *timestampToken) signerCert() (*x509.Certificate, *x509.CertPool, error) {
+	var signer *x509.Certificate
+
+	intermediates := x509.NewCertPool()
+
+	for _, c := range o.certs {
+		if signer == nil &&
+			bytes.Equal(c.RawIssuer, o.signer.SID.Issuer.FullBytes) &&
+			c.SerialNumber.Cmp(o.signer.SID.SerialNumber) == 0 {
+			signer = c
+			continue
+		}
+		intermediates.AddCert(c)
+	}
+
+	if signer == nil {
+		return nil, nil, errors.New("no TSA certificate matching the signer identifier")
+	}
+
+	return signer, intermediates, nil
+}
+
+func (o *timestampToken) verifySignature(cert *x509.Certificate) error {
+	hash, err := hashFromOID(o.signer.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return fmt.Errorf("digest algorithm: %w", err)
+	}
+
+	sigAlg, err := cmsSignatureAlgorithm(o.signer.SignatureAlgorithm.Algorithm, hash)
+	if err != nil {
+		return err
+	}
+
+	if len(o.signer.SignedAttrs.Bytes) == 0 {
+		return errors.New("missing signed attributes")
+	}
+
+	// the signature is computed over the DER encoding of the SET OF
+	// attributes, rather than over their [0] IMPLICIT encoding
+	signed, err := asn1.Marshal(asn1.RawValue{
+		Tag:        asn1.TagSet,
+		IsCompound: true,
+		Bytes:      o.signer.SignedAttrs.Bytes,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := cert.CheckSignature(sigAlg, signed, o.signer.Signature); err != nil {
+		return fmt.Errorf("TSA signature: %w", err)
+	}
+
+	var attrs []attribute
+	if rest, err := asn1.UnmarshalWithParams(signed, &attrs, "set"); err != nil || len(rest) != 0 {
+		return errors.New("parsing signed attributes")
+	}
+
+	return checkSignedAttrs(attrs, hashSum(hash, o.eContent), cert)
+}
+
+// checkSignedAttrs checks the content type and message digest attributes and,
+// if present, the ESS signing certificate v2 attribute
+func checkSignedAttrs(attrs []attribute, digest []byte, cert *x509.Certificate) error {
+	var hasContentType, hasDigest bool
+
+	for _, a := range attrs {
+		if len(a.Values) != 1 {
+			return fmt.Errorf("attribute %s: expecting a single value", a.Type)
+		}
+
+		v := a.Values[0].FullBytes
+
+		switch {
+		case a.Type.Equal(oidAttrContentType):
+			var ct asn1.ObjectIdentifier
+			if err := unmarshalASN1(v, &ct); err != nil || !ct.Equal(oidTSTInfo) {
+				return errors.New("content type attribute does not match TSTInfo")
+			}
+			hasContentType = true
+		case a.Type.Equal(oidAttrMessageDigest):
+			var md []byte
+			if err := unmarshalASN1(v, &md); err != nil || !bytes.Equal(md, digest) {
+				return errors.New("message digest attribute does not match TSTInfo")
+			}
+			hasDigest = true
+		case a.Type.Equal(oidAttrSigningCertV2):
+			if err := checkSigningCertV2(v, cert); err != nil {
+				return err
+			}
+		}
+	}
+
+	if !hasContentType || !hasDigest {
+		return errors.New("missing content type or message digest attribute")
+	}
+
+	return nil
+}
+
+func checkSigningCertV2(der []byte, cert *x509.Certificate) error {
+	var sc signingCertificateV2
+	if err := unmarshalASN1(der, &sc); err != nil || len(sc.Certs) == 0 {
+		return errors.New("parsing signing certificate attribute")
+	}
+
+	// the first ESSCertIDv2 identifies the TSA certificate
+	id := sc.Certs[0]
+
+	hash := crypto.SHA256
+	if len(id.HashAlgorithm.Algorithm) != 0 {
+		var err error
+		if hash, err = hashFromOID(id.HashAlgorithm.Algorithm); err != nil {
+			return fmt.Errorf("signing certificate attribute: %w", err)
+		}
+	}
+
+	if !bytes.Equal(id.CertHash, hashSum(hash, cert.Raw)) {
+		return errors.New("signing certificate attribute does not match the TSA certificate")
+	}
+
+	return nil
+}
+
+var cmsSignatureAlgorithms = []struct {
+	oid  asn1.ObjectIdentifier
+	hash crypto.Hash // the digest algorithm, if the OID does not imply it
+	alg  x509.SignatureAlgorithm
+}{
+	{oidECDSAWithSHA256, 0, x509.ECDSAWithSHA256},
+	{oidECDSAWithSHA384, 0, x509.ECDSAWithSHA384},
+	{oidECDSAWithSHA512, 0, x509.ECDSAWithSHA512},
+	{oidECPublicKey, crypto.SHA256, x509.ECDSAWithSHA256},
+	{oidECPublicKey, crypto.SHA384, x509.ECDSAWithSHA384},
+	{oidECPublicKey, crypto.SHA512, x509.ECDSAWithSHA512},
+	{oidSHA256WithRSA, 0, x509.SHA256WithRSA},
+	{oidSHA384WithRSA, 0, x509.SHA384WithRSA},
+	{oidSHA512WithRSA, 0, x509.SHA512WithRSA},
+	{oidRSAEncryption, crypto.SHA256, x509.SHA256WithRSA},
+	{oidRSAEncryption, crypto.SHA384, x509.SHA384WithRSA},
+	{oidRSAEncryption, crypto.SHA512, x509.SHA512WithRSA},
+	{oidEd25519, 0, x509.PureEd25519},
+}
+
+// cmsSignatureAlgorithm returns the x509.SignatureAlgorithm for the supplied
+// CMS signature algorithm OID, some of which (e.g., rsaEncryption) depend on
+// the digest algorithm
+func cmsSignatureAlgorithm(oid asn1.ObjectIdentifier, hash crypto.Hash) (x509.SignatureAlgorithm, error) {
+	for _, a := range cmsSignatureAlgorithms {
+		if a.oid.Equal(oid) && (a.hash == 0 || a.hash == hash) {
+			return a.alg, nil
+		}
+	}
+
+	return x509.UnknownSignatureAlgorithm, fmt.Errorf("unsupported signature algorithm %s with %s", oid, hash)
+}
+
+var hashOIDs = []struct {
+	hash crypto.Hash
+	oid  asn1.ObjectIdentifier
+}{
+	{crypto.SHA256, oidSHA256},
+	{crypto.SHA384, oidSHA384},
+	{crypto.SHA512, oidSHA512},
+}
+
+func hashOID(hash crypto.Hash) (asn1.ObjectIdentifier, error) {
+	for _, h := range hashOIDs {
+		if h.hash == hash {
+			return h.oid, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported hash function %s", hash)
+}
+
+func hashFromOID(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	for _, h := range hashOIDs {
+		if h.oid.Equal(oid) {
+			return h.hash, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unsupported hash algorithm %s", oid)
+}
+
+func hashSum(hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
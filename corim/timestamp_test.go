@@ -0,0 +1,258 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func asTSA(c *x509.Certificate) {
+	c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}
+}
+
+func longLived(c *x509.Certificate) {
+	c.NotAfter = testCertNotAfter.AddDate(5, 0, 0)
+}
+
+// newTestTSA returns a LocalTSA with a TSA certificate issued by a fresh root,
+// and the pool of the root, at the supplied time
+func newTestTSA(t *testing.T, now time.Time, tweaks ...func(*x509.Certificate)) (*LocalTSA, *x509.CertPool) {
+	root := newTestCert(t, "root", nil, longLived)
+	tsa := newTestCert(t, "TSA", root, func(c *x509.Certificate) {
+		for _, tweak := range tweaks {
+			tweak(c)
+		}
+	})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	l, err := NewLocalTSA(tsa.key, tsa.cert)
+	require.NoError(t, err)
+
+	l.Clock = func() time.Time { return now }
+
+	return l, roots
+}
+
+// testSign1CBOR returns a COSE Sign1 signed-corim with no validity period
+func testSign1CBOR(t *testing.T) []byte {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+
+	cbor, err := SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	return cbor
+}
+
+// timestampSignedCorim returns the decoded signed-corim with a timestamp token
+// from the supplied TSA
+func timestampSignedCorim(t *testing.T, cbor []byte, tsa TimestampAuthority) *SignedCorim {
+	var SignedCorimIn SignedCorim
+	require.NoError(t, SignedCorimIn.FromCOSE(cbor))
+
+	cbor, err := SignedCorimIn.AddTimestamp(tsa, crypto.SHA256)
+	require.NoError(t, err)
+
+	var SignedCorimOut SignedCorim
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+
+	return &SignedCorimOut
+}
+
+func TestSignedCorim_AddTimestamp_VerifyTimestamp(t *testing.T) {
+	root := newTestCert(t, "root", nil, nil)
+	leaf := newTestCert(t, "leaf", root, nil)
+
+	tsa, tsaRoots := newTestTSA(t, testCertNow, asTSA)
+
+	SignedCorimOut := timestampSignedCorim(t, signWithCerts(t, leaf), tsa)
+	require.NotEmpty(t, SignedCorimOut.TimestampToken)
+
+	// the timestamp does not invalidate the signature
+	assert.NoError(t, SignedCorimOut.Verify(&leaf.key.PublicKey))
+
+	ts, err := SignedCorimOut.VerifyTimestamp(tsaRoots)
+	require.NoError(t, err)
+	assert.Equal(t, testCertNow, ts.Time)
+	assert.Equal(t, LocalTSAPolicy, ts.Policy)
+	assert.Equal(t, big.NewInt(1), ts.SerialNumber)
+	assert.Equal(t, tsa.certs[0], ts.TSACert)
+
+	// the TSA is not trusted
+	_, otherRoots := newTestTSA(t, testCertNow, asTSA)
+
+	_, err = SignedCorimOut.VerifyTimestamp(otherRoots)
+	assert.ErrorContains(t, err, "verifying timestamp token: verifying TSA certificate")
+}
+
+func TestSignedCorim_VerifyTimestamp_TSA_key_types(t *testing.T) {
+	root := newTestCert(t, "root", nil, nil)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	for _, name := range []string{"rsa-2048-pkcs1.pem", "ed25519-pkcs8.pem"} {
+		t.Run(name, func(t *testing.T) {
+			block, _ := pem.Decode(readTestKey(t, name))
+			require.NotNil(t, block)
+
+			key, err := parsePrivateKeyPEM(block, nil)
+			require.NoError(t, err)
+
+			tmpl := &x509.Certificate{
+				SerialNumber: big.NewInt(time.Now().UnixNano()),
+				Subject:      pkix.Name{CommonName: "TSA"},
+				NotBefore:    testCertNotBefore,
+				NotAfter:     testCertNotAfter,
+				KeyUsage:     x509.KeyUsageDigitalSignature,
+				ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+			}
+
+			der, err := x509.CreateCertificate(rand.Reader, tmpl, root.cert, key.Public(), root.key)
+			require.NoError(t, err)
+
+			cert, err := x509.ParseCertificate(der)
+			require.NoError(t, err)
+
+			tsa, err := NewLocalTSA(key, cert)
+			require.NoError(t, err)
+
+			tsa.Clock = func() time.Time { return testCertNow }
+
+			SignedCorimOut := timestampSignedCorim(t, testSign1CBOR(t), tsa)
+
+			ts, err := SignedCorimOut.VerifyTimestamp(roots)
+			require.NoError(t, err)
+			assert.Equal(t, testCertNow, ts.Time)
+		})
+	}
+}
+
+func TestSignedCorim_VerifyTimestamp_signing_key_validity(t *testing.T) {
+	root := newTestCert(t, "root", nil, longLived)
+	leaf := newTestCert(t, "leaf", root, nil)
+
+	// the timestamp is after the expiry of the signing certificate
+	tsa, tsaRoots := newTestTSA(t, testCertNotAfter.AddDate(0, 6, 0), asTSA, longLived)
+
+	SignedCorimOut := timestampSignedCorim(t, signWithCerts(t, leaf), tsa)
+
+	_, err := SignedCorimOut.VerifyTimestamp(tsaRoots)
+	assert.ErrorIs(t, err, ErrExpired)
+	assert.EqualError(t, err, "signing certificate expired at 2025-07-01T00:00:00Z")
+
+	// the timestamp is after the end of the signature validity
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{
+		UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR),
+		Meta:          *metaGood(t),
+	}
+
+	cbor, err := SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	tsa, tsaRoots = newTestTSA(t, testCertNow, asTSA)
+
+	SignedCorimOut = timestampSignedCorim(t, cbor, tsa)
+
+	_, err = SignedCorimOut.VerifyTimestamp(tsaRoots)
+	assert.ErrorIs(t, err, ErrExpired)
+
+	var verr *ValidityError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, ValiditySignature, verr.Scope)
+	assert.Equal(t, testCertNow, verr.Now)
+}
+
+func TestSignedCorim_VerifyTimestamp_bad_token(t *testing.T) {
+	tsa, tsaRoots := newTestTSA(t, testCertNow, asTSA)
+
+	SignedCorimOut := timestampSignedCorim(t, testSign1CBOR(t), tsa)
+	token := SignedCorimOut.TimestampToken
+
+	// a token over another signature
+	other := timestampSignedCorim(t, testSign1CBOR(t), tsa)
+
+	SignedCorimOut.TimestampToken = other.TimestampToken
+	_, err := SignedCorimOut.VerifyTimestamp(tsaRoots)
+	assert.EqualError(t, err, "message imprint does not match the signature")
+
+	// a tampered TSA signature
+	tampered := append([]byte{}, token...)
+	tampered[len(tampered)-1] ^= 0xff
+
+	SignedCorimOut.TimestampToken = tampered
+	_, err = SignedCorimOut.VerifyTimestamp(tsaRoots)
+	assert.ErrorContains(t, err, "verifying timestamp token: TSA signature")
+
+	SignedCorimOut.TimestampToken = []byte{0x30, 0x00}
+	_, err = SignedCorimOut.VerifyTimestamp(tsaRoots)
+	assert.ErrorContains(t, err, "parsing timestamp token")
+
+	// the TSA certificate cannot be used for time stamping
+	tsa, tsaRoots = newTestTSA(t, testCertNow)
+
+	SignedCorimOut = timestampSignedCorim(t, testSign1CBOR(t), tsa)
+	_, err = SignedCorimOut.VerifyTimestamp(tsaRoots)
+	assert.ErrorContains(t, err, "verifying timestamp token: TSA certificate is not valid for time stamping")
+}
+
+func TestSignedCorim_AddTimestamp_fail(t *testing.T) {
+	tsa, tsaRoots := newTestTSA(t, testCertNow, asTSA)
+
+	var SignedCorimIn SignedCorim
+
+	_, err := SignedCorimIn.AddTimestamp(nil, crypto.SHA256)
+	assert.EqualError(t, err, "nil TSA")
+
+	_, err = SignedCorimIn.AddTimestamp(tsa, crypto.SHA256)
+	assert.EqualError(t, err, "no Sign1 message found")
+
+	_, err = SignedCorimIn.VerifyTimestamp(nil)
+	assert.EqualError(t, err, "nil TSA roots")
+
+	SignedCorimIn = *signWithKeyID(t, testES256Key, nil, "ACME Ltd.")
+
+	_, err = SignedCorimIn.AddTimestamp(tsa, crypto.SHA1)
+	assert.EqualError(t, err, "unsupported hash function SHA-1")
+
+	_, err = SignedCorimIn.VerifyTimestamp(tsaRoots)
+	assert.ErrorIs(t, err, ErrNoTimestamp)
+}
+
+func TestNewLocalTSA_fail(t *testing.T) {
+	tsa := newTestCert(t, "TSA", nil, asTSA)
+	other := newTestCert(t, "other", nil, nil)
+
+	_, err := NewLocalTSA(nil, tsa.cert)
+	assert.EqualError(t, err, "nil key")
+
+	_, err = NewLocalTSA(tsa.key, nil)
+	assert.EqualError(t, err, "nil TSA certificate")
+
+	_, err = NewLocalTSA(other.key, tsa.cert)
+	assert.EqualError(t, err, "key does not match the TSA certificate")
+
+	l, err := NewLocalTSA(tsa.key, tsa.cert)
+	require.NoError(t, err)
+
+	_, err = l.Timestamp(crypto.SHA256, []byte("short"))
+	assert.EqualError(t, err, "expecting SHA-256 digest of 32 bytes, got 5")
+}
@@ -97,18 +97,12 @@ func (o UnsignedCorim) GetID() string {
 // tags array of the unsigned-corim-map
 func (o *UnsignedCorim) AddComid(c *comid.Comid) *UnsignedCorim {
 	if o != nil {
-		if c.Valid() != nil {
-			return nil
-		}
-
-		comidCBOR, err := c.ToCBOR()
+		t, err := newComidTag(c)
 		if err != nil {
 			return nil
 		}
 
-		taggedComid := append(ComidTag, comidCBOR...) //nolint:gocritic
-
-		o.Tags = append(o.Tags, taggedComid)
+		o.Tags = append(o.Tags, t)
 	}
 	return o
 }
@@ -117,18 +111,12 @@ func (o *UnsignedCorim) AddComid(c *comid.Comid) *UnsignedCorim {
 // tags array of the unsigned-corim-map
 func (o *UnsignedCorim) AddCots(c *cots.ConciseTaStore) *UnsignedCorim {
 	if o != nil {
-		if c.Valid() != nil {
-			return nil
-		}
-
-		cotsCBOR, err := c.ToCBOR()
+		t, err := newCotsTag(c)
 		if err != nil {
 			return nil
 		}
 
-		taggedCots := append(cots.CotsTag, cotsCBOR...) //nolint:gocritic
-
-		o.Tags = append(o.Tags, taggedCots)
+		o.Tags = append(o.Tags, t)
 	}
 	return o
 }
@@ -137,19 +125,12 @@ func (o *UnsignedCorim) AddCots(c *cots.ConciseTaStore) *UnsignedCorim {
 // tags array of the unsigned-corim-map
 func (o *UnsignedCorim) AddCoswid(c *swid.SoftwareIdentity) *UnsignedCorim {
 	if o != nil {
-		// Currently the swid package doesn't offer an interface
-		// for validating the supplied CoSWID, so -- for now --
-		// we take any input for granted and pass it to the encoder.
-		// See also https://github.com/veraison/swid/issues/23.
-
-		coswidCBOR, err := c.ToCBOR()
+		t, err := newCoswidTag(c)
 		if err != nil {
 			return nil
 		}
 
-		taggedCoswid := append(CoswidTag, coswidCBOR...) //nolint:gocritic
-
-		o.Tags = append(o.Tags, taggedCoswid)
+		o.Tags = append(o.Tags, t)
 	}
 	return o
 }
@@ -244,11 +225,18 @@ func (o UnsignedCorim) Valid() error {
 	}
 
 	for i, t := range o.Tags {
-		if err := t.Valid(); err != nil {
+		if err := t.valid(o.Profile); err != nil {
 			return fmt.Errorf("tag validation failed at pos %d: %w", i, err)
 		}
 	}
 
+	return o.validHeader()
+}
+
+// validHeader checks the validity of all the fields of the target unsigned
+// CoRIM except for its id and tags
+// nolint:gocritic
+func (o UnsignedCorim) validHeader() error {
 	if o.DependentRims != nil {
 		for i, r := range *o.DependentRims {
 			if err := r.Valid(); err != nil {
@@ -280,6 +268,50 @@ func (o UnsignedCorim) Valid() error {
 	return o.Extensions.validCorim(&o)
 }
 
+// ValidCoswidTriples checks that the CoSWID tag-ids referenced by the
+// coswid-triples of the CoMIDs in the target unsigned CoRIM identify CoSWIDs
+// that are carried in its tags.  Since this requires decoding the tags, it is
+// not part of Valid and should be invoked explicitly by the caller.
+// nolint:gocritic
+func (o UnsignedCorim) ValidCoswidTriples() error {
+	coswids := make(map[string]bool)
+
+	tags, err := o.GetTags()
+	if err != nil {
+		return err
+	}
+
+	var comids []*comid.Comid
+
+	for _, t := range tags {
+		switch t.Type {
+		case TagTypeComid:
+			comids = append(comids, t.Comid)
+		case TagTypeCoswid:
+			coswids[t.Coswid.TagID.String()] = true
+		}
+	}
+
+	for _, c := range comids {
+		if c.Triples.CoswidTriples == nil {
+			continue
+		}
+
+		for i, ct := range *c.Triples.CoswidTriples {
+			for _, id := range ct.TagIDs {
+				if !coswids[id.String()] {
+					return fmt.Errorf(
+						"CoMID %s: coswid triple at index %d: CoSWID %q not found in tags",
+						c.TagIdentity.TagID.String(), i, id.String(),
+					)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 // ToCBOR serializes the target unsigned CoRIM to CBOR
 // nolint:gocritic
 func (o UnsignedCorim) ToCBOR() ([]byte, error) {
@@ -325,13 +357,20 @@ func (o *UnsignedCorim) FromJSON(data []byte) error {
 // Tag is either a CBOR-encoded CoMID, CoSWID or CoTS
 type Tag []byte
 
+// Valid checks that the target Tag is a CoMID, CoSWID or CoTS that can be
+// decoded
 func (o Tag) Valid() error {
-	// there is no much we can check here, except making sure that the tag is
-	// not zero-length
+	return o.valid(nil)
+}
+
+func (o Tag) valid(profileID *eat.Profile) error {
 	if len(o) == 0 {
 		return errors.New("empty tag")
 	}
-	return nil
+
+	_, err := o.Decode(profileID)
+
+	return err
 }
 
 // Locator is the internal representation of the corim-locator-map with CBOR and
@@ -415,3 +415,41 @@ func TestLocator_Valid(t *testing.T) {
 	assert.EqualError(t, l.Valid(), "invalid locator thumbprint: unknown hash algorithm 0")
 
 }
+
+func TestUnsignedCorim_ValidCoswidTriples(t *testing.T) {
+	coswid := swid.SoftwareIdentity{}
+	require.NoError(t, coswid.FromXML([]byte(
+		`<SoftwareIdentity xmlns="http://standards.iso.org/iso/19770/-2/2015/schema.xsd" tagId="com.acme.fw-1.0" name="ACME Firmware" version="1.0"><Entity name="ACME Ltd." regid="acme.example" role="tagCreator softwareCreator"></Entity></SoftwareIdentity>`,
+	)))
+
+	ct := &comid.CoswidTriple{
+		Environment: comid.Environment{Class: comid.NewClassUUID(comid.TestUUID)},
+	}
+	require.NotNil(t, ct.AddTagID("com.acme.fw-1.0"))
+
+	c := comid.NewComid().
+		SetTagIdentity("urn:example:comid", 0).
+		AddCoswidTriple(*ct)
+	require.NotNil(t, c)
+
+	tv := NewUnsignedCorim().
+		SetID("test corim id").
+		AddComid(c).
+		AddCoswid(&coswid)
+	require.NotNil(t, tv)
+	require.NoError(t, tv.Valid())
+
+	assert.NoError(t, tv.ValidCoswidTriples())
+
+	require.NotNil(t, ct.AddTagID("com.acme.missing"))
+	c.Triples.CoswidTriples = &comid.CoswidTriples{*ct}
+
+	tv = NewUnsignedCorim().
+		SetID("test corim id").
+		AddComid(c).
+		AddCoswid(&coswid)
+	require.NotNil(t, tv)
+
+	assert.EqualError(t, tv.ValidCoswidTriples(),
+		`CoMID urn:example:comid: coswid triple at index 0: CoSWID "com.acme.missing" not found in tags`)
+}
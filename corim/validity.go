@@ -4,6 +4,7 @@
 package corim
 
 import (
+	"errors"
 	"fmt"
 	"time"
 )
@@ -43,3 +44,65 @@ func (o Validity) Valid() error {
 	}
 	return nil
 }
+
+var (
+	// ErrNotYetValid is returned when a validity period has not started yet
+	ErrNotYetValid = errors.New("not yet valid")
+	// ErrExpired is returned when a validity period has ended
+	ErrExpired = errors.New("expired")
+)
+
+// ValidityScope identifies the validity period that a ValidityError refers to
+type ValidityScope int
+
+const (
+	// ValiditySignature is the validity of the signature, i.e., the
+	// validity in the corim-meta-map
+	ValiditySignature ValidityScope = iota
+	// ValidityRim is the validity of the CoRIM, i.e., the validity in the
+	// unsigned-corim-map
+	ValidityRim
+)
+
+func (o ValidityScope) String() string {
+	switch o {
+	case ValiditySignature:
+		return "signature"
+	case ValidityRim:
+		return "RIM"
+	default:
+		return fmt.Sprintf("ValidityScope(%d)", o)
+	}
+}
+
+// ValidityError is returned when a Validity check fails.  It wraps either
+// ErrNotYetValid or ErrExpired.
+type ValidityError struct {
+	Scope    ValidityScope
+	Validity Validity
+	Now      time.Time
+	Err      error
+}
+
+func (o *ValidityError) Error() string {
+	return fmt.Sprintf("%s validity: %s at %s", o.Scope, o.Err, o.Now.Format(time.RFC3339))
+}
+
+func (o *ValidityError) Unwrap() error {
+	return o.Err
+}
+
+// Check returns an error wrapping ErrNotYetValid or ErrExpired if the supplied
+// time is outside the validity period, extended on both sides by the supplied
+// clock skew tolerance
+func (o Validity) Check(now time.Time, skew time.Duration) error {
+	if o.NotBefore != nil && now.Add(skew).Before(*o.NotBefore) {
+		return ErrNotYetValid
+	}
+
+	if now.Add(-skew).After(o.NotAfter) {
+		return ErrExpired
+	}
+
+	return nil
+}
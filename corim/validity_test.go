@@ -0,0 +1,35 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidity_Check(t *testing.T) {
+	var (
+		notBefore = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		notAfter  = time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC)
+	)
+
+	v := NewValidity().Set(notAfter, &notBefore)
+	require.NotNil(t, v)
+
+	assert.NoError(t, v.Check(notBefore, 0))
+	assert.NoError(t, v.Check(notAfter, 0))
+	assert.ErrorIs(t, v.Check(notBefore.Add(-time.Second), 0), ErrNotYetValid)
+	assert.NoError(t, v.Check(notBefore.Add(-time.Second), time.Second))
+	assert.ErrorIs(t, v.Check(notAfter.Add(time.Second), 0), ErrExpired)
+	assert.NoError(t, v.Check(notAfter.Add(time.Second), time.Second))
+
+	// no not-before
+	v = NewValidity().Set(notAfter, nil)
+	require.NotNil(t, v)
+
+	assert.NoError(t, v.Check(time.Time{}, 0))
+}
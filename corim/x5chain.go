@@ -0,0 +1,144 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	cose "github.com/veraison/go-cose"
+)
+
+// ErrNoSigningCert is returned by VerifyChain when the signed-corim carries no
+// x5chain header
+var ErrNoSigningCert = errors.New("no signing certificate (x5chain) found")
+
+// x5chain returns the value of the x5chain header (RFC 9360) for the signing
+// and intermediate certificates: a single bstr if there is only the signing
+// certificate, otherwise an array of bstr.  nil is returned if no signing
+// certificate is set.
+func (o *SignedCorim) x5chain() any {
+	if o.SigningCert == nil {
+		return nil
+	}
+
+	if len(o.IntermediateCerts) == 0 {
+		return o.SigningCert.Raw
+	}
+
+	chain := [][]byte{o.SigningCert.Raw}
+	for _, c := range o.IntermediateCerts {
+		chain = append(chain, c.Raw)
+	}
+
+	return chain
+}
+
+// processX5Chain populates SigningCert and IntermediateCerts from the x5chain
+// header, which may be carried in either the protected or the unprotected
+// header bucket
+func (o *SignedCorim) processX5Chain() error {
+	o.SigningCert, o.IntermediateCerts = nil, nil
+
+	v, ok := o.message.Headers.Protected[cose.HeaderLabelX5Chain]
+	if !ok {
+		v, ok = o.message.Headers.Unprotected[cose.HeaderLabelX5Chain]
+		if !ok {
+			return nil
+		}
+	}
+
+	var ders [][]byte
+
+	switch t := v.(type) {
+	case []byte:
+		ders = [][]byte{t}
+	case []any:
+		for i, e := range t {
+			der, ok := e.([]byte)
+			if !ok {
+				return fmt.Errorf("expecting bstr at index %d, got %T instead", i, e)
+			}
+			ders = append(ders, der)
+		}
+	default:
+		return fmt.Errorf("expecting bstr or array of bstr, got %T instead", v)
+	}
+
+	if len(ders) == 0 {
+		return errors.New("empty x5chain")
+	}
+
+	certs := make([]*x509.Certificate, 0, len(ders))
+
+	for i, der := range ders {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("parsing certificate at index %d: %w", i, err)
+		}
+		certs = append(certs, cert)
+	}
+
+	o.SigningCert, o.IntermediateCerts = certs[0], certs[1:]
+
+	return nil
+}
+
+// VerifyChain verifies the signing certificate from the x5chain header up to
+// one of the supplied roots, using the intermediate certificates from the
+// same header, then verifies the signature of the target SignedCorim with the
+// public key of the signing certificate and checks the validity periods as
+// VerifyWithOptions does.  The chain is verified at the time given by the
+// options' Clock (the Skew is not applied to certificates), and the signing
+// certificate must be usable for one of the options' ExtKeyUsages (any
+// extended key usage, if unset) and, if its key usage extension is present,
+// for digital signatures.  The options' AlgorithmPolicy, if set, applies
+// instead of the SignedCorim's.
+func (o *SignedCorim) VerifyChain(roots *x509.CertPool, opts VerifyOptions) error {
+	if o.message == nil {
+		return errors.New("no Sign1 message found")
+	}
+
+	if roots == nil {
+		return errors.New("no root certificates")
+	}
+
+	if o.SigningCert == nil {
+		return ErrNoSigningCert
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range o.IntermediateCerts {
+		intermediates.AddCert(c)
+	}
+
+	usages := opts.ExtKeyUsages
+	if len(usages) == 0 {
+		usages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	}
+
+	now := opts.now()
+
+	_, err := o.SigningCert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     usages,
+	})
+	if err != nil {
+		return fmt.Errorf("certificate chain verification failed: %w", err)
+	}
+
+	ku := o.SigningCert.KeyUsage
+	if ku != 0 && ku&x509.KeyUsageDigitalSignature == 0 {
+		return errors.New("signing certificate key usage does not include digital signature")
+	}
+
+	if err := o.verify(o.SigningCert.PublicKey, opts.policy(o.AlgorithmPolicy)); err != nil {
+		return err
+	}
+
+	return o.CheckValidity(now, opts.Skew)
+}
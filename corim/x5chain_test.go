@@ -0,0 +1,229 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package corim
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cose "github.com/veraison/go-cose"
+)
+
+var (
+	testCertNotBefore = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	testCertNotAfter  = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	testCertNow       = time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert creates a certificate for a fresh P-256 key, signed by the
+// supplied parent (self-signed if parent is nil), and modified by tweak
+func newTestCert(t *testing.T, cn string, parent *testCert, tweak func(*x509.Certificate)) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    testCertNotBefore,
+		NotAfter:     testCertNotAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	}
+
+	if tweak != nil {
+		tweak(tmpl)
+	}
+
+	issuer, signer := tmpl, crypto.Signer(key)
+	if parent != nil {
+		issuer, signer = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer, &key.PublicKey, signer)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{cert, key}
+}
+
+func asCA(c *x509.Certificate) {
+	c.IsCA, c.BasicConstraintsValid = true, true
+	c.KeyUsage = x509.KeyUsageCertSign
+}
+
+func signWithCerts(t *testing.T, leaf *testCert, intermediates ...*testCert) []byte {
+	signer, err := cose.NewSigner(cose.AlgorithmES256, leaf.key)
+	require.NoError(t, err)
+
+	var SignedCorimIn SignedCorim
+
+	SignedCorimIn.UnsignedCorim = *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)
+	SignedCorimIn.Meta = *NewMeta().SetSigner("ACME Ltd.", nil)
+
+	require.NoError(t, SignedCorimIn.AddSigningCert(leaf.cert.Raw))
+	for _, c := range intermediates {
+		require.NoError(t, SignedCorimIn.AddIntermediateCerts(c.cert.Raw))
+	}
+
+	cbor, err := SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	return cbor
+}
+
+func TestSignedCorim_VerifyChain_ok(t *testing.T) {
+	root := newTestCert(t, "root", nil, nil)
+	inter := newTestCert(t, "intermediate", root, asCA)
+	leaf := newTestCert(t, "leaf", inter, nil)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	var SignedCorimOut SignedCorim
+
+	require.NoError(t, SignedCorimOut.FromCOSE(signWithCerts(t, leaf, inter)))
+	assert.Equal(t, leaf.cert.Raw, SignedCorimOut.SigningCert.Raw)
+	require.Len(t, SignedCorimOut.IntermediateCerts, 1)
+	assert.Equal(t, inter.cert.Raw, SignedCorimOut.IntermediateCerts[0].Raw)
+
+	opts := VerifyOptions{Clock: func() time.Time { return testCertNow }}
+
+	assert.NoError(t, SignedCorimOut.VerifyChain(roots, opts))
+
+	// the signing certificate alone is encoded as a single bstr
+	leaf = newTestCert(t, "leaf", root, nil)
+
+	require.NoError(t, SignedCorimOut.FromCOSE(signWithCerts(t, leaf)))
+	assert.Empty(t, SignedCorimOut.IntermediateCerts)
+	assert.NoError(t, SignedCorimOut.VerifyChain(roots, opts))
+}
+
+func TestSignedCorim_VerifyChain_fail(t *testing.T) {
+	root := newTestCert(t, "root", nil, nil)
+	other := newTestCert(t, "other root", nil, nil)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	opts := VerifyOptions{Clock: func() time.Time { return testCertNow }}
+
+	var SignedCorimOut SignedCorim
+
+	// unknown root
+	require.NoError(t, SignedCorimOut.FromCOSE(signWithCerts(t, newTestCert(t, "leaf", other, nil))))
+	assert.ErrorContains(t, SignedCorimOut.VerifyChain(roots, opts),
+		"certificate chain verification failed: x509: certificate signed by unknown authority")
+
+	// expired
+	leaf := newTestCert(t, "leaf", root, nil)
+	require.NoError(t, SignedCorimOut.FromCOSE(signWithCerts(t, leaf)))
+	err := SignedCorimOut.VerifyChain(roots, VerifyOptions{
+		Clock: func() time.Time { return testCertNotAfter.Add(time.Hour) },
+	})
+	assert.ErrorContains(t, err, "certificate has expired or is not yet valid")
+
+	// extended key usage
+	leaf = newTestCert(t, "leaf", root, func(c *x509.Certificate) {
+		c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	})
+	require.NoError(t, SignedCorimOut.FromCOSE(signWithCerts(t, leaf)))
+	assert.NoError(t, SignedCorimOut.VerifyChain(roots, opts))
+	err = SignedCorimOut.VerifyChain(roots, VerifyOptions{
+		Clock:        opts.Clock,
+		ExtKeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	assert.ErrorContains(t, err, "incompatible key usage")
+
+	// key usage
+	leaf = newTestCert(t, "leaf", root, func(c *x509.Certificate) {
+		c.KeyUsage = x509.KeyUsageKeyEncipherment
+	})
+	require.NoError(t, SignedCorimOut.FromCOSE(signWithCerts(t, leaf)))
+	assert.EqualError(t, SignedCorimOut.VerifyChain(roots, opts),
+		"signing certificate key usage does not include digital signature")
+
+	// certificate does not match the signing key
+	leaf = newTestCert(t, "leaf", root, nil)
+	leaf.key = newTestCert(t, "leaf", root, nil).key
+	require.NoError(t, SignedCorimOut.FromCOSE(signWithCerts(t, leaf)))
+	assert.EqualError(t, SignedCorimOut.VerifyChain(roots, opts), "verification error")
+
+	// no x5chain
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+	cbor, err := SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	require.NoError(t, SignedCorimOut.FromCOSE(cbor))
+	assert.Nil(t, SignedCorimOut.SigningCert)
+	assert.ErrorIs(t, SignedCorimOut.VerifyChain(roots, opts), ErrNoSigningCert)
+	assert.EqualError(t, SignedCorimOut.VerifyChain(nil, opts), "no root certificates")
+}
+
+func TestSignedCorim_Sign_fail_intermediates_only(t *testing.T) {
+	root := newTestCert(t, "root", nil, nil)
+
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+	require.NoError(t, SignedCorimIn.AddIntermediateCerts(root.cert.Raw))
+
+	_, err = SignedCorimIn.Sign(signer)
+	assert.EqualError(t, err, "intermediate certificates set without a signing certificate")
+
+	assert.ErrorContains(t, SignedCorimIn.AddSigningCert([]byte("bad")), "parsing signing certificate")
+	assert.ErrorContains(t, SignedCorimIn.AddIntermediateCerts([]byte("bad")),
+		"parsing intermediate certificates")
+}
+
+func TestSignedCorim_FromCOSE_fail_bad_x5chain(t *testing.T) {
+	signer, err := NewSignerFromJWK(testES256Key)
+	require.NoError(t, err)
+
+	SignedCorimIn := SignedCorim{UnsignedCorim: *unsignedCorimFromCBOR(t, testGoodUnsignedCorimCBOR)}
+	_, err = SignedCorimIn.Sign(signer)
+	require.NoError(t, err)
+
+	for _, tv := range []struct {
+		x5chain any
+		testerr string
+	}{
+		{[]byte("bad"), "parsing certificate at index 0"},
+		{[]any{}, "empty x5chain"},
+		{[]any{1}, "expecting bstr at index 0, got int64 instead"},
+		{"bad", "expecting bstr or array of bstr, got string instead"},
+	} {
+		SignedCorimIn.message.Headers.Unprotected[cose.HeaderLabelX5Chain] = tv.x5chain
+
+		cbor, err := SignedCorimIn.message.MarshalCBOR()
+		require.NoError(t, err)
+
+		var SignedCorimOut SignedCorim
+		assert.ErrorContains(t, SignedCorimOut.FromCOSE(cbor),
+			"processing COSE headers: processing x5chain: "+tv.testerr)
+	}
+}
@@ -11,6 +11,7 @@ require (
 	github.com/veraison/eat v0.0.0-20210331113810-3da8a4dd42ff
 	github.com/veraison/go-cose v1.2.1
 	github.com/veraison/swid v1.1.1-0.20230911094910-8ffdd07a22ca
+	golang.org/x/crypto v0.12.0
 )
 
 require (
@@ -25,7 +26,6 @@ require (
 	github.com/lestrrat-go/option v1.0.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/x448/float16 v0.8.4 // indirect
-	golang.org/x/crypto v0.12.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,113 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package transparency
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	cose "github.com/veraison/go-cose"
+)
+
+// MemoryLog is a transparency Service backed by an in-memory RFC 9162 Merkle
+// tree, whose receipts are signed with a local key.  It stands in for a
+// transparency service in tests and tooling.
+type MemoryLog struct {
+	mu     sync.Mutex
+	signer cose.Signer
+	kid    []byte
+	leaves [][]byte
+}
+
+// NewMemoryLog instantiates an empty MemoryLog that signs its receipts with
+// the supplied cose Signer and (optional) key identifier
+func NewMemoryLog(signer cose.Signer, kid []byte) (*MemoryLog, error) {
+	if signer == nil {
+		return nil, errors.New("nil signer")
+	}
+
+	return &MemoryLog{signer: signer, kid: kid}, nil
+}
+
+// Register appends the log entry of the supplied signed statement to the log
+// and returns a receipt for it
+func (o *MemoryLog) Register(statement []byte) ([]byte, error) {
+	entry, err := Entry(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.leaves = append(o.leaves, leafHash(entry))
+
+	return o.receipt(uint64(len(o.leaves) - 1))
+}
+
+// Receipt returns a receipt for the entry at the supplied index, against the
+// current state of the log
+func (o *MemoryLog) Receipt(index uint64) ([]byte, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if index >= uint64(len(o.leaves)) {
+		return nil, fmt.Errorf("no entry at index %d", index)
+	}
+
+	return o.receipt(index)
+}
+
+// Size returns the number of entries in the log
+func (o *MemoryLog) Size() uint64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return uint64(len(o.leaves))
+}
+
+// Root returns the current root of the log's Merkle tree
+func (o *MemoryLog) Root() []byte {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return treeHash(o.leaves)
+}
+
+func (o *MemoryLog) receipt(index uint64) ([]byte, error) {
+	proof, err := cbor.Marshal(inclusionProof{
+		TreeSize:  uint64(len(o.leaves)),
+		LeafIndex: index,
+		Path:      inclusionPath(index, o.leaves),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding inclusion proof: %w", err)
+	}
+
+	m := cose.NewSign1Message()
+	m.Headers.Protected.SetAlgorithm(o.signer.Algorithm())
+	m.Headers.Protected[HeaderLabelVDS] = VDSRFC9162SHA256
+
+	if len(o.kid) != 0 {
+		m.Headers.Protected[cose.HeaderLabelKeyID] = o.kid
+	}
+
+	m.Headers.Unprotected[HeaderLabelVDP] = map[int64]any{
+		inclusionProofsLabel: [][]byte{proof},
+	}
+
+	// the payload, i.e., the root of the tree, is detached
+	m.Payload = treeHash(o.leaves)
+
+	if err := m.Sign(rand.Reader, nil, o.signer); err != nil {
+		return nil, fmt.Errorf("signing receipt: %w", err)
+	}
+
+	m.Payload = nil
+
+	return m.MarshalCBOR()
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package transparency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLog(t *testing.T) {
+	log, _ := newTestLog(t, []byte("log"))
+
+	assert.Equal(t, uint64(0), log.Size())
+	assert.Equal(t, treeHash(nil), log.Root())
+
+	var statements [][]byte
+
+	for i := 0; i < 3; i++ {
+		statement := testStatement(t)
+		statements = append(statements, statement)
+
+		r, err := log.Register(statement)
+		require.NoError(t, err)
+
+		rcpt, err := parseReceipt(r)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("log"), rcpt.kid)
+		assert.Equal(t, uint64(i+1), rcpt.proof.TreeSize)
+		assert.Equal(t, uint64(i), rcpt.proof.LeafIndex)
+	}
+
+	assert.Equal(t, uint64(3), log.Size())
+
+	entry, err := Entry(statements[0])
+	require.NoError(t, err)
+
+	// a fresh receipt for the first entry is against the current tree
+	r, err := log.Receipt(0)
+	require.NoError(t, err)
+
+	rcpt, err := parseReceipt(r)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), rcpt.proof.TreeSize)
+
+	root, err := rootFromInclusionPath(0, 3, leafHash(entry), rcpt.proof.Path)
+	require.NoError(t, err)
+	assert.Equal(t, log.Root(), root)
+}
+
+func TestMemoryLog_fail(t *testing.T) {
+	_, err := NewMemoryLog(nil, nil)
+	assert.EqualError(t, err, "nil signer")
+
+	log, _ := newTestLog(t, nil)
+
+	_, err = log.Register([]byte("not a statement"))
+	assert.ErrorContains(t, err, "decoding COSE Sign1 signed statement")
+	assert.Equal(t, uint64(0), log.Size())
+
+	_, err = log.Receipt(0)
+	assert.EqualError(t, err, "no entry at index 0")
+}
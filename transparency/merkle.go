@@ -0,0 +1,107 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package transparency
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/bits"
+)
+
+// The Merkle tree hashing and inclusion proofs below follow RFC 9162 (Section
+// 2.1), using SHA-256.
+
+var (
+	leafPrefix = []byte{0x00}
+	nodePrefix = []byte{0x01}
+)
+
+// leafHash returns the Merkle tree hash of a leaf holding the supplied entry
+func leafHash(entry []byte) []byte {
+	h := sha256.New()
+	h.Write(leafPrefix)
+	h.Write(entry)
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write(nodePrefix)
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// splitPoint returns the largest power of two smaller than n, which must be
+// greater than 1
+func splitPoint(n uint64) uint64 {
+	return 1 << (bits.Len64(n-1) - 1)
+}
+
+// treeHash returns the Merkle tree hash (MTH) of the supplied leaf hashes
+func treeHash(leaves [][]byte) []byte {
+	switch n := uint64(len(leaves)); n {
+	case 0:
+		h := sha256.Sum256(nil)
+		return h[:]
+	case 1:
+		return leaves[0]
+	default:
+		k := splitPoint(n)
+		return nodeHash(treeHash(leaves[:k]), treeHash(leaves[k:]))
+	}
+}
+
+// inclusionPath returns the inclusion path (PATH) of the leaf at the supplied
+// index, which must be within the supplied leaf hashes
+func inclusionPath(index uint64, leaves [][]byte) [][]byte {
+	n := uint64(len(leaves))
+	if n <= 1 {
+		return [][]byte{}
+	}
+
+	k := splitPoint(n)
+	if index < k {
+		return append(inclusionPath(index, leaves[:k]), treeHash(leaves[k:]))
+	}
+
+	return append(inclusionPath(index-k, leaves[k:]), treeHash(leaves[:k]))
+}
+
+// rootFromInclusionPath returns the root of the tree of the supplied size
+// computed from the hash of the leaf at the supplied index and its inclusion
+// path, as in the verification of an inclusion proof
+func rootFromInclusionPath(index, size uint64, leaf []byte, path [][]byte) ([]byte, error) {
+	if index >= size {
+		return nil, errors.New("leaf index out of range")
+	}
+
+	fn, sn, r := index, size-1, leaf
+
+	for _, p := range path {
+		if sn == 0 {
+			return nil, errors.New("inclusion path too long")
+		}
+
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+
+		fn >>= 1
+		sn >>= 1
+	}
+
+	if sn != 0 {
+		return nil, errors.New("inclusion path too short")
+	}
+
+	return r, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package transparency
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLeaves(n int) [][]byte {
+	leaves := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		leaves = append(leaves, leafHash([]byte(fmt.Sprintf("entry %d", i))))
+	}
+	return leaves
+}
+
+func TestTreeHash(t *testing.T) {
+	// SHA-256 of the empty string
+	assert.Equal(t,
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		hex.EncodeToString(treeHash(nil)))
+
+	leaves := testLeaves(3)
+
+	assert.Equal(t, leaves[0], treeHash(leaves[:1]))
+	assert.Equal(t, nodeHash(nodeHash(leaves[0], leaves[1]), leaves[2]), treeHash(leaves))
+}
+
+func TestInclusionPath_roundtrip(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		leaves := testLeaves(n)
+		root := treeHash(leaves)
+
+		for i := 0; i < n; i++ {
+			path := inclusionPath(uint64(i), leaves)
+
+			actual, err := rootFromInclusionPath(uint64(i), uint64(n), leaves[i], path)
+			require.NoError(t, err, "leaf %d of %d", i, n)
+			assert.Equal(t, root, actual, "leaf %d of %d", i, n)
+		}
+	}
+}
+
+func TestRootFromInclusionPath_fail(t *testing.T) {
+	leaves := testLeaves(5)
+	path := inclusionPath(2, leaves)
+
+	_, err := rootFromInclusionPath(5, 5, leaves[2], path)
+	assert.EqualError(t, err, "leaf index out of range")
+
+	_, err = rootFromInclusionPath(2, 5, leaves[2], append(path, leaves[0]))
+	assert.EqualError(t, err, "inclusion path too long")
+
+	_, err = rootFromInclusionPath(2, 5, leaves[2], path[:1])
+	assert.EqualError(t, err, "inclusion path too short")
+
+	// a different leaf leads to a different root
+	root, err := rootFromInclusionPath(2, 5, leaves[3], path)
+	require.NoError(t, err)
+	assert.NotEqual(t, treeHash(leaves), root)
+}
@@ -0,0 +1,405 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package transparency implements the registration of signed CoRIMs with a
+// SCITT-style transparency service, and the verification of the receipts that
+// prove their inclusion in the service's log.  Receipts are COSE Sign1
+// messages over the root of an RFC 9162 Merkle tree, with an inclusion proof
+// for the signed-corim (draft-ietf-cose-merkle-tree-proofs), and are carried
+// in the unprotected header of the signed-corim.
+package transparency
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
+)
+
+var (
+	// HeaderLabelReceipts is the label of the receipts header parameter,
+	// which carries an array of receipts in the unprotected header of a
+	// signed statement
+	HeaderLabelReceipts = int64(394)
+	// HeaderLabelVDS is the label of the verifiable data structure header
+	// parameter of a receipt
+	HeaderLabelVDS = int64(395)
+	// HeaderLabelVDP is the label of the verifiable data structure proofs
+	// header parameter of a receipt
+	HeaderLabelVDP = int64(396)
+)
+
+const (
+	// VDSRFC9162SHA256 identifies the RFC 9162 SHA-256 Merkle tree
+	VDSRFC9162SHA256 = 1
+	// inclusionProofsLabel is the label of the inclusion proofs in the
+	// verifiable data structure proofs
+	inclusionProofsLabel = -1
+)
+
+// ErrNoReceipt is returned by VerifyReceipts when the signed-corim carries no
+// receipt
+var ErrNoReceipt = errors.New("no receipt found")
+
+// Service is a SCITT-style transparency service
+type Service interface {
+	// Register registers the supplied signed statement, i.e., a serialized
+	// COSE Sign1 signed-corim, and returns a receipt for its inclusion in
+	// the log
+	Register(statement []byte) ([]byte, error)
+}
+
+// Inclusion describes a verified receipt
+type Inclusion struct {
+	// KeyID is the kid header of the receipt, if any
+	KeyID     []byte
+	Algorithm cose.Algorithm
+	TreeSize  uint64
+	LeafIndex uint64
+	// Root is the root of the Merkle tree signed by the receipt
+	Root []byte
+}
+
+// inclusionProof is the RFC 9162 SHA-256 inclusion proof
+type inclusionProof struct {
+	_         struct{} `cbor:",toarray"`
+	TreeSize  uint64
+	LeafIndex uint64
+	Path      [][]byte
+}
+
+// receipt is a decoded receipt
+type receipt struct {
+	message   *cose.Sign1Message
+	algorithm cose.Algorithm
+	kid       []byte
+	proof     inclusionProof
+}
+
+// Entry returns the log entry for the supplied signed statement: the COSE
+// Sign1 message with an empty unprotected header, so that receipts (and other
+// unprotected headers) can be added to the statement without changing its
+// entry
+func Entry(statement []byte) ([]byte, error) {
+	m := cose.NewSign1Message()
+
+	if err := m.UnmarshalCBOR(statement); err != nil {
+		return nil, fmt.Errorf("decoding COSE Sign1 signed statement: %w", err)
+	}
+
+	m.Headers.Unprotected, m.Headers.RawUnprotected = cose.UnprotectedHeader{}, nil
+
+	return m.MarshalCBOR()
+}
+
+// Register registers the supplied signed-corim, which must hold a signed COSE
+// Sign1 message, with the supplied transparency service, adds the returned
+// receipt to its receipts, and returns the serialized signed-corim
+func Register(svc Service, s *corim.SignedCorim) ([]byte, error) {
+	if svc == nil {
+		return nil, errors.New("nil transparency service")
+	}
+
+	statement, err := s.ToCOSE()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := svc.Register(statement)
+	if err != nil {
+		return nil, fmt.Errorf("registration failed: %w", err)
+	}
+
+	return AddReceipt(s, r)
+}
+
+// AddReceipt adds the supplied receipt to the receipts carried in the
+// unprotected header of the supplied signed-corim, and returns the serialized
+// signed-corim.  The receipt is decoded but not verified.
+func AddReceipt(s *corim.SignedCorim, r []byte) ([]byte, error) {
+	if _, err := parseReceipt(r); err != nil {
+		return nil, fmt.Errorf("decoding receipt: %w", err)
+	}
+
+	receipts, err := Receipts(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.SetUnprotectedHeader(HeaderLabelReceipts, append(receipts, r))
+}
+
+// Receipts returns the receipts carried in the unprotected header of the
+// supplied signed-corim
+func Receipts(s *corim.SignedCorim) ([][]byte, error) {
+	v, ok := s.UnprotectedHeader(HeaderLabelReceipts)
+	if !ok {
+		return nil, nil
+	}
+
+	// the header has been set by AddReceipt, rather than decoded
+	if receipts, ok := v.([][]byte); ok {
+		return receipts, nil
+	}
+
+	a, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("receipts: expecting array, got %T instead", v)
+	}
+
+	receipts := make([][]byte, 0, len(a))
+
+	for i, e := range a {
+		r, ok := e.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("receipts: expecting bstr at index %d, got %T instead", i, e)
+		}
+		receipts = append(receipts, r)
+	}
+
+	return receipts, nil
+}
+
+// VerifyReceipts verifies the receipts of the supplied signed-corim, according
+// to the supplied policy.  The verification keys of each receipt are looked up
+// with the supplied KeyResolver, using the receipt's kid (the signer is empty),
+// and must be accepted by the signed-corim's AlgorithmPolicy, if set.  The
+// options' AlgorithmPolicy, if set, applies instead of the signed-corim's; the
+// other options are not used.  On success, the receipts that have been
+// verified are returned.  The signature of the signed-corim itself is not
+// verified.
+func VerifyReceipts(
+	s *corim.SignedCorim, r corim.KeyResolver, policy corim.SignerPolicy, opts corim.VerifyOptions,
+) ([]Inclusion, error) {
+	if r == nil {
+		return nil, errors.New("nil key resolver")
+	}
+
+	receipts, err := Receipts(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(receipts) == 0 {
+		return nil, ErrNoReceipt
+	}
+
+	leaf, err := statementLeaf(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		verified []Inclusion
+		errs     []error
+		algs     = algorithmPolicy(s, opts)
+	)
+
+	for i, data := range receipts {
+		inc, err := verifyReceiptWithResolver(data, leaf, r, algs)
+		if err != nil {
+			err = fmt.Errorf("receipt %d: %w", i, err)
+			if policy == corim.SignerPolicyAll {
+				return nil, err
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		verified = append(verified, *inc)
+	}
+
+	if len(verified) == 0 {
+		return nil, fmt.Errorf("no receipt verified: %w", errors.Join(errs...))
+	}
+
+	return verified, nil
+}
+
+// VerifyReceipt verifies the supplied receipt for the supplied signed-corim
+// with the supplied public key of the transparency service.  As for
+// VerifyReceipts, the options' AlgorithmPolicy, if set, applies instead of the
+// signed-corim's.
+func VerifyReceipt(
+	s *corim.SignedCorim, r []byte, pk crypto.PublicKey, opts corim.VerifyOptions,
+) (*Inclusion, error) {
+	leaf, err := statementLeaf(s)
+	if err != nil {
+		return nil, err
+	}
+
+	rcpt, err := parseReceipt(r)
+	if err != nil {
+		return nil, fmt.Errorf("decoding receipt: %w", err)
+	}
+
+	return rcpt.verify(leaf, pk, algorithmPolicy(s, opts))
+}
+
+// algorithmPolicy returns the AlgorithmPolicy of the supplied options, if set,
+// or else that of the signed-corim
+func algorithmPolicy(s *corim.SignedCorim, opts corim.VerifyOptions) *corim.AlgorithmPolicy {
+	if opts.AlgorithmPolicy != nil {
+		return opts.AlgorithmPolicy
+	}
+	return s.AlgorithmPolicy
+}
+
+func verifyReceiptWithResolver(
+	data, leaf []byte, r corim.KeyResolver, algs *corim.AlgorithmPolicy,
+) (*Inclusion, error) {
+	rcpt, err := parseReceipt(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding receipt: %w", err)
+	}
+
+	keys, err := r.ResolveKeys(rcpt.kid, corim.Signer{})
+	if err != nil {
+		return nil, fmt.Errorf("resolving verification keys: %w", err)
+	}
+
+	if len(keys) == 0 {
+		return nil, corim.ErrNoKey
+	}
+
+	var inc *Inclusion
+
+	for _, pk := range keys {
+		if inc, err = rcpt.verify(leaf, pk, algs); err == nil {
+			return inc, nil
+		}
+	}
+
+	// report the error from the last key tried
+	return nil, err
+}
+
+// statementLeaf returns the Merkle tree leaf hash of the log entry of the
+// supplied signed-corim
+func statementLeaf(s *corim.SignedCorim) ([]byte, error) {
+	statement, err := s.ToCOSE()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := Entry(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	return leafHash(entry), nil
+}
+
+func parseReceipt(data []byte) (*receipt, error) {
+	m := cose.NewSign1Message()
+
+	if err := m.UnmarshalCBOR(data); err != nil {
+		return nil, err
+	}
+
+	if m.Payload != nil {
+		return nil, errors.New("expecting detached payload")
+	}
+
+	alg, err := m.Headers.Protected.Algorithm()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get receipt algorithm: %w", err)
+	}
+
+	if vds := m.Headers.Protected[HeaderLabelVDS]; !isInt(vds, VDSRFC9162SHA256) {
+		return nil, fmt.Errorf("unsupported verifiable data structure %v", vds)
+	}
+
+	var kid []byte
+	if v, ok := m.Headers.Protected[cose.HeaderLabelKeyID]; ok {
+		if kid, ok = v.([]byte); !ok {
+			return nil, fmt.Errorf("expecting bstr key id, got %T instead", v)
+		}
+	}
+
+	proof, err := decodeInclusionProof(m.Headers.Unprotected[HeaderLabelVDP])
+	if err != nil {
+		return nil, err
+	}
+
+	return &receipt{message: m, algorithm: alg, kid: kid, proof: *proof}, nil
+}
+
+// decodeInclusionProof decodes the single inclusion proof of the supplied
+// verifiable data structure proofs
+func decodeInclusionProof(vdp any) (*inclusionProof, error) {
+	if vdp == nil {
+		return nil, errors.New("missing verifiable data structure proofs")
+	}
+
+	data, err := cbor.Marshal(vdp)
+	if err != nil {
+		return nil, err
+	}
+
+	var proofs map[int64][][]byte
+	if err := cbor.Unmarshal(data, &proofs); err != nil {
+		return nil, fmt.Errorf("decoding verifiable data structure proofs: %w", err)
+	}
+
+	inclusion := proofs[inclusionProofsLabel]
+	if len(inclusion) != 1 {
+		return nil, fmt.Errorf("expecting one inclusion proof, got %d", len(inclusion))
+	}
+
+	var proof inclusionProof
+	if err := cbor.Unmarshal(inclusion[0], &proof); err != nil {
+		return nil, fmt.Errorf("decoding inclusion proof: %w", err)
+	}
+
+	return &proof, nil
+}
+
+func isInt(v any, i int64) bool {
+	switch t := v.(type) {
+	case int64:
+		return t == i
+	case uint64:
+		return i >= 0 && t == uint64(i)
+	default:
+		return false
+	}
+}
+
+// verify checks that the inclusion proof of the receipt leads from the
+// supplied leaf to a root signed by the supplied key, with an algorithm (and
+// a key) accepted by the supplied policy
+func (o *receipt) verify(leaf []byte, pk crypto.PublicKey, algs *corim.AlgorithmPolicy) (*Inclusion, error) {
+	root, err := rootFromInclusionPath(o.proof.LeafIndex, o.proof.TreeSize, leaf, o.proof.Path)
+	if err != nil {
+		return nil, fmt.Errorf("inclusion proof: %w", err)
+	}
+
+	if err := algs.Check(o.algorithm, pk); err != nil {
+		return nil, err
+	}
+
+	verifier, err := cose.NewVerifier(o.algorithm, pk)
+	if err != nil {
+		return nil, fmt.Errorf("unable to instantiate verifier: %w", err)
+	}
+
+	// the payload is detached: it is the root computed from the proof
+	o.message.Payload = root
+	defer func() { o.message.Payload = nil }()
+
+	if err := o.message.Verify(nil, verifier); err != nil {
+		return nil, fmt.Errorf("receipt signature: %w", err)
+	}
+
+	return &Inclusion{
+		KeyID:     o.kid,
+		Algorithm: o.algorithm,
+		TreeSize:  o.proof.TreeSize,
+		LeafIndex: o.proof.LeafIndex,
+		Root:      root,
+	}, nil
+}
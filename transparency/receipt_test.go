@@ -0,0 +1,238 @@
+// Copyright 2024 Contributors to the Veraison project.
+// SPDX-License-Identifier: Apache-2.0
+
+package transparency
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/veraison/corim/comid"
+	"github.com/veraison/corim/corim"
+	cose "github.com/veraison/go-cose"
+)
+
+// kidResolver resolves the kid of a receipt to the keys of a log
+type kidResolver map[string][]crypto.PublicKey
+
+func (o kidResolver) ResolveKeys(kid []byte, _ corim.Signer) ([]crypto.PublicKey, error) {
+	return o[string(kid)], nil
+}
+
+func newTestSigner(t *testing.T) (cose.Signer, crypto.PublicKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	signer, err := cose.NewSigner(cose.AlgorithmES256, key)
+	require.NoError(t, err)
+
+	return signer, key.Public()
+}
+
+func newTestLog(t *testing.T, kid []byte) (*MemoryLog, crypto.PublicKey) {
+	signer, pk := newTestSigner(t)
+
+	log, err := NewMemoryLog(signer, kid)
+	require.NoError(t, err)
+
+	return log, pk
+}
+
+// testStatement returns a freshly signed COSE Sign1 signed-corim
+func testStatement(t *testing.T) []byte {
+	c := comid.NewComid()
+	require.NoError(t, c.FromJSON([]byte(comid.PSARefValJSONTemplate)))
+
+	u := corim.NewUnsignedCorim().SetID("transparency-test").AddComid(c)
+	require.NotNil(t, u)
+
+	signer, _ := newTestSigner(t)
+
+	s := corim.SignedCorim{UnsignedCorim: *u}
+
+	statement, err := s.Sign(signer)
+	require.NoError(t, err)
+
+	return statement
+}
+
+func testSignedCorim(t *testing.T) *corim.SignedCorim {
+	var s corim.SignedCorim
+	require.NoError(t, s.FromCOSE(testStatement(t)))
+
+	return &s
+}
+
+func TestRegister_VerifyReceipts(t *testing.T) {
+	log, pk := newTestLog(t, []byte("log"))
+	otherLog, otherPK := newTestLog(t, []byte("other-log"))
+
+	// other statements are logged before and after
+	_, err := log.Register(testStatement(t))
+	require.NoError(t, err)
+
+	s := testSignedCorim(t)
+
+	cbor, err := Register(log, s)
+	require.NoError(t, err)
+
+	_, err = log.Register(testStatement(t))
+	require.NoError(t, err)
+
+	var decoded corim.SignedCorim
+	require.NoError(t, decoded.FromCOSE(cbor))
+
+	receipts, err := Receipts(&decoded)
+	require.NoError(t, err)
+	require.Len(t, receipts, 1)
+
+	inc, err := VerifyReceipt(&decoded, receipts[0], pk, corim.VerifyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("log"), inc.KeyID)
+	assert.Equal(t, uint64(2), inc.TreeSize)
+	assert.Equal(t, uint64(1), inc.LeafIndex)
+
+	// the signed-corim is registered with a second log
+	cbor, err = Register(otherLog, &decoded)
+	require.NoError(t, err)
+
+	require.NoError(t, decoded.FromCOSE(cbor))
+
+	receipts, err = Receipts(&decoded)
+	require.NoError(t, err)
+	assert.Len(t, receipts, 2)
+
+	r := kidResolver{"log": {pk}, "other-log": {otherPK}}
+
+	verified, err := VerifyReceipts(&decoded, r, corim.SignerPolicyAll, corim.VerifyOptions{})
+	require.NoError(t, err)
+	assert.Len(t, verified, 2)
+
+	// only the first log is known
+	r = kidResolver{"log": {pk}}
+
+	verified, err = VerifyReceipts(&decoded, r, corim.SignerPolicyAny, corim.VerifyOptions{})
+	require.NoError(t, err)
+	require.Len(t, verified, 1)
+	assert.Equal(t, []byte("log"), verified[0].KeyID)
+
+	_, err = VerifyReceipts(&decoded, r, corim.SignerPolicyAll, corim.VerifyOptions{})
+	assert.ErrorIs(t, err, corim.ErrNoKey)
+	assert.EqualError(t, err, "receipt 1: no verification key found")
+
+	// a fresh receipt against the grown log verifies as well
+	fresh, err := log.Receipt(1)
+	require.NoError(t, err)
+
+	inc, err = VerifyReceipt(&decoded, fresh, pk, corim.VerifyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), inc.TreeSize)
+	assert.Equal(t, log.Root(), inc.Root)
+}
+
+func TestVerifyReceipt_fail(t *testing.T) {
+	log, pk := newTestLog(t, nil)
+	_, otherPK := newTestSigner(t)
+
+	s := testSignedCorim(t)
+
+	_, err := Register(log, s)
+	require.NoError(t, err)
+
+	receipts, err := Receipts(s)
+	require.NoError(t, err)
+	require.Len(t, receipts, 1)
+
+	// the receipt is signed by another key
+	_, err = VerifyReceipt(s, receipts[0], otherPK, corim.VerifyOptions{})
+	assert.EqualError(t, err, "receipt signature: verification error")
+
+	// the receipt is for another statement
+	other := testSignedCorim(t)
+
+	_, err = VerifyReceipt(other, receipts[0], pk, corim.VerifyOptions{})
+	assert.EqualError(t, err, "receipt signature: verification error")
+
+	_, err = VerifyReceipts(other, kidResolver{"": {pk}}, corim.SignerPolicyAny, corim.VerifyOptions{})
+	assert.ErrorIs(t, err, ErrNoReceipt)
+
+	// the algorithm policy of the signed-corim applies
+	s.AlgorithmPolicy = &corim.AlgorithmPolicy{Algorithms: []cose.Algorithm{cose.AlgorithmES384}}
+
+	_, err = VerifyReceipt(s, receipts[0], pk, corim.VerifyOptions{})
+	assert.ErrorIs(t, err, corim.ErrAlgorithmPolicy)
+
+	_, err = VerifyReceipts(s, kidResolver{"": {pk}}, corim.SignerPolicyAll, corim.VerifyOptions{})
+	assert.ErrorIs(t, err, corim.ErrAlgorithmPolicy)
+
+	// the verifier's own policy overrides it...
+	es256 := corim.VerifyOptions{
+		AlgorithmPolicy: &corim.AlgorithmPolicy{Algorithms: []cose.Algorithm{cose.AlgorithmES256}},
+	}
+
+	_, err = VerifyReceipt(s, receipts[0], pk, es256)
+	assert.NoError(t, err)
+
+	_, err = VerifyReceipts(s, kidResolver{"": {pk}}, corim.SignerPolicyAll, es256)
+	assert.NoError(t, err)
+
+	// ...and also applies to signed-corims with no policy of their own
+	s.AlgorithmPolicy = nil
+
+	es384 := corim.VerifyOptions{
+		AlgorithmPolicy: &corim.AlgorithmPolicy{Algorithms: []cose.Algorithm{cose.AlgorithmES384}},
+	}
+
+	_, err = VerifyReceipt(s, receipts[0], pk, es384)
+	assert.ErrorIs(t, err, corim.ErrAlgorithmPolicy)
+
+	_, err = VerifyReceipts(s, kidResolver{"": {pk}}, corim.SignerPolicyAny, es384)
+	assert.ErrorIs(t, err, corim.ErrAlgorithmPolicy)
+
+	_, err = VerifyReceipt(s, []byte("bad"), pk, corim.VerifyOptions{})
+	assert.ErrorContains(t, err, "decoding receipt")
+
+	// the statement itself is not a receipt
+	statement, err := s.ToCOSE()
+	require.NoError(t, err)
+
+	_, err = AddReceipt(s, statement)
+	assert.EqualError(t, err, "decoding receipt: expecting detached payload")
+}
+
+func TestRegister_fail(t *testing.T) {
+	log, _ := newTestLog(t, nil)
+
+	_, err := Register(nil, testSignedCorim(t))
+	assert.EqualError(t, err, "nil transparency service")
+
+	_, err = Register(log, &corim.SignedCorim{})
+	assert.EqualError(t, err, "no Sign1 message found")
+
+	_, err = VerifyReceipts(testSignedCorim(t), nil, corim.SignerPolicyAny, corim.VerifyOptions{})
+	assert.EqualError(t, err, "nil key resolver")
+}
+
+func TestEntry_ignores_unprotected_header(t *testing.T) {
+	s := testSignedCorim(t)
+
+	before, err := s.ToCOSE()
+	require.NoError(t, err)
+
+	after, err := s.SetUnprotectedHeader(-70000, []byte("x"))
+	require.NoError(t, err)
+	require.NotEqual(t, before, after)
+
+	entryBefore, err := Entry(before)
+	require.NoError(t, err)
+
+	entryAfter, err := Entry(after)
+	require.NoError(t, err)
+
+	assert.Equal(t, entryBefore, entryAfter)
+}